@@ -0,0 +1,191 @@
+package config
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/midedickson/github-service/utils"
+)
+
+type Config struct {
+	// postgres url (postgres://...) or path of a sqlite database file
+	DatabaseURL string
+	// base url of the github api, overridable for github enterprise and tests
+	GithubAPIURL string
+	// number of items requested per page from list endpoints (github allows up to 100)
+	GithubPerPage int
+	// maximum number of pages to walk per listing; 0 means no cap
+	GithubMaxPages int
+	// personal access tokens used to authenticate against github, rotated by remaining quota
+	GithubTokens []string
+	// secret github signs the webhooks it sends to /hooks/github with; the endpoint is off without it
+	GithubWebhookSecret string
+	// global start date for commit syncs; repositories may override it with their own setting
+	CommitSyncSince time.Time
+	// apply pending schema migrations on startup
+	AutoMigrate bool
+	// how long idle workers wait before checking the job queue again
+	JobPollInterval time.Duration
+	// how long a claimed job stays leased to a worker before others may take it over
+	JobLeaseDuration time.Duration
+	// how often a repository is checked for updates; active repositories are checked more often
+	// and dormant ones less, within the min and max intervals
+	RepoRefreshInterval    time.Duration
+	RepoRefreshMinInterval time.Duration
+	RepoRefreshMaxInterval time.Duration
+	// when recurring jobs run, keyed by job type; cron expressions or "@every <duration>"
+	JobSchedules map[string]string
+	// number of workers per job type, for types without their own entry in WorkerPoolSizes
+	WorkerPoolSize int
+	// number of workers for individual job types, keyed by job type
+	WorkerPoolSizes map[string]int
+	// maximum number of pending jobs per job type; further jobs requested through the api are rejected. 0 means no limit
+	JobQueueLimit int
+	// maximum number of requests in flight to github at once, shared by every worker
+	GithubMaxConcurrentRequests int
+	// how long a url github answered with a 404 is answered as not found without asking again
+	GithubNotFoundTTL time.Duration
+	// how long a webhook receiver gets to answer a delivery before the attempt counts as failed
+	WebhookTimeout time.Duration
+	// events buffered per event stream client; a client that falls this far behind is disconnected
+	EventStreamBuffer int
+	// how long shutdown waits for in-flight requests and running jobs before interrupting them
+	ShutdownTimeout time.Duration
+}
+
+// load configuration from environment variables, falling back to sane defaults
+func Load() *Config {
+	return &Config{
+		DatabaseURL:         getEnv("DATABASE_URL", "db.sqlite"),
+		GithubAPIURL:        getEnv("GITHUB_API_URL", "https://api.github.com"),
+		GithubPerPage:       getEnvInt("GITHUB_PER_PAGE", 100),
+		GithubMaxPages:      getEnvInt("GITHUB_MAX_PAGES", 0),
+		GithubTokens:        getEnvList("GITHUB_TOKENS", getEnv("GITHUB_TOKEN", "")),
+		CommitSyncSince:     getEnvDate("COMMIT_SYNC_SINCE"),
+		GithubWebhookSecret: getEnv("GITHUB_WEBHOOK_SECRET", ""),
+		AutoMigrate:         getEnvBool("AUTO_MIGRATE", true),
+
+		JobPollInterval:        getEnvDuration("JOB_POLL_INTERVAL", 2*time.Second),
+		JobLeaseDuration:       getEnvDuration("JOB_LEASE_DURATION", 5*time.Minute),
+		RepoRefreshInterval:    getEnvDuration("REPO_REFRESH_INTERVAL", time.Hour),
+		RepoRefreshMinInterval: getEnvDuration("REPO_REFRESH_MIN_INTERVAL", 15*time.Minute),
+		RepoRefreshMaxInterval: getEnvDuration("REPO_REFRESH_MAX_INTERVAL", 24*time.Hour),
+		JobSchedules:           getEnvMap("JOB_SCHEDULES"),
+
+		WorkerPoolSize:              getEnvInt("WORKER_POOL_SIZE", 1),
+		WorkerPoolSizes:             getEnvIntMap("WORKER_POOL_SIZES"),
+		JobQueueLimit:               getEnvInt("JOB_QUEUE_LIMIT", 1000),
+		GithubMaxConcurrentRequests: getEnvInt("GITHUB_MAX_CONCURRENT_REQUESTS", 4),
+		GithubNotFoundTTL:           getEnvDuration("GITHUB_NOT_FOUND_TTL", 5*time.Minute),
+
+		WebhookTimeout:    getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		EventStreamBuffer: getEnvInt("EVENT_STREAM_BUFFER", 256),
+		ShutdownTimeout:   getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+	}
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+// read a comma separated list, dropping empty entries
+func getEnvList(key, fallback string) []string {
+	value := getEnv(key, fallback)
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// read comma separated key=value pairs with integer values, e.g. "sync-commits=4,fetch-repo=2";
+// malformed pairs are skipped
+func getEnvIntMap(key string) map[string]int {
+	values := map[string]int{}
+	for _, pair := range getEnvList(key, "") {
+		name, value, found := strings.Cut(pair, "=")
+		parsed, err := strconv.Atoi(strings.TrimSpace(value))
+		if !found || err != nil {
+			log.Printf("Ignoring %s entry %q", key, pair)
+			continue
+		}
+		values[strings.TrimSpace(name)] = parsed
+	}
+	return values
+}
+
+// read semicolon separated key=value pairs, e.g. "refresh-repo=*/5 * * * *;other=@every 1h";
+// semicolons because cron expressions contain commas
+func getEnvMap(key string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(getEnv(key, ""), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found {
+			log.Printf("Ignoring %s entry %q", key, pair)
+			continue
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+// read a date, returning the zero time when it is unset or invalid
+func getEnvDate(key string) time.Time {
+	value := getEnv(key, "")
+	if value == "" {
+		return time.Time{}
+	}
+	parsed, err := utils.ParseDate(value)
+	if err != nil {
+		log.Printf("Ignoring %s: %v", key, err)
+		return time.Time{}
+	}
+	return parsed
+}
@@ -0,0 +1,71 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/midedickson/github-service/utils"
+)
+
+const (
+	defaultTopAuthors = 10
+	maxTopAuthors     = 100
+)
+
+// read the top and since/until query parameters of the author endpoints
+func parseAuthorStatsQuery(r *http.Request) (*utils.AuthorStatsQuery, error) {
+	statsQuery := &utils.AuthorStatsQuery{Top: defaultTopAuthors}
+	if value := r.URL.Query().Get("top"); value != "" {
+		top, err := strconv.Atoi(value)
+		if err != nil || top < 1 || top > maxTopAuthors {
+			return nil, fmt.Errorf("top must be between 1 and %d", maxTopAuthors)
+		}
+		statsQuery.Top = top
+	}
+	since, until, err := utils.ParseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+	statsQuery.Since, statsQuery.Until = since, until
+	return statsQuery, nil
+}
+
+// the top committers to a repository
+func (c *Controller) GetRepositoryTopAuthors(w http.ResponseWriter, r *http.Request) {
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
+		return
+	}
+	repoName, ok := requirePathParam(w, r, "repo")
+	if !ok {
+		return
+	}
+	statsQuery, err := parseAuthorStatsQuery(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid query parameters", err.Error())
+		return
+	}
+	statsQuery.Owner, statsQuery.RepoName = owner, repoName
+	c.dispatchTopAuthors(w, r, statsQuery)
+}
+
+// the top committers across every stored repository, or every repository of ?owner=
+func (c *Controller) GetTopAuthors(w http.ResponseWriter, r *http.Request) {
+	statsQuery, err := parseAuthorStatsQuery(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid query parameters", err.Error())
+		return
+	}
+	statsQuery.Owner = r.URL.Query().Get("owner")
+	c.dispatchTopAuthors(w, r, statsQuery)
+}
+
+func (c *Controller) dispatchTopAuthors(w http.ResponseWriter, r *http.Request, statsQuery *utils.AuthorStatsQuery) {
+	authors, err := c.dbRepository.GetTopCommitAuthors(r.Context(), statsQuery)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Top Authors Fetched Successfully", authors)
+}
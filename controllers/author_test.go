@@ -0,0 +1,48 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRepositoryTopAuthors(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	mockDBRepository.On("GetTopCommitAuthors", &utils.AuthorStatsQuery{Owner: "testuser", RepoName: "testrepo", Since: &since, Top: 3}).
+		Return([]*dto.AuthorStatsResponseDTO{{Author: "alice", Commits: 4}}, nil)
+
+	req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/authors?top=3&since=2024-07-01", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+	controller.GetRepositoryTopAuthors(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Top Authors Fetched Successfully", response.Message)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetTopAuthors_InvalidTop(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+
+	for _, query := range []string{"top=0", "top=1000", "top=ten", "until=soon"} {
+		req, _ := http.NewRequest("GET", "/authors/top?"+query, nil)
+		rr := httptest.NewRecorder()
+		controller.GetTopAuthors(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
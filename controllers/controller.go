@@ -1,25 +1,47 @@
 package controllers
 
 import (
+	"net/http"
+
 	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/eventbus"
 	"github.com/midedickson/github-service/requester"
 	"github.com/midedickson/github-service/tasks"
+	"github.com/midedickson/github-service/utils"
 )
 
 type Controller struct {
 	requester    requester.Requester
 	dbRepository database.DBRepository
 	task         tasks.Task
+	// verifies the webhooks github sends; see ReceiveGithubHook
+	githubWebhookSecret string
+	// what the workers publish, streamed to clients by StreamEvents
+	eventBus *eventbus.Bus
 }
 
 func NewController(
 	requester requester.Requester,
 	dbRepository database.DBRepository,
 	task tasks.Task,
+	githubWebhookSecret string,
+	eventBus *eventbus.Bus,
 ) *Controller {
 	return &Controller{
-		requester:    requester,
-		dbRepository: dbRepository,
-		task:         task,
+		requester:           requester,
+		dbRepository:        dbRepository,
+		task:                task,
+		githubWebhookSecret: githubWebhookSecret,
+		eventBus:            eventBus,
+	}
+}
+
+// read a path param the route can't do without, such as {owner}, dispatching a 400 naming it when it is missing
+func requirePathParam(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	value, err := utils.GetPathParam(r, name)
+	if err != nil || value == "" {
+		utils.Dispatch400Error(w, "Invalid Payload", name+" is required")
+		return "", false
 	}
+	return value, true
 }
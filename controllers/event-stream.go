@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/midedickson/github-service/utils"
+)
+
+// how often an idle stream sends a comment, so proxies don't close it for inactivity
+const eventStreamKeepAlive = 15 * time.Second
+
+// write one server-sent event and push it out to the client
+func writeServerSentEvent(w http.ResponseWriter, flusher http.Flusher, id uint64, eventType string, data interface{}) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", id); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, body); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// stream what the workers do, as server-sent events, until the client goes away. ?owner= limits
+// the stream to one account. a client that doesn't keep up is sent an evicted event and
+// disconnected, and picks up from the events after it reconnects
+func (c *Controller) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.Dispatch500Error(w, errors.New("streaming is not supported by this connection"))
+		return
+	}
+	owner := r.URL.Query().Get("owner")
+	if owner != "" {
+		user, err := c.dbRepository.GetUser(r.Context(), owner)
+		if err != nil {
+			utils.Dispatch500Error(w, err)
+			return
+		}
+		if user == nil {
+			utils.Dispatch404Error(w, "User with this github username not found, please register this github username", nil)
+			return
+		}
+	}
+	sub := c.eventBus.Subscribe(owner)
+	defer c.eventBus.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// nginx would otherwise buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				if sub.Evicted() {
+					log.Printf("Evicting event stream client %s for falling behind", r.RemoteAddr)
+					writeServerSentEvent(w, flusher, 0, "evicted", map[string]string{
+						"message": "The stream fell too far behind and was closed; reconnect to resume",
+					})
+				}
+				return
+			}
+			if err := writeServerSentEvent(w, flusher, event.ID, event.Type, event); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package controllers_test
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/eventbus"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// open an event stream against a server running the controller, once the bus has it subscribed
+func openEventStream(t *testing.T, bus *eventbus.Bus, controller *controllers.Controller, query string) (*http.Response, *bufio.Reader) {
+	server := httptest.NewServer(http.HandlerFunc(controller.StreamEvents))
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/events/stream"+query, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Eventually(t, func() bool { return bus.Subscribers() == 1 }, time.Second, 5*time.Millisecond)
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// the next event on the stream as its id, event and data lines, skipping comments
+func readServerSentEvent(t *testing.T, stream *bufio.Reader) []string {
+	lines := []string{}
+	for {
+		line, err := stream.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" && len(lines) > 0 {
+			return lines
+		}
+		if line != "" && !strings.HasPrefix(line, ":") {
+			lines = append(lines, line)
+		}
+	}
+}
+
+func TestStreamEvents_FiltersByOwner(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	bus := eventbus.New(10)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", bus)
+	mockDBRepository.On("GetUser", "alice").Return(&models.User{Username: "alice"}, nil)
+
+	resp, stream := openEventStream(t, bus, controller, "?owner=alice")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	bus.Publish(eventbus.Event{Type: eventbus.EventCommitCreated, Owner: "bob"})
+	bus.Publish(eventbus.Event{Type: eventbus.EventJobFailed, Owner: "alice", Data: map[string]string{"error": "boom"}})
+
+	event := readServerSentEvent(t, stream)
+	require.Len(t, event, 3)
+	assert.Equal(t, "id: 2", event[0])
+	assert.Equal(t, "event: job.failed", event[1])
+	assert.Contains(t, event[2], `"owner":"alice"`)
+	assert.Contains(t, event[2], `"error":"boom"`)
+}
+
+func TestStreamEvents_EndsWhenTheBusCloses(t *testing.T) {
+	bus := eventbus.New(10)
+	controller := controllers.NewController(new(mocks.MockRequester), new(mocks.MockDBRepository), new(mocks.MockTask), "", bus)
+
+	_, stream := openEventStream(t, bus, controller, "")
+	bus.Close()
+	_, err := io.ReadAll(stream)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+func TestStreamEvents_UnknownOwner(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	bus := eventbus.New(10)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", bus)
+	mockDBRepository.On("GetUser", "nobody").Return(nil, nil)
+
+	req, _ := http.NewRequest("GET", "/events/stream?owner=nobody", nil)
+	rr := httptest.NewRecorder()
+	controller.StreamEvents(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, 0, bus.Subscribers())
+}
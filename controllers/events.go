@@ -0,0 +1,34 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// the changes found in a repository over time, newest first, optionally only those of ?type=
+func (c *Controller) GetRepositoryEvents(w http.ResponseWriter, r *http.Request) {
+	eventType := r.URL.Query().Get("type")
+	if eventType != "" && !slices.Contains(models.RepositoryEventTypes, eventType) {
+		utils.Dispatch400Error(w, "Invalid query parameters", fmt.Sprintf("type must be one of %v", models.RepositoryEventTypes))
+		return
+	}
+	list, err := utils.ParseListParams(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid pagination parameters", err.Error())
+		return
+	}
+	repo, ok := c.getOwnerRepository(w, r)
+	if !ok {
+		return
+	}
+	events, page, err := c.dbRepository.GetRepositoryEvents(r.Context(), repo, eventType, list)
+	if err != nil {
+		dispatchListError(w, err)
+		return
+	}
+	utils.Dispatch200Page(w, "Repository Events Fetched Successfully", events, page.Meta(r))
+}
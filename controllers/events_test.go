@@ -0,0 +1,46 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetRepositoryEvents(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	user := &models.User{Username: "testuser"}
+	repo := &models.Repository{Name: "testrepo"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(repo, nil)
+	mockDBRepository.On("GetRepositoryEvents", repo, models.RepositoryEventRenamed, &utils.ListParams{PerPage: 10}).
+		Return([]*models.RepositoryEvent{{Type: models.RepositoryEventRenamed, OldValue: "old", NewValue: "testrepo"}}, &utils.PageInfo{Total: 1, PerPage: 10}, nil)
+
+	req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/events?type=renamed&per_page=10", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+	controller.GetRepositoryEvents(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetRepositoryEvents_InvalidType(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+
+	req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/events?type=exploded", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+	controller.GetRepositoryEvents(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+}
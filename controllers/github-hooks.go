@@ -0,0 +1,194 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// github doesn't send payloads larger than this
+const maxGithubHookSize = 25 << 20
+
+// receive the webhooks github sends for the repositories it's set up on, so changes are picked up
+// as they happen rather than on the next update check. only hooks signed with the configured
+// secret are accepted
+func (c *Controller) ReceiveGithubHook(w http.ResponseWriter, r *http.Request) {
+	if c.githubWebhookSecret == "" {
+		utils.Dispatch503Error(w, "GitHub webhooks are not enabled; set GITHUB_WEBHOOK_SECRET", nil)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxGithubHookSize))
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err)
+		return
+	}
+	if !utils.VerifySignature(c.githubWebhookSecret, body, r.Header.Get(utils.SignatureHeader)) {
+		utils.Dispatch403Error(w, "Invalid signature", nil)
+		return
+	}
+	var payload dto.GithubHookPayloadDTO
+	if err := json.Unmarshal(body, &payload); err != nil {
+		log.Printf("Error decoding github hook payload: %v", err)
+		utils.Dispatch400Error(w, "Invalid Payload", err)
+		return
+	}
+	event := r.Header.Get("X-GitHub-Event")
+	log.Printf("Received github %s hook %s", event, r.Header.Get("X-GitHub-Delivery"))
+	switch event {
+	case "ping":
+		utils.Dispatch200(w, "Pong", payload.Zen)
+	case "push":
+		c.receivePushHook(w, r, &payload)
+	case "repository":
+		c.receiveRepositoryHook(w, r, &payload)
+	case "create", "delete":
+		c.receiveRefHook(w, r, &payload)
+	default:
+		utils.Dispatch200(w, fmt.Sprintf("Ignoring %s event", event), nil)
+	}
+}
+
+// the repository a hook is about and, when it is stored, the stored repository
+func (c *Controller) getHookRepository(w http.ResponseWriter, r *http.Request, payload *dto.GithubHookPayloadDTO) (*dto.GithubHookRepositoryDTO, *models.Repository, bool) {
+	hookRepo := &dto.GithubHookRepositoryDTO{}
+	if err := json.Unmarshal(payload.Repository, hookRepo); err != nil || hookRepo.ID == 0 {
+		utils.Dispatch400Error(w, "Invalid Payload", "the payload names no repository")
+		return nil, nil, false
+	}
+	repo, err := c.dbRepository.GetRepositoryInfoByRemoteId(r.Context(), hookRepo.ID)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return nil, nil, false
+	}
+	return hookRepo, repo, true
+}
+
+// queue a refresh of a repository a hook told us about
+func (c *Controller) dispatchHookRefresh(w http.ResponseWriter, r *http.Request, repo *models.Repository, message string) {
+	job, err := c.task.RefreshRepositoryFromHook(r.Context(), repo)
+	if err != nil {
+		dispatchEnqueueError(w, err)
+		return
+	}
+	utils.Dispatch202(w, message, jobLocation(job), job)
+}
+
+// a hook about a repository that isn't stored: fetch it if its owner is registered, and leave it otherwise
+func (c *Controller) receiveUntrackedRepositoryHook(w http.ResponseWriter, r *http.Request, hookRepo *dto.GithubHookRepositoryDTO) {
+	user, err := c.dbRepository.GetUser(r.Context(), hookRepo.Owner.Login)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	if user == nil {
+		utils.Dispatch200(w, "Repository is not tracked; ignoring", nil)
+		return
+	}
+	job, err := c.task.AddRequestToFetchNewlyRequestedRepoQueue(r.Context(), user.Username, hookRepo.Name)
+	if err != nil {
+		dispatchEnqueueError(w, err)
+		return
+	}
+	utils.Dispatch202(w, "Repository Fetch Queued", jobLocation(job), job)
+}
+
+// store the commits pushed to the default branch straight from the payload, then refresh the
+// repository, which also syncs any commits the payload left out
+func (c *Controller) receivePushHook(w http.ResponseWriter, r *http.Request, payload *dto.GithubHookPayloadDTO) {
+	hookRepo, repo, ok := c.getHookRepository(w, r, payload)
+	if !ok {
+		return
+	}
+	if repo == nil {
+		c.receiveUntrackedRepositoryHook(w, r, hookRepo)
+		return
+	}
+	// only the default branch is synced
+	if payload.Ref != "refs/heads/"+repo.DefaultBranch {
+		utils.Dispatch200(w, "Push to a branch that isn't synced; ignoring", nil)
+		return
+	}
+	if !payload.Deleted && len(payload.Commits) > 0 {
+		commits := make([]dto.CommitResponseDTO, 0, len(payload.Commits))
+		for _, commit := range payload.Commits {
+			commits = append(commits, dto.CommitResponseDTO{
+				SHA: commit.ID, Message: commit.Message, Author: commit.Author.Name, Date: commit.Timestamp, URL: commit.URL,
+			})
+		}
+		// the latest commit date is left to the refresh's sync, which would skip commits older
+		// than it that the payload didn't include
+		if _, err := c.dbRepository.StoreRepositoryCommits(r.Context(), &commits, repo); err != nil {
+			utils.Dispatch500Error(w, err)
+			return
+		}
+	}
+	c.dispatchHookRefresh(w, r, repo, "Push Received")
+}
+
+// a repository was renamed, transferred, edited or otherwise changed on github, or deleted there.
+// except for deletions the payload carries the whole repository, which is stored like a refresh
+// would store it and logs the same change events
+func (c *Controller) receiveRepositoryHook(w http.ResponseWriter, r *http.Request, payload *dto.GithubHookPayloadDTO) {
+	hookRepo, repo, ok := c.getHookRepository(w, r, payload)
+	if !ok {
+		return
+	}
+	if payload.Action == "deleted" {
+		if repo == nil {
+			utils.Dispatch200(w, "Repository is not tracked; ignoring", nil)
+			return
+		}
+		if err := c.dbRepository.DeleteRepository(r.Context(), repo); err != nil {
+			utils.Dispatch500Error(w, err)
+			return
+		}
+		utils.Dispatch200(w, "Repository Deleted", repo)
+		return
+	}
+	var remoteRepoInfo dto.RepositoryInfoResponseDTO
+	if err := json.Unmarshal(payload.Repository, &remoteRepoInfo); err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err)
+		return
+	}
+	owner, err := c.dbRepository.GetUser(r.Context(), hookRepo.Owner.Login)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	if owner == nil {
+		if repo == nil {
+			utils.Dispatch200(w, "Repository is not tracked; ignoring", nil)
+			return
+		}
+		// transferred to an account that isn't registered; it stays with the owner we know
+		owner = repo.Owner
+	}
+	// the payload didn't come from the requester, so there are no validators to store with it;
+	// the forced refresh queued below fetches the repository and stores the ones github sends
+	stored, err := c.dbRepository.StoreRepositoryInfo(r.Context(), &remoteRepoInfo, owner)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	stored.Owner = owner
+	c.dispatchHookRefresh(w, r, stored, "Repository Change Received")
+}
+
+// a branch or tag was created or deleted; the refresh picks up whatever that changed
+func (c *Controller) receiveRefHook(w http.ResponseWriter, r *http.Request, payload *dto.GithubHookPayloadDTO) {
+	_, repo, ok := c.getHookRepository(w, r, payload)
+	if !ok {
+		return
+	}
+	if repo == nil {
+		utils.Dispatch200(w, "Repository is not tracked; ignoring", nil)
+		return
+	}
+	c.dispatchHookRefresh(w, r, repo, "Ref Change Received")
+}
@@ -0,0 +1,131 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+const testHookSecret = "hook-secret"
+
+// send a github hook signed with the given secret
+func sendGithubHook(controller *controllers.Controller, event, secret, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", "/hooks/github", bytes.NewBufferString(body))
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", utils.SignPayload(secret, []byte(body)))
+	rr := httptest.NewRecorder()
+	controller.ReceiveGithubHook(rr, req)
+	return rr
+}
+
+func TestReceiveGithubHook_RejectsUnsignedHooks(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), testHookSecret, nil)
+
+	rr := sendGithubHook(controller, "ping", "wrong-secret", `{"zen": "Keep it logically awesome."}`)
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	rr = sendGithubHook(controller, "ping", testHookSecret, `{"zen": "Keep it logically awesome."}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	// without a secret there is nothing to check hooks against
+	controller = controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	rr = sendGithubHook(controller, "ping", "", `{}`)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestReceiveGithubHook_Push(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockTask := new(mocks.MockTask)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, mockTask, testHookSecret, nil)
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, RemoteID: 42, Name: "testrepo", DefaultBranch: "main", Owner: &models.User{Username: "testuser"}}
+	mockDBRepository.On("GetRepositoryInfoByRemoteId", 42).Return(repo, nil)
+	mockDBRepository.On("StoreRepositoryCommits", &[]dto.CommitResponseDTO{{
+		SHA: "abc123", Message: "fix the parser", Author: "Alice", Date: "2024-07-01T12:00:00+02:00", URL: "https://github.com/testuser/testrepo/commit/abc123",
+	}}, repo).Return([]*models.Commit{{SHA: "abc123"}}, nil).Once()
+	mockTask.On("RefreshRepositoryFromHook", repo).Return(&models.Job{Model: gorm.Model{ID: 9}}, nil).Once()
+
+	rr := sendGithubHook(controller, "push", testHookSecret, `{
+		"ref": "refs/heads/main",
+		"repository": {"id": 42, "name": "testrepo", "owner": {"login": "testuser"}, "created_at": 1719835200},
+		"commits": [{"id": "abc123", "message": "fix the parser", "timestamp": "2024-07-01T12:00:00+02:00",
+			"url": "https://github.com/testuser/testrepo/commit/abc123", "author": {"name": "Alice"}}]
+	}`)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/jobs/9", rr.Header().Get("Location"))
+
+	// pushes to other branches aren't synced
+	rr = sendGithubHook(controller, "push", testHookSecret, `{
+		"ref": "refs/heads/feature",
+		"repository": {"id": 42, "name": "testrepo", "owner": {"login": "testuser"}},
+		"commits": [{"id": "def456", "message": "wip"}]
+	}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
+func TestReceiveGithubHook_PushToUntrackedRepository(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockTask := new(mocks.MockTask)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, mockTask, testHookSecret, nil)
+	mockDBRepository.On("GetRepositoryInfoByRemoteId", 43).Return(nil, nil)
+	mockDBRepository.On("GetUser", "testuser").Return(&models.User{Username: "testuser"}, nil)
+	mockTask.On("AddRequestToFetchNewlyRequestedRepoQueue", "testuser", "newrepo").Return(&models.Job{Model: gorm.Model{ID: 10}}, nil).Once()
+
+	rr := sendGithubHook(controller, "push", testHookSecret, `{
+		"ref": "refs/heads/main",
+		"repository": {"id": 43, "name": "newrepo", "owner": {"login": "testuser"}}
+	}`)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockDBRepository.AssertNotCalled(t, "StoreRepositoryCommits", mock.Anything, mock.Anything)
+	mockTask.AssertExpectations(t)
+}
+
+func TestReceiveGithubHook_RepositoryRenamed(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockTask := new(mocks.MockTask)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, mockTask, testHookSecret, nil)
+	user := &models.User{Model: gorm.Model{ID: 1}, Username: "testuser"}
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, RemoteID: 42, Name: "testrepo", Owner: user}
+	mockDBRepository.On("GetRepositoryInfoByRemoteId", 42).Return(repo, nil)
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("StoreRepositoryInfo", mock.MatchedBy(func(remote *dto.RepositoryInfoResponseDTO) bool {
+		return remote.ID == 42 && remote.Name == "renamed" && remote.StarsCount == 3 && remote.UpdatedAt == "2024-07-02T00:00:00Z"
+	}), user).Return(repo, nil).Once()
+	mockTask.On("RefreshRepositoryFromHook", repo).Return(&models.Job{Model: gorm.Model{ID: 9}}, nil).Once()
+
+	rr := sendGithubHook(controller, "repository", testHookSecret, `{
+		"action": "renamed",
+		"repository": {"id": 42, "name": "renamed", "owner": {"login": "testuser"}, "stargazers_count": 3,
+			"created_at": "2024-01-01T00:00:00Z", "updated_at": "2024-07-02T00:00:00Z"}
+	}`)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
+func TestReceiveGithubHook_RepositoryDeleted(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockTask := new(mocks.MockTask)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, mockTask, testHookSecret, nil)
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, RemoteID: 42, Name: "testrepo"}
+	mockDBRepository.On("GetRepositoryInfoByRemoteId", 42).Return(repo, nil)
+	mockDBRepository.On("DeleteRepository", repo).Return(nil).Once()
+
+	rr := sendGithubHook(controller, "repository", testHookSecret, `{
+		"action": "deleted", "repository": {"id": 42, "name": "testrepo", "owner": {"login": "testuser"}}
+	}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertNotCalled(t, "RefreshRepositoryFromHook", mock.Anything)
+}
@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/tasks"
+	"github.com/midedickson/github-service/utils"
+)
+
+// respond to a failed enqueue; a full queue is the client's cue to back off rather than a server error,
+// and a repository github recently couldn't find isn't fetched again
+func dispatchEnqueueError(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrRepoNotFound) {
+		utils.Dispatch404Error(w, "Repository not found on Github", nil)
+		return
+	}
+	if errors.Is(err, tasks.ErrQueueFull) {
+		w.Header().Set("Retry-After", "60")
+		utils.Dispatch503Error(w, "Too many jobs are queued; please try again later", nil)
+		return
+	}
+	utils.Dispatch500Error(w, err)
+}
+
+// where clients can follow a queued job
+func jobLocation(job *models.Job) string {
+	return fmt.Sprintf("/jobs/%d", job.ID)
+}
+
+func (c *Controller) GetJob(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.GetIDPathParam(r, "id")
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err.Error())
+		return
+	}
+	job, err := c.task.GetJob(r.Context(), id)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	if job == nil {
+		utils.Dispatch404Error(w, "Job not found", nil)
+		return
+	}
+	utils.Dispatch200(w, "Job Fetched Successfully", job)
+}
+
+func (c *Controller) GetSyncStatus(w http.ResponseWriter, r *http.Request) {
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
+		return
+	}
+	user, err := c.dbRepository.GetUser(r.Context(), owner)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	if user == nil {
+		utils.Dispatch404Error(w, "User with this github username not found, please register this github username", nil)
+		return
+	}
+	status, err := c.task.GetSyncStatus(r.Context(), user.Username)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Sync Status Fetched Successfully", status)
+}
+
+func (c *Controller) GetQueueStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := c.task.GetQueueStats(r.Context())
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Queue Stats Fetched Successfully", stats)
+}
+
+func (c *Controller) GetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	deadLetters, err := c.dbRepository.GetDeadLetters(r.Context(), r.URL.Query().Get("type"))
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Dead Letters Fetched Successfully", deadLetters)
+}
+
+// resolve the {id} path param to a dead letter, dispatching the error response if that fails
+func (c *Controller) getDeadLetter(w http.ResponseWriter, r *http.Request) (*models.DeadLetter, bool) {
+	id, err := utils.GetIDPathParam(r, "id")
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err.Error())
+		return nil, false
+	}
+	deadLetter, err := c.dbRepository.GetDeadLetter(r.Context(), id)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return nil, false
+	}
+	if deadLetter == nil {
+		utils.Dispatch404Error(w, "Dead letter not found", nil)
+		return nil, false
+	}
+	return deadLetter, true
+}
+
+func (c *Controller) GetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	deadLetter, ok := c.getDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	utils.Dispatch200(w, "Dead Letter Fetched Successfully", deadLetter)
+}
+
+func (c *Controller) RequeueDeadLetter(w http.ResponseWriter, r *http.Request) {
+	deadLetter, ok := c.getDeadLetter(w, r)
+	if !ok {
+		return
+	}
+	job, err := c.dbRepository.RequeueDeadLetter(r.Context(), deadLetter)
+	if errors.Is(err, database.ErrJobNotFailed) {
+		utils.Dispatch409Error(w, "Job has already been requeued", nil)
+		return
+	}
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Job Requeued Successfully", job)
+}
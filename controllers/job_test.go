@@ -0,0 +1,249 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetJob(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	job := &models.Job{Type: models.JobTypeSyncCommits, State: models.JobStateRunning, Attempts: 1, PagesFetched: 3, CommitsStored: 250}
+	mockTask.On("GetJob", uint(42)).Return(job, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/jobs/{id}", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+
+	controller.GetJob(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response struct {
+		Success bool
+		Message string
+		Data    models.Job
+	}
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Job Fetched Successfully", response.Message)
+	assert.Equal(t, models.JobStateRunning, response.Data.State)
+	assert.Equal(t, 250, response.Data.CommitsStored)
+
+	// Assert that the expectations were met
+	mockTask.AssertExpectations(t)
+}
+
+func TestGetJob_NotFound(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	mockTask.On("GetJob", uint(42)).Return(nil, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/jobs/{id}", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "42"})
+
+	controller.GetJob(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Job not found", response.Message)
+
+	// Assert that the expectations were met
+	mockTask.AssertExpectations(t)
+}
+
+func TestGetSyncStatus(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	status := &dto.SyncStatusResponseDTO{Owner: "testuser", Syncing: true, JobCounts: map[string]int64{models.JobStatePending: 2}}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockTask.On("GetSyncStatus", "testuser").Return(status, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/{owner}/sync-status", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser"})
+
+	controller.GetSyncStatus(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Sync Status Fetched Successfully", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
+func TestGetSyncStatus_MissingOwner(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/{owner}/sync-status", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": ""})
+
+	controller.GetSyncStatus(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, false, response.Success)
+	assert.Equal(t, "Invalid Payload", response.Message)
+	assert.Equal(t, "owner is required", response.Data)
+	mockDBRepository.AssertNotCalled(t, "GetUser")
+}
+
+func TestGetDeadLetters(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	deadLetters := []*models.DeadLetter{{JobID: 1, JobType: models.JobTypeFetchRepo, Reason: models.DeadLetterReasonPermanent}}
+	mockDBRepository.On("GetDeadLetters", models.JobTypeFetchRepo).Return(deadLetters, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/dead-letters?type=fetch-repo", nil)
+	rr := httptest.NewRecorder()
+
+	controller.GetDeadLetters(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Dead Letters Fetched Successfully", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestRequeueDeadLetter(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	deadLetter := &models.DeadLetter{JobID: 7}
+	job := &models.Job{State: models.JobStatePending}
+	mockDBRepository.On("GetDeadLetter", uint(3)).Return(deadLetter, nil)
+	mockDBRepository.On("RequeueDeadLetter", deadLetter).Return(job, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("POST", "/dead-letters/{id}/requeue", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+
+	controller.RequeueDeadLetter(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Job Requeued Successfully", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestRequeueDeadLetter_AlreadyRequeued(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	deadLetter := &models.DeadLetter{JobID: 7}
+	mockDBRepository.On("GetDeadLetter", uint(3)).Return(deadLetter, nil)
+	mockDBRepository.On("RequeueDeadLetter", deadLetter).Return(nil, database.ErrJobNotFailed)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("POST", "/dead-letters/{id}/requeue", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "3"})
+
+	controller.RequeueDeadLetter(rr, req)
+
+	// Check the response status code
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetDeadLetter_InvalidID(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/dead-letters/{id}", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "abc"})
+
+	controller.GetDeadLetter(rr, req)
+
+	// Check the response status code
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockDBRepository.AssertNotCalled(t, "GetDeadLetter")
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/midedickson/github-service/utils"
+)
+
+const (
+	// points in a history response
+	defaultHistoryPoints = 30
+	maxHistoryPoints     = 1000
+
+	defaultTrendingWindow       = "7d"
+	defaultTrendingRepositories = 10
+	maxTrendingRepositories     = 100
+)
+
+var historyIntervals = []string{utils.IntervalHour, utils.IntervalDay, utils.IntervalWeek, utils.IntervalMonth}
+
+// read the metric query parameter, stars by default
+func parseMetric(r *http.Request) (string, error) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		return utils.MetricStars, nil
+	}
+	if !slices.Contains(utils.RepositoryMetrics, metric) {
+		return "", fmt.Errorf("metric must be one of %v", utils.RepositoryMetrics)
+	}
+	return metric, nil
+}
+
+// read metric, interval, from and to; without from the history covers the last 30 intervals up to to,
+// which defaults to now
+func parseMetricHistoryQuery(r *http.Request) (*utils.MetricHistoryQuery, error) {
+	metric, err := parseMetric(r)
+	if err != nil {
+		return nil, err
+	}
+	historyQuery := &utils.MetricHistoryQuery{Metric: metric, Interval: r.URL.Query().Get("interval"), To: time.Now()}
+	if historyQuery.Interval == "" {
+		historyQuery.Interval = utils.IntervalDay
+	}
+	if !slices.Contains(historyIntervals, historyQuery.Interval) {
+		return nil, fmt.Errorf("interval must be one of %v", historyIntervals)
+	}
+	from, to, err := utils.ParseDateRangeParams(r, "from", "to")
+	if err != nil {
+		return nil, err
+	}
+	if to != nil {
+		historyQuery.To = *to
+	}
+	historyQuery.From = utils.AddIntervals(historyQuery.To, historyQuery.Interval, 1-defaultHistoryPoints)
+	if from != nil {
+		historyQuery.From = *from
+	}
+	if historyQuery.To.Before(historyQuery.From) {
+		return nil, fmt.Errorf("to must not be before from")
+	}
+	if utils.AddIntervals(utils.IntervalStart(historyQuery.From, historyQuery.Interval), historyQuery.Interval, maxHistoryPoints).Before(historyQuery.To) {
+		return nil, fmt.Errorf("the range covers more than %d intervals; use a longer interval", maxHistoryPoints)
+	}
+	return historyQuery, nil
+}
+
+// read metric, window, top and owner of the trending repositories
+func parseTrendingQuery(r *http.Request) (*utils.TrendingQuery, error) {
+	metric, err := parseMetric(r)
+	if err != nil {
+		return nil, err
+	}
+	query := r.URL.Query()
+	trendingQuery := &utils.TrendingQuery{Metric: metric, Owner: query.Get("owner"), Top: defaultTrendingRepositories}
+	window := query.Get("window")
+	if window == "" {
+		window = defaultTrendingWindow
+	}
+	duration, err := utils.ParseWindow(window)
+	if err != nil {
+		return nil, err
+	}
+	trendingQuery.Since = time.Now().Add(-duration)
+	if value := query.Get("top"); value != "" {
+		top, err := strconv.Atoi(value)
+		if err != nil || top < 1 || top > maxTrendingRepositories {
+			return nil, fmt.Errorf("top must be between 1 and %d", maxTrendingRepositories)
+		}
+		trendingQuery.Top = top
+	}
+	return trendingQuery, nil
+}
+
+// a time series of one of a repository's metrics
+func (c *Controller) GetRepositoryHistory(w http.ResponseWriter, r *http.Request) {
+	historyQuery, err := parseMetricHistoryQuery(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid query parameters", err.Error())
+		return
+	}
+	repo, ok := c.getOwnerRepository(w, r)
+	if !ok {
+		return
+	}
+	history, err := c.dbRepository.GetRepositoryMetricHistory(r.Context(), repo, historyQuery)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Repository History Fetched Successfully", history)
+}
+
+// the repositories whose metric grew most over a window, across every stored repository or those of ?owner=
+func (c *Controller) GetTrendingRepositories(w http.ResponseWriter, r *http.Request) {
+	trendingQuery, err := parseTrendingQuery(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid query parameters", err.Error())
+		return
+	}
+	trending, err := c.dbRepository.GetTrendingRepositories(r.Context(), trendingQuery)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Trending Repositories Fetched Successfully", trending)
+}
@@ -0,0 +1,82 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetRepositoryHistory(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	user := &models.User{Username: "testuser"}
+	repo := &models.Repository{Name: "testrepo"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(repo, nil)
+	mockDBRepository.On("GetRepositoryMetricHistory", repo, &utils.MetricHistoryQuery{
+		Metric: utils.MetricForks, Interval: utils.IntervalWeek,
+		From: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 31, 23, 59, 59, 999999999, time.UTC),
+	}).Return(&dto.MetricHistoryResponseDTO{Metric: utils.MetricForks, Points: []*dto.MetricPointDTO{{Value: 3}}}, nil)
+
+	req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/history?metric=forks&interval=week&from=2024-07-01&to=2024-07-31", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+	controller.GetRepositoryHistory(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Repository History Fetched Successfully", response.Message)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetRepositoryHistory_InvalidQuery(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+
+	for _, query := range []string{"metric=likes", "interval=minute", "from=yesterday", "from=2024-07-02&to=2024-07-01", "interval=hour&from=2020-01-01&to=2024-01-01"} {
+		req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/history?"+query, nil)
+		rr := httptest.NewRecorder()
+		req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+		controller.GetRepositoryHistory(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetTrendingRepositories(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	before := time.Now()
+	mockDBRepository.On("GetTrendingRepositories", mock.MatchedBy(func(q *utils.TrendingQuery) bool {
+		// a 3 day window
+		since := before.Add(-72 * time.Hour)
+		return q.Metric == utils.MetricStars && q.Owner == "testuser" && q.Top == 5 &&
+			!q.Since.Before(since) && q.Since.Before(since.Add(time.Minute))
+	})).Return([]*dto.TrendingRepositoryResponseDTO{{RepositoryName: "testrepo", Growth: 4}}, nil)
+
+	req, _ := http.NewRequest("GET", "/repos/trending?window=3d&top=5&owner=testuser", nil)
+	rr := httptest.NewRecorder()
+	controller.GetTrendingRepositories(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+
+	for _, query := range []string{"window=0d", "window=week", "top=0", "metric=likes"} {
+		req, _ := http.NewRequest("GET", "/repos/trending?"+query, nil)
+		rr := httptest.NewRecorder()
+		controller.GetTrendingRepositories(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -8,25 +9,15 @@ import (
 )
 
 func (c *Controller) GetRepositoryInfo(w http.ResponseWriter, r *http.Request) {
-	owner, err := utils.GetPathParam(r, "owner")
-	if err != nil {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
-		return
-	}
-	if owner == "" {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
-		return
-	}
-	repoName, err := utils.GetPathParam(r, "repo")
-	if err != nil {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
 		return
 	}
-	if repoName == "" {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
+	repoName, ok := requirePathParam(w, r, "repo")
+	if !ok {
 		return
 	}
-	user, err := c.dbRepository.GetUser(owner)
+	user, err := c.dbRepository.GetUser(r.Context(), owner)
 	if err != nil {
 		utils.Dispatch500Error(w, err)
 		return
@@ -35,14 +26,18 @@ func (c *Controller) GetRepositoryInfo(w http.ResponseWriter, r *http.Request) {
 		utils.Dispatch404Error(w, "User with this github username not found, please register this github username", err)
 		return
 	}
-	repo, err := c.dbRepository.GetRepository(user.ID, repoName)
+	repo, err := c.dbRepository.GetRepository(r.Context(), user.ID, repoName)
 	if err != nil {
 		utils.Dispatch500Error(w, err)
 		return
 	}
 	if repo == nil {
-		go c.task.AddRequestToFetchNewlyRequestedRepoQueue(user.Username, repoName)
-		utils.Dispatch404Error(w, "Repository not found on Github; kindly check back again.", err)
+		job, err := c.task.AddRequestToFetchNewlyRequestedRepoQueue(r.Context(), user.Username, repoName)
+		if err != nil {
+			dispatchEnqueueError(w, err)
+			return
+		}
+		utils.Dispatch202(w, "Repository is being fetched from Github; follow the job for its progress.", jobLocation(job), job)
 		return
 	}
 
@@ -50,32 +45,64 @@ func (c *Controller) GetRepositoryInfo(w http.ResponseWriter, r *http.Request) {
 }
 
 func (c *Controller) GetRepositoryCommits(w http.ResponseWriter, r *http.Request) {
-	repoName, err := utils.GetPathParam(r, "repo")
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
+		return
+	}
+	repoName, ok := requirePathParam(w, r, "repo")
+	if !ok {
+		return
+	}
+	list, err := utils.ParseListParams(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid pagination parameters", err.Error())
+		return
+	}
+	filters, err := utils.ParseCommitFilters(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid commit filters", err.Error())
+		return
+	}
+	commits, page, err := c.dbRepository.GetRepositoryCommits(r.Context(), owner, repoName, filters, list)
 	if err != nil {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
+		dispatchListError(w, err)
 		return
 	}
-	if repoName == "" {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
+	utils.Dispatch200Page(w, "Repository Commits Fetched Successfully", commits, page.Meta(r))
+}
+
+func (c *Controller) GetRepositoryCommit(w http.ResponseWriter, r *http.Request) {
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
+		return
+	}
+	repoName, ok := requirePathParam(w, r, "repo")
+	if !ok {
 		return
 	}
-	commits, err := c.dbRepository.GetRepositoryCommits(repoName)
+	sha, ok := requirePathParam(w, r, "sha")
+	if !ok {
+		return
+	}
+	commit, err := c.dbRepository.GetRepositoryCommit(r.Context(), owner, repoName, sha)
 	if err != nil {
-		log.Printf("%v", err)
 		utils.Dispatch500Error(w, err)
 		return
 	}
-	utils.Dispatch200(w, "Repository Commits Fetched Successfully", commits)
+	if commit == nil {
+		utils.Dispatch404Error(w, "Commit not found", nil)
+		return
+	}
+	utils.Dispatch200(w, "Repository Commit Fetched Successfully", commit)
 }
 
 func (c *Controller) GetRepositories(w http.ResponseWriter, r *http.Request) {
 	repoSearchParams := &utils.RepositorySearchParams{}
-	owner, err := utils.GetPathParam(r, "owner")
-	if err != nil || owner == "" {
-		utils.Dispatch400Error(w, "Invalid Payload", err)
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
 		return
 	}
-	user, err := c.dbRepository.GetUser(owner)
+	user, err := c.dbRepository.GetUser(r.Context(), owner)
 	if err != nil {
 		utils.Dispatch500Error(w, err)
 		return
@@ -85,11 +112,25 @@ func (c *Controller) GetRepositories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	utils.ParseQueryParams(r, repoSearchParams)
-	repositories, err := c.dbRepository.SearchRepository(user.ID, repoSearchParams)
+	list, err := utils.ParseListParams(r)
 	if err != nil {
-		log.Printf("%v", err)
-		utils.Dispatch500Error(w, err)
+		utils.Dispatch400Error(w, "Invalid pagination parameters", err.Error())
+		return
+	}
+	repositories, page, err := c.dbRepository.SearchRepository(r.Context(), user.ID, repoSearchParams, list)
+	if err != nil {
+		dispatchListError(w, err)
+		return
+	}
+	utils.Dispatch200Page(w, "Repositories Fetched Successfully", repositories, page.Meta(r))
+}
+
+// a sort or cursor the list doesn't know is the client's mistake; anything else is ours
+func dispatchListError(w http.ResponseWriter, err error) {
+	if errors.Is(err, utils.ErrInvalidSort) || errors.Is(err, utils.ErrInvalidCursor) {
+		utils.Dispatch400Error(w, "Invalid pagination parameters", err.Error())
 		return
 	}
-	utils.Dispatch200(w, "Repositories Fetched Successfully", repositories)
+	log.Printf("%v", err)
+	utils.Dispatch500Error(w, err)
 }
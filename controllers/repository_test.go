@@ -4,13 +4,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"sync"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/midedickson/github-service/controllers"
 	"github.com/midedickson/github-service/mocks"
 	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/tasks"
 	"github.com/midedickson/github-service/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -23,18 +24,28 @@ func TestGetRepositoryCommits(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	// Test cases
 	tests := []struct {
 		name          string
+		owner         string
 		repoName      string
 		mockSetup     func()
 		expectedCode  int
 		expectedError string
 	}{
+		{
+			name:          "Invalid owner path parameter",
+			owner:         "",
+			repoName:      "testrepo",
+			mockSetup:     func() {},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: "Invalid Payload",
+		},
 		{
 			name:          "Invalid repo path parameter",
+			owner:         "testuser",
 			repoName:      "",
 			mockSetup:     func() {},
 			expectedCode:  http.StatusBadRequest,
@@ -42,21 +53,23 @@ func TestGetRepositoryCommits(t *testing.T) {
 		},
 		{
 			name:     "Database error while fetching commits",
+			owner:    "testuser",
 			repoName: "testrepo",
 			mockSetup: func() {
-				mockDBRepository.On("GetRepositoryCommits", "testrepo").Return([]*models.Commit{}, assert.AnError)
+				mockDBRepository.On("GetRepositoryCommits", "testuser", "testrepo", mock.Anything, mock.Anything).Return([]*models.Commit{}, nil, assert.AnError)
 			},
 			expectedCode:  http.StatusInternalServerError,
 			expectedError: "assert.AnError general error for testing",
 		},
 		{
 			name:     "Successful fetch of repository commits",
+			owner:    "testuser",
 			repoName: "testrepox",
 			mockSetup: func() {
 				commits := []*models.Commit{
-					{SHA: "commitsha", Message: "commit message", Author: "author", Date: "date"},
+					{SHA: "commitsha", Message: "commit message", Author: "author", Date: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
 				}
-				mockDBRepository.On("GetRepositoryCommits", "testrepox").Return(commits, nil)
+				mockDBRepository.On("GetRepositoryCommits", "testuser", "testrepox", &utils.CommitFilters{}, &utils.ListParams{PerPage: utils.DefaultPerPage}).Return(commits, &utils.PageInfo{Total: 1, PerPage: utils.DefaultPerPage}, nil)
 			},
 			expectedCode:  http.StatusOK,
 			expectedError: "",
@@ -69,9 +82,9 @@ func TestGetRepositoryCommits(t *testing.T) {
 			tt.mockSetup()
 
 			// Create a new HTTP request
-			req, _ := http.NewRequest("GET", "/repos/{repo}/commits", nil)
+			req, _ := http.NewRequest("GET", "/{owner}/repos/{repo}/commits", nil)
 			rr := httptest.NewRecorder()
-			req = mux.SetURLVars(req, map[string]string{"repo": tt.repoName})
+			req = mux.SetURLVars(req, map[string]string{"owner": tt.owner, "repo": tt.repoName})
 
 			// Call the GetRepositoryCommits method
 			controller.GetRepositoryCommits(rr, req)
@@ -96,6 +109,95 @@ func TestGetRepositoryCommits(t *testing.T) {
 	}
 }
 
+func TestGetRepositoryCommits_Filters(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	since := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	// a plain day as until covers the whole day
+	until := time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC).Add(-time.Nanosecond)
+	filters := &utils.CommitFilters{Author: "alice", Message: "fix", SHAPrefix: "abc", Since: &since, Until: &until}
+	mockDBRepository.On("GetRepositoryCommits", "testuser", "testrepo", filters, mock.Anything).Return([]*models.Commit{}, &utils.PageInfo{}, nil)
+
+	req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/commits?author=alice&message=fix&sha=ABC&since=2024-07-01&until=2024-07-01", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+	controller.GetRepositoryCommits(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	for _, query := range []string{"since=yesterday", "sha=xyz", "since=2024-07-02&until=2024-07-01"} {
+		req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/commits?"+query, nil)
+		rr := httptest.NewRecorder()
+		req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+		controller.GetRepositoryCommits(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		var response utils.APIResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		assert.Equal(t, "Invalid commit filters", response.Message, query)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetRepositoryCommit(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	mockDBRepository.On("GetRepositoryCommit", "testuser", "testrepo", "abc123").Return(&models.Commit{SHA: "abc123"}, nil)
+	mockDBRepository.On("GetRepositoryCommit", "testuser", "testrepo", "fff").Return(nil, nil)
+
+	for sha, expectedCode := range map[string]int{"abc123": http.StatusOK, "fff": http.StatusNotFound} {
+		req, _ := http.NewRequest("GET", "/testuser/repos/testrepo/commits/"+sha, nil)
+		rr := httptest.NewRecorder()
+		req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo", "sha": sha})
+		controller.GetRepositoryCommit(rr, req)
+		assert.Equal(t, expectedCode, rr.Code, sha)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetRepositories_Paginates(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	user := &models.User{Username: "testuser"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("SearchRepository", user.ID, &utils.RepositorySearchParams{Language: "Go"}, &utils.ListParams{PerPage: 2, Cursor: "abc", Sort: "stars", Order: "asc"}).
+		Return([]*models.Repository{{Name: "api"}, {Name: "web"}}, &utils.PageInfo{Total: 5, PerPage: 2, NextCursor: "def", PrevCursor: "xyz"}, nil)
+
+	req, _ := http.NewRequest("GET", "/testuser/repos?language=Go&per_page=2&cursor=abc&sort=stars&order=asc", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser"})
+	controller.GetRepositories(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	if assert.NotNil(t, response.Meta) {
+		assert.Equal(t, int64(5), response.Meta.Total)
+		assert.Equal(t, "/testuser/repos?cursor=def&language=Go&order=asc&per_page=2&sort=stars", response.Meta.Next)
+		assert.Equal(t, "/testuser/repos?cursor=xyz&language=Go&order=asc&per_page=2&sort=stars", response.Meta.Prev)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestGetRepositories_InvalidPagination(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	user := &models.User{Username: "testuser"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("SearchRepository", user.ID, mock.Anything, mock.Anything).Return([]*models.Repository{}, nil, utils.ErrInvalidSort)
+
+	for _, query := range []string{"page=0", "per_page=500", "order=up", "page=2&cursor=abc", "sort=size"} {
+		req, _ := http.NewRequest("GET", "/testuser/repos?"+query, nil)
+		rr := httptest.NewRecorder()
+		req = mux.SetURLVars(req, map[string]string{"owner": "testuser"})
+		controller.GetRepositories(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+		var response utils.APIResponse
+		json.Unmarshal(rr.Body.Bytes(), &response)
+		assert.Equal(t, "Invalid pagination parameters", response.Message, query)
+	}
+}
+
 func TestGetRepositoryInfo_InvalidOwnerPathParameter(t *testing.T) {
 	// Initialize the mocks
 	mockDBRepository := new(mocks.MockDBRepository)
@@ -103,7 +205,7 @@ func TestGetRepositoryInfo_InvalidOwnerPathParameter(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	// Create a new HTTP request
 	req, _ := http.NewRequest("GET", "/repos/{owner}/{repo}", nil)
@@ -132,7 +234,7 @@ func TestGetRepositoryInfo_UserNotFoundInDatabase(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	mockDBRepository.On("GetUser", "testuser").Return(nil, nil)
 
@@ -163,7 +265,7 @@ func TestGetRepositoryInfo_DatabaseErrorWhileFetchingUser(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	mockDBRepository.On("GetUser", "testuser").Return(nil, assert.AnError)
 
@@ -194,7 +296,7 @@ func TestGetRepositoryInfo_InvalidRepoPathParameter(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	// Create a new HTTP request
 	req, _ := http.NewRequest("GET", "/repos/{owner}/{repo}", nil)
@@ -223,17 +325,15 @@ func TestGetRepositoryInfo_RepositoryNotFoundInDatabase(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	user := &models.User{Username: "testuser"}
 	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
 	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(nil, nil)
 
-	var wg sync.WaitGroup
-	wg.Add(1)
-	mockTask.On("AddRequestToFetchNewlyRequestedRepoQueue", "testuser", "testrepo").Run(func(args mock.Arguments) {
-		wg.Done()
-	}).Return()
+	job := &models.Job{Type: models.JobTypeFetchRepo, State: models.JobStatePending}
+	job.ID = 42
+	mockTask.On("AddRequestToFetchNewlyRequestedRepoQueue", "testuser", "testrepo").Return(job, nil)
 
 	// Create a new HTTP request
 	req, _ := http.NewRequest("GET", "/repos/{owner}/{repo}", nil)
@@ -242,20 +342,78 @@ func TestGetRepositoryInfo_RepositoryNotFoundInDatabase(t *testing.T) {
 
 	// Call the GetRepositoryInfo method
 	controller.GetRepositoryInfo(rr, req)
-	wg.Wait()
 
 	// Check the response status code and body
-	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/jobs/42", rr.Header().Get("Location"))
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Repository is being fetched from Github; follow the job for its progress.", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
+func TestGetRepositoryInfo_QueueFull(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(nil, nil)
+	mockTask.On("AddRequestToFetchNewlyRequestedRepoQueue", "testuser", "testrepo").Return(nil, tasks.ErrQueueFull)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("GET", "/repos/{owner}/{repo}", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+
+	// Call the GetRepositoryInfo method
+	controller.GetRepositoryInfo(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
 	var response utils.APIResponse
 	json.Unmarshal(rr.Body.Bytes(), &response)
 	assert.Equal(t, false, response.Success)
-	assert.Equal(t, "Repository not found on Github; kindly check back again.", response.Message)
 
 	// Assert that the expectations were met
 	mockDBRepository.AssertExpectations(t)
 	mockTask.AssertExpectations(t)
 }
 
+func TestGetRepositoryInfo_RecentlyNotFoundOnGithub(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "missing").Return(nil, nil)
+	mockTask.On("AddRequestToFetchNewlyRequestedRepoQueue", "testuser", "missing").Return(nil, utils.ErrRepoNotFound)
+
+	req, _ := http.NewRequest("GET", "/repos/{owner}/{repo}", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "missing"})
+	controller.GetRepositoryInfo(rr, req)
+
+	// polling a repository github doesn't have is answered with a 404 rather than another fetch
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
 func TestGetRepositoryInfo_DatabaseErrorWhileFetchingRepository(t *testing.T) {
 	// Initialize the mocks
 	mockDBRepository := new(mocks.MockDBRepository)
@@ -263,7 +421,7 @@ func TestGetRepositoryInfo_DatabaseErrorWhileFetchingRepository(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	user := &models.User{Username: "testuser"}
 	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
@@ -296,7 +454,7 @@ func TestGetRepositoryInfo_SuccessfulFetchOfRepositoryInformation(t *testing.T)
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	user := &models.User{Username: "testuser"}
 	repo := &models.Repository{Name: "testrepo"}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/midedickson/github-service/utils"
+)
+
+// read q, type, owner, repo, page and per_page from the query string. results are ordered by
+// rank, so they can only be paged through by page number
+func parseSearchQuery(r *http.Request) (*utils.SearchQuery, error) {
+	query := r.URL.Query()
+	searchQuery := &utils.SearchQuery{
+		Text:     strings.TrimSpace(query.Get("q")),
+		Type:     query.Get("type"),
+		Owner:    query.Get("owner"),
+		RepoName: query.Get("repo"),
+	}
+	if searchQuery.Text == "" {
+		return nil, fmt.Errorf("q is required")
+	}
+	switch searchQuery.Type {
+	case "":
+		searchQuery.Type = utils.SearchTypeCommits
+	case utils.SearchTypeCommits, utils.SearchTypeRepositories:
+	default:
+		return nil, fmt.Errorf("type must be %s or %s", utils.SearchTypeCommits, utils.SearchTypeRepositories)
+	}
+	if searchQuery.RepoName != "" && searchQuery.Owner == "" {
+		return nil, fmt.Errorf("repo needs an owner")
+	}
+	list, err := utils.ParseListParams(r)
+	if err != nil {
+		return nil, err
+	}
+	if list.Cursor != "" || list.Sort != "" || list.Order != "" {
+		return nil, fmt.Errorf("search results are ordered by rank and paged by page number")
+	}
+	searchQuery.Page, searchQuery.PerPage = max(list.Page, 1), list.PerPage
+	return searchQuery, nil
+}
+
+// full text search over the stored commits or repositories
+func (c *Controller) Search(w http.ResponseWriter, r *http.Request) {
+	searchQuery, err := parseSearchQuery(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid search", err.Error())
+		return
+	}
+	results, page, err := c.dbRepository.Search(r.Context(), searchQuery)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200Page(w, "Search Results Fetched Successfully", results, page.Meta(r))
+}
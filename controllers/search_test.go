@@ -0,0 +1,61 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearch(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	mockDBRepository.On("Search", &utils.SearchQuery{Text: "fix parser", Type: utils.SearchTypeRepositories, Owner: "testuser", Page: 2, PerPage: 10}).
+		Return([]*dto.SearchResultResponseDTO{{Type: "repository", Owner: "testuser", RepositoryName: "parsers", Snippet: "<mark>parser</mark>s"}},
+			&utils.PageInfo{Total: 11, Page: 2, PerPage: 10, HasPrev: true}, nil)
+
+	req, _ := http.NewRequest("GET", "/search?q=fix+parser&type=repos&owner=testuser&page=2&per_page=10", nil)
+	rr := httptest.NewRecorder()
+	controller.Search(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Search Results Fetched Successfully", response.Message)
+	assert.Equal(t, int64(11), response.Meta.Total)
+	assert.Contains(t, response.Meta.Prev, "page=1")
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestSearch_DefaultsToCommits(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	mockDBRepository.On("Search", &utils.SearchQuery{Text: "fix", Type: utils.SearchTypeCommits, Page: 1, PerPage: utils.DefaultPerPage}).
+		Return([]*dto.SearchResultResponseDTO{}, &utils.PageInfo{Page: 1, PerPage: utils.DefaultPerPage}, nil)
+
+	req, _ := http.NewRequest("GET", "/search?q=fix", nil)
+	rr := httptest.NewRecorder()
+	controller.Search(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestSearch_InvalidQuery(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+
+	for _, query := range []string{"", "q=+", "q=fix&type=users", "q=fix&repo=api", "q=fix&cursor=abc", "q=fix&sort=stars", "q=fix&page=0"} {
+		req, _ := http.NewRequest("GET", "/search?"+query, nil)
+		rr := httptest.NewRecorder()
+		controller.Search(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
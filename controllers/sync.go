@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// resolve the {owner} and {repo} path params to a stored repository, dispatching the error response if that fails
+func (c *Controller) getOwnerRepository(w http.ResponseWriter, r *http.Request) (*models.Repository, bool) {
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
+		return nil, false
+	}
+	repoName, ok := requirePathParam(w, r, "repo")
+	if !ok {
+		return nil, false
+	}
+	user, err := c.dbRepository.GetUser(r.Context(), owner)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return nil, false
+	}
+	if user == nil {
+		utils.Dispatch404Error(w, "User with this github username not found, please register this github username", err)
+		return nil, false
+	}
+	repo, err := c.dbRepository.GetRepository(r.Context(), user.ID, repoName)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return nil, false
+	}
+	if repo == nil {
+		utils.Dispatch404Error(w, "Repository not found", err)
+		return nil, false
+	}
+	return repo, true
+}
+
+// parse an optional date from a payload; nil means unset
+func parseOptionalDate(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	parsed, err := utils.ParseDate(*value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func (c *Controller) UpdateRepositorySyncSettings(w http.ResponseWriter, r *http.Request) {
+	repo, ok := c.getOwnerRepository(w, r)
+	if !ok {
+		return
+	}
+	var syncSettingsPayload dto.SyncSettingsPayloadDTO
+	if err := json.NewDecoder(r.Body).Decode(&syncSettingsPayload); err != nil {
+		log.Printf("Error decoding sync settings payload: %v", err)
+		utils.Dispatch400Error(w, "Invalid Payload", err)
+		return
+	}
+	since, err := parseOptionalDate(syncSettingsPayload.SyncCommitsSince)
+	if err != nil {
+		utils.Dispatch400Error(w, err.Error(), nil)
+		return
+	}
+	if err := c.dbRepository.SetRepositorySyncSince(r.Context(), repo, since); err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Repository Sync Settings Updated Successfully", repo)
+}
+
+func (c *Controller) BackfillRepositoryCommits(w http.ResponseWriter, r *http.Request) {
+	repo, ok := c.getOwnerRepository(w, r)
+	if !ok {
+		return
+	}
+	var backfillPayload dto.BackfillPayloadDTO
+	// the payload is optional
+	if err := json.NewDecoder(r.Body).Decode(&backfillPayload); err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("Error decoding backfill payload: %v", err)
+		utils.Dispatch400Error(w, "Invalid Payload", err)
+		return
+	}
+	since, err := parseOptionalDate(backfillPayload.Since)
+	if err != nil {
+		utils.Dispatch400Error(w, err.Error(), nil)
+		return
+	}
+	if since == nil {
+		since = repo.SyncCommitsSince
+	}
+	backfill, err := c.dbRepository.CreateCommitBackfill(r.Context(), repo, since)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	job, err := c.task.AddRepositoryToBackfillQueue(r.Context(), backfill)
+	if err != nil {
+		dispatchEnqueueError(w, err)
+		return
+	}
+	utils.Dispatch202(w, "Repository Commit Backfill Started", jobLocation(job), backfill)
+}
+
+// parse the optional ?full= flag of the sync endpoints
+func parseFullSync(r *http.Request) (bool, error) {
+	value := r.URL.Query().Get("full")
+	if value == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// answer a sync request with the job doing the sync, which is an earlier one when a sync was already queued
+func dispatchSyncJob(w http.ResponseWriter, job *models.Job, queued bool) {
+	message := "Sync Started"
+	if !queued {
+		message = "A sync is already in progress; follow the existing job"
+	}
+	utils.Dispatch202(w, message, jobLocation(job), job)
+}
+
+func (c *Controller) SyncUser(w http.ResponseWriter, r *http.Request) {
+	owner, ok := requirePathParam(w, r, "owner")
+	if !ok {
+		return
+	}
+	full, err := parseFullSync(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid value for full", err)
+		return
+	}
+	user, err := c.dbRepository.GetUser(r.Context(), owner)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	if user == nil {
+		utils.Dispatch404Error(w, "User with this github username not found, please register this github username", err)
+		return
+	}
+	job, queued, err := c.task.SyncUser(r.Context(), user, full)
+	if err != nil {
+		dispatchEnqueueError(w, err)
+		return
+	}
+	dispatchSyncJob(w, job, queued)
+}
+
+func (c *Controller) SyncRepository(w http.ResponseWriter, r *http.Request) {
+	full, err := parseFullSync(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid value for full", err)
+		return
+	}
+	repo, ok := c.getOwnerRepository(w, r)
+	if !ok {
+		return
+	}
+	job, queued, err := c.task.SyncRepository(r.Context(), repo, full)
+	if err != nil {
+		dispatchEnqueueError(w, err)
+		return
+	}
+	dispatchSyncJob(w, job, queued)
+}
@@ -0,0 +1,206 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateRepositorySyncSettings(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	repo := &models.Repository{Name: "testrepo"}
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(repo, nil)
+	mockDBRepository.On("SetRepositorySyncSince", repo, &since).Return(nil)
+
+	// Create a new HTTP request
+	body := []byte(`{"syncCommitsSince": "2020-01-01"}`)
+	req, _ := http.NewRequest("PUT", "/{owner}/repos/{repo}/sync-settings", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+
+	controller.UpdateRepositorySyncSettings(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Repository Sync Settings Updated Successfully", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestUpdateRepositorySyncSettings_InvalidDate(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	repo := &models.Repository{Name: "testrepo"}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(repo, nil)
+
+	// Create a new HTTP request
+	body := []byte(`{"syncCommitsSince": "last tuesday"}`)
+	req, _ := http.NewRequest("PUT", "/{owner}/repos/{repo}/sync-settings", bytes.NewBuffer(body))
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+
+	controller.UpdateRepositorySyncSettings(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, false, response.Success)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestBackfillRepositoryCommits(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo := &models.Repository{Name: "testrepo", SyncCommitsSince: &since}
+	backfill := &models.CommitBackfill{Repository: repo, Status: models.BackfillStatusPending}
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(repo, nil)
+	mockDBRepository.On("CreateCommitBackfill", repo, &since).Return(backfill, nil)
+
+	job := &models.Job{Type: models.JobTypeBackfillCommits}
+	job.ID = 7
+	mockTask.On("AddRepositoryToBackfillQueue", backfill).Return(job, nil)
+
+	// Create a new HTTP request without a payload
+	req, _ := http.NewRequest("POST", "/{owner}/repos/{repo}/backfill", http.NoBody)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+
+	controller.BackfillRepositoryCommits(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/jobs/7", rr.Header().Get("Location"))
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, true, response.Success)
+	assert.Equal(t, "Repository Commit Backfill Started", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
+func TestSyncUser(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	job := &models.Job{Type: models.JobTypeFetchUserRepos}
+	job.ID = 7
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockTask.On("SyncUser", user, true).Return(job, true, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("POST", "/{owner}/sync?full=true", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser"})
+
+	controller.SyncUser(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/jobs/7", rr.Header().Get("Location"))
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "Sync Started", response.Message)
+
+	// Assert that the expectations were met
+	mockDBRepository.AssertExpectations(t)
+	mockTask.AssertExpectations(t)
+}
+
+func TestSyncRepository_ReturnsTheSyncAlreadyInProgress(t *testing.T) {
+	// Initialize the mocks
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	mockTask := new(mocks.MockTask)
+
+	// Create the controller with mocked dependencies
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
+
+	user := &models.User{Username: "testuser"}
+	repo := &models.Repository{Name: "testrepo"}
+	existing := &models.Job{Type: models.JobTypeRefreshRepo, State: models.JobStateRunning}
+	existing.ID = 3
+	mockDBRepository.On("GetUser", "testuser").Return(user, nil)
+	mockDBRepository.On("GetRepository", user.ID, "testrepo").Return(repo, nil)
+	mockTask.On("SyncRepository", repo, false).Return(existing, false, nil)
+
+	// Create a new HTTP request
+	req, _ := http.NewRequest("POST", "/{owner}/repos/{repo}/sync", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+
+	controller.SyncRepository(rr, req)
+
+	// Check the response status code and body
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/jobs/3", rr.Header().Get("Location"))
+	var response utils.APIResponse
+	json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.Equal(t, "A sync is already in progress; follow the existing job", response.Message)
+
+	// Assert that the expectations were met
+	mockTask.AssertExpectations(t)
+}
+
+func TestSyncRepository_InvalidFullFlag(t *testing.T) {
+	controller := controllers.NewController(new(mocks.MockRequester), new(mocks.MockDBRepository), new(mocks.MockTask), "", nil)
+
+	req, _ := http.NewRequest("POST", "/{owner}/repos/{repo}/sync?full=maybe", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"owner": "testuser", "repo": "testrepo"})
+
+	controller.SyncRepository(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
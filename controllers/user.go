@@ -19,11 +19,16 @@ func (c *Controller) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := c.dbRepository.CreateUser(&createUserPayload)
+	user, err := c.dbRepository.CreateUser(r.Context(), &createUserPayload)
 	if err != nil {
 		utils.Dispatch500Error(w, err)
 		return
 	}
-	go c.task.AddUserToGetAllRepoQueue(user)
-	utils.Dispatch200(w, "user created successfully", user)
+	job, err := c.task.AddUserToGetAllRepoQueue(r.Context(), user)
+	if err != nil {
+		dispatchEnqueueError(w, err)
+		return
+	}
+	// the user is stored, but their repositories are only fetched once the job has run
+	utils.Dispatch202(w, "user created successfully", jobLocation(job), user)
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"sync"
 	"testing"
 
 	"github.com/midedickson/github-service/controllers"
@@ -14,7 +13,7 @@ import (
 	"github.com/midedickson/github-service/models"
 	"github.com/midedickson/github-service/utils"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
 )
 
 func TestCreateUser(t *testing.T) {
@@ -24,7 +23,7 @@ func TestCreateUser(t *testing.T) {
 	mockTask := new(mocks.MockTask)
 
 	// Create the controller with mocked dependencies
-	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask)
+	controller := controllers.NewController(mockRequester, mockDBRepository, mockTask, "", nil)
 
 	// Define the input payload and the expected user
 	createUserPayload := &dto.CreateUserPayloadDTO{
@@ -36,11 +35,7 @@ func TestCreateUser(t *testing.T) {
 
 	// Set up the expectations
 	mockDBRepository.On("CreateUser", createUserPayload).Return(user, nil)
-	var wg sync.WaitGroup
-	wg.Add(1)
-	mockTask.On("AddUserToGetAllRepoQueue", user).Run(func(args mock.Arguments) {
-		wg.Done()
-	}).Return()
+	mockTask.On("AddUserToGetAllRepoQueue", user).Return(&models.Job{Model: gorm.Model{ID: 3}}, nil)
 
 	// Create a new HTTP request with the input payload
 	body, _ := json.Marshal(createUserPayload)
@@ -49,10 +44,10 @@ func TestCreateUser(t *testing.T) {
 
 	// Call the CreateUser method
 	controller.CreateUser(rr, req)
-	wg.Wait()
 
 	// Check the response status code and body
-	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/jobs/3", rr.Header().Get("Location"))
 	var response utils.APIResponse
 	json.Unmarshal(rr.Body.Bytes(), &response)
 	assert.Equal(t, true, response.Success)
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// check a subscription request: an http(s) url, a secret to sign payloads with and at least one known event
+func parseWebhookSubscription(payload *dto.CreateWebhookPayloadDTO) (*models.WebhookSubscription, error) {
+	target, err := url.Parse(payload.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("url must be an http or https url")
+	}
+	if payload.Secret == "" {
+		return nil, fmt.Errorf("secret is required")
+	}
+	if len(payload.Events) == 0 {
+		return nil, fmt.Errorf("events must name at least one of %s", strings.Join(models.WebhookEvents, ", "))
+	}
+	events := []string{}
+	for _, event := range payload.Events {
+		if !slices.Contains(models.WebhookEvents, event) {
+			return nil, fmt.Errorf("unknown event %q, expected one of %s", event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !slices.Contains(events, event) {
+			events = append(events, event)
+		}
+	}
+	return &models.WebhookSubscription{URL: payload.URL, Secret: payload.Secret, Events: events}, nil
+}
+
+// where clients can follow a webhook delivery
+func webhookDeliveryLocation(delivery *models.WebhookDelivery) string {
+	return fmt.Sprintf("/webhooks/%d/deliveries/%d", delivery.SubscriptionID, delivery.ID)
+}
+
+func (c *Controller) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var createWebhookPayload dto.CreateWebhookPayloadDTO
+	if err := json.NewDecoder(r.Body).Decode(&createWebhookPayload); err != nil {
+		log.Printf("Error decoding create webhook payload: %v", err)
+		utils.Dispatch400Error(w, "Invalid Payload", err)
+		return
+	}
+	subscription, err := parseWebhookSubscription(&createWebhookPayload)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err.Error())
+		return
+	}
+	if err := c.dbRepository.CreateWebhookSubscription(r.Context(), subscription); err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Webhook Created Successfully", subscription)
+}
+
+func (c *Controller) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	subscriptions, err := c.dbRepository.GetWebhookSubscriptions(r.Context())
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Webhooks Fetched Successfully", subscriptions)
+}
+
+// resolve the {id} path param to a webhook subscription, dispatching the error response if that fails
+func (c *Controller) getWebhookSubscription(w http.ResponseWriter, r *http.Request) (*models.WebhookSubscription, bool) {
+	id, err := utils.GetIDPathParam(r, "id")
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err.Error())
+		return nil, false
+	}
+	subscription, err := c.dbRepository.GetWebhookSubscription(r.Context(), id)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return nil, false
+	}
+	if subscription == nil {
+		utils.Dispatch404Error(w, "Webhook not found", nil)
+		return nil, false
+	}
+	return subscription, true
+}
+
+func (c *Controller) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := c.getWebhookSubscription(w, r)
+	if !ok {
+		return
+	}
+	utils.Dispatch200(w, "Webhook Fetched Successfully", subscription)
+}
+
+func (c *Controller) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := c.getWebhookSubscription(w, r)
+	if !ok {
+		return
+	}
+	if err := c.dbRepository.DeleteWebhookSubscription(r.Context(), subscription); err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch200(w, "Webhook Deleted Successfully", subscription)
+}
+
+// the delivery log of a subscription, newest first; ?state= narrows it down to one state
+func (c *Controller) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	states := []string{models.WebhookDeliveryPending, models.WebhookDeliveryRetrying, models.WebhookDeliveryDelivered, models.WebhookDeliveryFailed}
+	if state != "" && !slices.Contains(states, state) {
+		utils.Dispatch400Error(w, "Invalid state", fmt.Sprintf("state must be one of %s", strings.Join(states, ", ")))
+		return
+	}
+	list, err := utils.ParseListParams(r)
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid pagination parameters", err.Error())
+		return
+	}
+	subscription, ok := c.getWebhookSubscription(w, r)
+	if !ok {
+		return
+	}
+	deliveries, page, err := c.dbRepository.GetWebhookDeliveries(r.Context(), subscription, state, list)
+	if err != nil {
+		dispatchListError(w, err)
+		return
+	}
+	utils.Dispatch200Page(w, "Webhook Deliveries Fetched Successfully", deliveries, page.Meta(r))
+}
+
+// resolve the {id} and {deliveryID} path params to a delivery of that subscription, dispatching
+// the error response if that fails
+func (c *Controller) getWebhookDelivery(w http.ResponseWriter, r *http.Request) (*models.WebhookDelivery, bool) {
+	subscription, ok := c.getWebhookSubscription(w, r)
+	if !ok {
+		return nil, false
+	}
+	deliveryID, err := utils.GetIDPathParam(r, "deliveryID")
+	if err != nil {
+		utils.Dispatch400Error(w, "Invalid Payload", err.Error())
+		return nil, false
+	}
+	delivery, err := c.dbRepository.GetWebhookDelivery(r.Context(), deliveryID)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return nil, false
+	}
+	if delivery == nil || delivery.SubscriptionID != subscription.ID {
+		utils.Dispatch404Error(w, "Webhook delivery not found", nil)
+		return nil, false
+	}
+	return delivery, true
+}
+
+func (c *Controller) GetWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := c.getWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+	utils.Dispatch200(w, "Webhook Delivery Fetched Successfully", delivery)
+}
+
+// send a delivery's payload again, whatever became of it the first time
+func (c *Controller) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	delivery, ok := c.getWebhookDelivery(w, r)
+	if !ok {
+		return
+	}
+	redelivery, err := c.dbRepository.RedeliverWebhook(r.Context(), delivery)
+	if err != nil {
+		utils.Dispatch500Error(w, err)
+		return
+	}
+	utils.Dispatch202(w, "Webhook Redelivery Queued", webhookDeliveryLocation(redelivery), redelivery)
+}
@@ -0,0 +1,96 @@
+package controllers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/controllers"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateWebhook(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	mockDBRepository.On("CreateWebhookSubscription", &models.WebhookSubscription{
+		URL: "https://example.com/hook", Secret: "s3cret", Events: []string{models.WebhookEventCommitCreated},
+	}).Return(nil)
+
+	payload, _ := json.Marshal(map[string]interface{}{
+		"url": "https://example.com/hook", "secret": "s3cret", "events": []string{"commit.created", "commit.created"},
+	})
+	req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBuffer(payload))
+	rr := httptest.NewRecorder()
+	controller.CreateWebhook(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	// the secret is never sent back
+	assert.NotContains(t, rr.Body.String(), "s3cret")
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestCreateWebhook_InvalidPayload(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+
+	for _, payload := range []string{
+		`{"url": "ftp://example.com", "secret": "s3cret", "events": ["commit.created"]}`,
+		`{"url": "https://example.com/hook", "events": ["commit.created"]}`,
+		`{"url": "https://example.com/hook", "secret": "s3cret", "events": []}`,
+		`{"url": "https://example.com/hook", "secret": "s3cret", "events": ["commit.deleted"]}`,
+	} {
+		req, _ := http.NewRequest("POST", "/webhooks", bytes.NewBufferString(payload))
+		rr := httptest.NewRecorder()
+		controller.CreateWebhook(rr, req)
+		assert.Equal(t, http.StatusBadRequest, rr.Code, payload)
+	}
+	mockDBRepository.AssertNotCalled(t, "CreateWebhookSubscription", mock.Anything)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	subscription := &models.WebhookSubscription{URL: "https://example.com/hook"}
+	subscription.ID = 3
+	delivery := &models.WebhookDelivery{SubscriptionID: 3, Event: models.WebhookEventCommitCreated, State: models.WebhookDeliveryFailed}
+	delivery.ID = 7
+	redelivery := &models.WebhookDelivery{SubscriptionID: 3, Event: models.WebhookEventCommitCreated, State: models.WebhookDeliveryPending}
+	redelivery.ID = 8
+	mockDBRepository.On("GetWebhookSubscription", uint(3)).Return(subscription, nil)
+	mockDBRepository.On("GetWebhookDelivery", uint(7)).Return(delivery, nil)
+	mockDBRepository.On("RedeliverWebhook", delivery).Return(redelivery, nil)
+
+	req, _ := http.NewRequest("POST", "/webhooks/3/deliveries/7/redeliver", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "3", "deliveryID": "7"})
+	controller.RedeliverWebhook(rr, req)
+
+	assert.Equal(t, http.StatusAccepted, rr.Code)
+	assert.Equal(t, "/webhooks/3/deliveries/8", rr.Header().Get("Location"))
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestRedeliverWebhook_DeliveryOfAnotherSubscription(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	controller := controllers.NewController(new(mocks.MockRequester), mockDBRepository, new(mocks.MockTask), "", nil)
+	subscription := &models.WebhookSubscription{URL: "https://example.com/hook"}
+	subscription.ID = 3
+	delivery := &models.WebhookDelivery{SubscriptionID: 4}
+	delivery.ID = 7
+	mockDBRepository.On("GetWebhookSubscription", uint(3)).Return(subscription, nil)
+	mockDBRepository.On("GetWebhookDelivery", uint(7)).Return(delivery, nil)
+
+	req, _ := http.NewRequest("POST", "/webhooks/3/deliveries/7/redeliver", nil)
+	rr := httptest.NewRecorder()
+	req = mux.SetURLVars(req, map[string]string{"id": "3", "deliveryID": "7"})
+	controller.RedeliverWebhook(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockDBRepository.AssertNotCalled(t, "RedeliverWebhook", mock.Anything)
+}
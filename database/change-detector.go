@@ -0,0 +1,76 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+)
+
+// the fields of a repository compared every time github reports on it
+var trackedTextFields = []struct {
+	event, field string
+	stored       func(*models.Repository) string
+	remote       func(*dto.RepositoryInfoResponseDTO) string
+}{
+	{models.RepositoryEventRenamed, "name",
+		func(r *models.Repository) string { return r.Name }, func(d *dto.RepositoryInfoResponseDTO) string { return d.Name }},
+	{models.RepositoryEventDescriptionChanged, "description",
+		func(r *models.Repository) string { return r.Description }, func(d *dto.RepositoryInfoResponseDTO) string { return d.Description }},
+	{models.RepositoryEventLanguageChanged, "language",
+		func(r *models.Repository) string { return r.Language }, func(d *dto.RepositoryInfoResponseDTO) string { return d.Language }},
+	{models.RepositoryEventDefaultBranchChanged, "default_branch",
+		func(r *models.Repository) string { return r.DefaultBranch }, func(d *dto.RepositoryInfoResponseDTO) string { return d.DefaultBranch }},
+}
+
+var trackedCountFields = []struct {
+	event, field string
+	stored       func(*models.Repository) int
+	remote       func(*dto.RepositoryInfoResponseDTO) int
+}{
+	{models.RepositoryEventStarsChanged, "stars",
+		func(r *models.Repository) int { return r.StarsCount }, func(d *dto.RepositoryInfoResponseDTO) int { return d.StarsCount }},
+	{models.RepositoryEventForksChanged, "forks",
+		func(r *models.Repository) int { return r.ForksCount }, func(d *dto.RepositoryInfoResponseDTO) int { return d.ForksCount }},
+	{models.RepositoryEventOpenIssuesChanged, "open_issues",
+		func(r *models.Repository) int { return r.OpenIssues }, func(d *dto.RepositoryInfoResponseDTO) int { return d.OpenIssues }},
+	{models.RepositoryEventWatchersChanged, "watchers",
+		func(r *models.Repository) int { return r.Watchers }, func(d *dto.RepositoryInfoResponseDTO) int { return d.Watchers }},
+}
+
+// what github now reports differently from the stored repository, one event per changed field
+func detectRepositoryChanges(repo *models.Repository, remoteRepoInfo *dto.RepositoryInfoResponseDTO) []*models.RepositoryEvent {
+	events := []*models.RepositoryEvent{}
+	for _, tracked := range trackedTextFields {
+		previous, current := tracked.stored(repo), tracked.remote(remoteRepoInfo)
+		if previous != current {
+			events = append(events, &models.RepositoryEvent{
+				RepositoryID: repo.ID, Type: tracked.event, Field: tracked.field, OldValue: previous, NewValue: current,
+				Summary: fmt.Sprintf("%s changed from %q to %q", tracked.field, previous, current),
+			})
+		}
+	}
+	for _, tracked := range trackedCountFields {
+		previous, current := tracked.stored(repo), tracked.remote(remoteRepoInfo)
+		if previous != current {
+			events = append(events, &models.RepositoryEvent{
+				RepositoryID: repo.ID, Type: tracked.event, Field: tracked.field,
+				OldValue: strconv.Itoa(previous), NewValue: strconv.Itoa(current), Delta: current - previous,
+				// e.g. stars +5
+				Summary: fmt.Sprintf("%s %+d", tracked.field, current-previous),
+			})
+		}
+	}
+	return events
+}
+
+// whether any of the metrics recorded in snapshots changed
+func metricsChanged(events []*models.RepositoryEvent) bool {
+	for _, event := range events {
+		if event.Delta != 0 {
+			return true
+		}
+	}
+	return false
+}
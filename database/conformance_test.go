@@ -0,0 +1,1013 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/database/migrations"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// every DBRepository implementation runs through the same cases so they stay interchangeable
+func runDBRepositoryConformance(t *testing.T, newRepository func(t *testing.T) database.DBRepository) {
+	cases := []struct {
+		name string
+		run  func(t *testing.T, repository database.DBRepository)
+	}{
+		{"CreateAndGetUser", testCreateAndGetUser},
+		{"StoreAndGetRepository", testStoreAndGetRepository},
+		{"DeleteRepository", testDeleteRepository},
+		{"StoreRepositoryCommitsDeduplicates", testStoreRepositoryCommitsDeduplicates},
+		{"UpdateRepositoryLatestCommitAtOnlyMovesForward", testUpdateRepositoryLatestCommitAtOnlyMovesForward},
+		{"CommitsAreScopedByOwner", testCommitsAreScopedByOwner},
+		{"SearchRepository", testSearchRepository},
+		{"PaginateRepositories", testPaginateRepositories},
+		{"PaginateCommits", testPaginateCommits},
+		{"FilterCommits", testFilterCommits},
+		{"TopCommitAuthors", testTopCommitAuthors},
+		{"FullTextSearch", testFullTextSearch},
+		{"RepositoryMetrics", testRepositoryMetrics},
+		{"RepositoryChangeEvents", testRepositoryChangeEvents},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"RepositoryRefreshSchedule", testRepositoryRefreshSchedule},
+		{"HTTPCacheEntries", testHTTPCacheEntries},
+		{"CommitBackfills", testCommitBackfills},
+		{"JobQueueClaimsDueJobs", testJobQueueClaimsDueJobs},
+		{"JobQueueReclaimsExpiredLeases", testJobQueueReclaimsExpiredLeases},
+		{"JobQueueRetriesAndDeadLetters", testJobQueueRetriesAndDeadLetters},
+		{"JobQueueDeadLettersExpiredLeasesOnTheLastAttempt", testJobQueueDeadLettersExpiredLeasesOnTheLastAttempt},
+		{"JobQueueReleasesInterruptedJobs", testJobQueueReleasesInterruptedJobs},
+		{"JobProgressAndStatus", testJobProgressAndStatus},
+		{"EnqueueUniqueJob", testEnqueueUniqueJob},
+		{"EnqueuePendingUniqueJob", testEnqueuePendingUniqueJob},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, newRepository(t))
+		})
+	}
+}
+
+func TestSqliteDBRepositoryConformance(t *testing.T) {
+	runDBRepositoryConformance(t, func(t *testing.T) database.DBRepository {
+		db := openMemoryDB(t)
+		require.NoError(t, database.MigrateUp(db))
+		return database.NewDBRepository(db)
+	})
+}
+
+// runs against the database in TEST_POSTGRES_DSN, which is wiped before every case
+func TestPostgresDBRepositoryConformance(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set; skipping postgres conformance tests")
+	}
+	runDBRepositoryConformance(t, func(t *testing.T) database.DBRepository {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+		require.NoError(t, err)
+		require.NoError(t, database.MigrateDown(db, len(migrations.All())))
+		require.NoError(t, database.MigrateUp(db))
+		repository := database.NewDBRepository(db)
+		_, isPostgres := repository.(*database.PostgresDBRepository)
+		require.True(t, isPostgres)
+		return repository
+	})
+}
+
+func createTestUser(t *testing.T, repository database.DBRepository, username string) *models.User {
+	user, err := repository.CreateUser(context.Background(), &dto.CreateUserPayloadDTO{Username: username, FullName: "Test " + username})
+	require.NoError(t, err)
+	return user
+}
+
+func createTestRepository(t *testing.T, repository database.DBRepository, owner *models.User, remoteID int, name string) *models.Repository {
+	repo, err := repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+		ID:         remoteID,
+		Name:       name,
+		HtmlUrl:    "https://github.com/" + owner.Username + "/" + name,
+		Language:   "Go",
+		StarsCount: remoteID,
+		UpdatedAt:  "2024-07-01T00:00:00Z",
+	}, owner)
+	require.NoError(t, err)
+	return repo
+}
+
+func enqueueTestJob(t *testing.T, repository database.DBRepository, jobType, payload string, runAt time.Time) *models.Job {
+	job := &models.Job{Type: jobType, Owner: "alice", Payload: payload, RunAt: runAt}
+	require.NoError(t, repository.EnqueueJob(context.Background(), job))
+	return job
+}
+
+func testCreateAndGetUser(t *testing.T, repository database.DBRepository) {
+	user := createTestUser(t, repository, "alice")
+	assert.NotZero(t, user.ID)
+
+	fetched, err := repository.GetUser(context.Background(), "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, fetched.ID)
+
+	// registering again updates the existing user
+	updated, err := repository.CreateUser(context.Background(), &dto.CreateUserPayloadDTO{Username: "alice", FullName: "Alice Updated"})
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, updated.ID)
+	assert.Equal(t, "Alice Updated", updated.FullName)
+
+	missing, err := repository.GetUser(context.Background(), "nobody")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func testStoreAndGetRepository(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	stored := createTestRepository(t, repository, owner, 1, "api")
+
+	fetched, err := repository.GetRepository(context.Background(), owner.ID, "api")
+	assert.NoError(t, err)
+	assert.Equal(t, stored.ID, fetched.ID)
+	assert.Equal(t, "alice", fetched.Owner.Username)
+
+	missing, err := repository.GetRepository(context.Background(), owner.ID, "web")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	all, err := repository.GetAllRepositories(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, all, 1)
+}
+
+func testDeleteRepository(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	stored := createTestRepository(t, repository, owner, 1, "api")
+	createTestRepository(t, repository, owner, 2, "web")
+
+	fetched, err := repository.GetRepositoryInfoByRemoteId(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, stored.ID, fetched.ID)
+	assert.Equal(t, "alice", fetched.Owner.Username)
+
+	require.NoError(t, repository.DeleteRepository(context.Background(), fetched))
+	missing, err := repository.GetRepositoryInfoByRemoteId(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+	all, err := repository.GetAllRepositories(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"web"}, repositoryNames(all))
+}
+
+func testStoreRepositoryCommitsDeduplicates(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	repo := createTestRepository(t, repository, owner, 1, "api")
+	commits := &[]dto.CommitResponseDTO{
+		{SHA: "a1", Message: "first", Author: "alice", Date: "2024-07-01T00:00:00Z"},
+		{SHA: "a2", Message: "second", Author: "alice", Date: "2024-07-02T00:00:00Z"},
+	}
+
+	stored, err := repository.StoreRepositoryCommits(context.Background(), commits, repo)
+	require.NoError(t, err)
+	assert.Len(t, stored, 2)
+	// only commits that weren't stored before are returned
+	stored, err = repository.StoreRepositoryCommits(context.Background(), commits, repo)
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+
+	all, _, err := repository.GetRepositoryCommits(context.Background(), "alice", "api", nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func testUpdateRepositoryLatestCommitAtOnlyMovesForward(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	repo := createTestRepository(t, repository, owner, 1, "api")
+	latest := time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repository.UpdateRepositoryLatestCommitAt(context.Background(), repo, latest))
+	require.NoError(t, repository.UpdateRepositoryLatestCommitAt(context.Background(), repo, latest.AddDate(0, 0, -1)))
+	assert.Equal(t, latest, repo.LatestCommitAt.UTC())
+
+	fetched, err := repository.GetRepository(context.Background(), owner.ID, "api")
+	assert.NoError(t, err)
+	assert.Equal(t, latest, fetched.LatestCommitAt.UTC())
+}
+
+func testCommitsAreScopedByOwner(t *testing.T, repository database.DBRepository) {
+	alice := createTestUser(t, repository, "alice")
+	bob := createTestUser(t, repository, "bob")
+	aliceRepo := createTestRepository(t, repository, alice, 1, "api")
+	bobRepo := createTestRepository(t, repository, bob, 2, "api")
+
+	// the same sha may exist in both repositories, e.g. for forks
+	_, err := repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{{SHA: "shared"}, {SHA: "a1"}}, aliceRepo)
+	require.NoError(t, err)
+	_, err = repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{{SHA: "shared"}}, bobRepo)
+	require.NoError(t, err)
+
+	aliceCommits, _, err := repository.GetRepositoryCommits(context.Background(), "alice", "api", nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, aliceCommits, 2)
+	bobCommits, _, err := repository.GetRepositoryCommits(context.Background(), "bob", "api", nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, bobCommits, 1)
+}
+
+func testSearchRepository(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	createTestRepository(t, repository, owner, 1, "Service-API")
+	createTestRepository(t, repository, owner, 3, "web")
+	createTestRepository(t, repository, owner, 2, "api-docs")
+
+	byName, _, err := repository.SearchRepository(context.Background(), owner.ID, &utils.RepositorySearchParams{Name: "api"}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, byName, 2)
+
+	top, _, err := repository.SearchRepository(context.Background(), owner.ID, &utils.RepositorySearchParams{TopStarsCount: 2}, nil)
+	assert.NoError(t, err)
+	require.Len(t, top, 2)
+	assert.Equal(t, "web", top[0].Name)
+	assert.Equal(t, "api-docs", top[1].Name)
+
+	byLanguage, _, err := repository.SearchRepository(context.Background(), owner.ID, &utils.RepositorySearchParams{Language: "Rust"}, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, byLanguage)
+}
+
+func repositoryNames(repos []*models.Repository) []string {
+	names := []string{}
+	for _, repo := range repos {
+		names = append(names, repo.Name)
+	}
+	return names
+}
+
+func testPaginateRepositories(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	// stars tie on purpose so the id breaks them, in the same direction as the sort
+	for i, name := range []string{"a", "b", "c", "d", "e"} {
+		_, err := repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+			ID: i + 1, Name: name, StarsCount: []int{5, 9, 5, 1, 9}[i],
+		}, owner)
+		require.NoError(t, err)
+	}
+	ctx := context.Background()
+
+	byPage, page, err := repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, &utils.ListParams{Page: 2, PerPage: 2, Sort: "stars"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, repositoryNames(byPage))
+	assert.Equal(t, int64(5), page.Total)
+	assert.True(t, page.HasNext)
+	assert.True(t, page.HasPrev)
+
+	// walk forwards by cursor and back again
+	list := &utils.ListParams{PerPage: 2, Sort: "stars"}
+	var walked []string
+	var pages []*utils.PageInfo
+	for {
+		repos, page, err := repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, list)
+		require.NoError(t, err)
+		walked = append(walked, repositoryNames(repos)...)
+		pages = append(pages, page)
+		if page.NextCursor == "" {
+			break
+		}
+		list = &utils.ListParams{PerPage: 2, Cursor: page.NextCursor}
+	}
+	assert.Equal(t, []string{"e", "b", "c", "a", "d"}, walked)
+	require.Len(t, pages, 3)
+	assert.Empty(t, pages[0].PrevCursor)
+
+	back, page, err := repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, &utils.ListParams{PerPage: 2, Cursor: pages[2].PrevCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "a"}, repositoryNames(back))
+	assert.NotEmpty(t, page.PrevCursor)
+	assert.NotEmpty(t, page.NextCursor)
+
+	byNameDesc, _, err := repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, &utils.ListParams{PerPage: 10, Sort: "name", Order: "desc"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e", "d", "c", "b", "a"}, repositoryNames(byNameDesc))
+
+	_, _, err = repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, &utils.ListParams{Sort: "size"})
+	assert.ErrorIs(t, err, utils.ErrInvalidSort)
+	_, _, err = repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, &utils.ListParams{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, utils.ErrInvalidCursor)
+	// a cursor only continues the ordering it was handed out for
+	_, _, err = repository.SearchRepository(ctx, owner.ID, &utils.RepositorySearchParams{}, &utils.ListParams{Cursor: pages[0].NextCursor, Sort: "name"})
+	assert.ErrorIs(t, err, utils.ErrInvalidCursor)
+}
+
+func testPaginateCommits(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	repo := createTestRepository(t, repository, owner, 1, "api")
+	_, err := repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "a1", Date: "2024-07-01T00:00:00Z"},
+		{SHA: "a3", Date: "2024-07-03T00:00:00Z"},
+		{SHA: "a2", Date: "2024-07-02T00:00:00Z"},
+	}, repo)
+	require.NoError(t, err)
+
+	// newest first by default
+	first, page, err := repository.GetRepositoryCommits(context.Background(), "alice", "api", nil, &utils.ListParams{PerPage: 2})
+	require.NoError(t, err)
+	require.Len(t, first, 2)
+	assert.Equal(t, "a3", first[0].SHA)
+	assert.Equal(t, "a2", first[1].SHA)
+	assert.Equal(t, int64(3), page.Total)
+
+	rest, page, err := repository.GetRepositoryCommits(context.Background(), "alice", "api", nil, &utils.ListParams{PerPage: 2, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, rest, 1)
+	assert.Equal(t, "a1", rest[0].SHA)
+	assert.Empty(t, page.NextCursor)
+}
+
+func testFilterCommits(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	repo := createTestRepository(t, repository, owner, 1, "api")
+	_, err := repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "abc123", Author: "Alice", Message: "Fix login bug", Date: "2024-07-01T09:00:00Z"},
+		{SHA: "abd456", Author: "bob", Message: "Add signup", Date: "2024-07-02T12:30:00+02:00"},
+		{SHA: "fed789", Author: "alice", Message: "fix typo", Date: "2024-07-03T23:59:00Z"},
+	}, repo)
+	require.NoError(t, err)
+	shas := func(filters *utils.CommitFilters) []string {
+		commits, _, err := repository.GetRepositoryCommits(context.Background(), "alice", "api", filters, nil)
+		require.NoError(t, err)
+		found := []string{}
+		for _, commit := range commits {
+			found = append(found, commit.SHA)
+		}
+		return found
+	}
+	since := time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC)
+	until := time.Date(2024, 7, 2, 10, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, []string{"fed789", "abc123"}, shas(&utils.CommitFilters{Author: "alice"}))
+	assert.Equal(t, []string{"fed789", "abc123"}, shas(&utils.CommitFilters{Message: "FIX"}))
+	assert.Equal(t, []string{"abd456", "abc123"}, shas(&utils.CommitFilters{SHAPrefix: "ab"}))
+	// dates with an offset are compared in UTC: 12:30+02:00 is 10:30Z
+	assert.Equal(t, []string{"abd456"}, shas(&utils.CommitFilters{Since: &since, Until: &until}))
+	assert.Equal(t, []string{"fed789", "abd456"}, shas(&utils.CommitFilters{Since: &since}))
+
+	commit, err := repository.GetRepositoryCommit(context.Background(), "alice", "api", "ABD456")
+	require.NoError(t, err)
+	require.NotNil(t, commit)
+	assert.Equal(t, time.Date(2024, 7, 2, 10, 30, 0, 0, time.UTC), commit.Date.UTC())
+	missing, err := repository.GetRepositoryCommit(context.Background(), "alice", "api", "abd")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func testTopCommitAuthors(t *testing.T, repository database.DBRepository) {
+	alice := createTestUser(t, repository, "alice")
+	bob := createTestUser(t, repository, "bob")
+	api := createTestRepository(t, repository, alice, 1, "api")
+	web := createTestRepository(t, repository, bob, 2, "web")
+	_, err := repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "a1", Author: "carol", Date: "2024-07-01T00:00:00Z"},
+		{SHA: "a2", Author: "carol", Date: "2024-07-05T00:00:00Z"},
+		{SHA: "a3", Author: "dave", Date: "2024-07-03T00:00:00Z"},
+		{SHA: "a4", Author: "erin", Date: "2024-07-04T00:00:00Z"},
+	}, api)
+	require.NoError(t, err)
+	_, err = repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "w1", Author: "dave", Date: "2024-07-02T00:00:00Z"},
+		{SHA: "w2", Author: "dave", Date: "2024-07-06T00:00:00Z"},
+	}, web)
+	require.NoError(t, err)
+
+	perRepo, err := repository.GetTopCommitAuthors(context.Background(), &utils.AuthorStatsQuery{Owner: "alice", RepoName: "api", Top: 2})
+	require.NoError(t, err)
+	require.Len(t, perRepo, 2)
+	assert.Equal(t, "carol", perRepo[0].Author)
+	assert.Equal(t, int64(2), perRepo[0].Commits)
+	assert.Equal(t, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), perRepo[0].FirstCommitAt)
+	assert.Equal(t, time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC), perRepo[0].LastCommitAt)
+	// one commit each; erin committed last
+	assert.Equal(t, "erin", perRepo[1].Author)
+
+	overall, err := repository.GetTopCommitAuthors(context.Background(), &utils.AuthorStatsQuery{Top: 10})
+	require.NoError(t, err)
+	require.Len(t, overall, 3)
+	assert.Equal(t, "dave", overall[0].Author)
+	assert.Equal(t, int64(3), overall[0].Commits)
+	assert.Equal(t, int64(2), overall[0].Repositories)
+
+	since := time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC)
+	recent, err := repository.GetTopCommitAuthors(context.Background(), &utils.AuthorStatsQuery{Since: &since, Top: 10})
+	require.NoError(t, err)
+	require.Len(t, recent, 3)
+	assert.Equal(t, "dave", recent[0].Author)
+	assert.Equal(t, int64(1), recent[0].Commits)
+	assert.Equal(t, time.Date(2024, 7, 6, 0, 0, 0, 0, time.UTC), recent[0].FirstCommitAt)
+}
+
+// holds for every backend, whether or not it ranks matches
+func testFullTextSearch(t *testing.T, repository database.DBRepository) {
+	alice := createTestUser(t, repository, "alice")
+	bob := createTestUser(t, repository, "bob")
+	api := createTestRepository(t, repository, alice, 1, "api")
+	web := createTestRepository(t, repository, bob, 2, "web")
+	_, err := repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+		ID: 3, Name: "parsers", Description: "A collection of Parsing tools", Language: "Rust",
+		UpdatedAt: "2024-07-01T00:00:00Z",
+	}, alice)
+	require.NoError(t, err)
+	_, err = repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "a1", Message: "Fix the parser on empty input", Date: "2024-07-01T00:00:00Z"},
+		{SHA: "a2", Message: "Add a README", Date: "2024-07-02T00:00:00Z"},
+	}, api)
+	require.NoError(t, err)
+	_, err = repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "w1", Message: "fix layout of the parser demo", Date: "2024-07-03T00:00:00Z"},
+	}, web)
+	require.NoError(t, err)
+
+	// every word has to match, regardless of case and also as the start of a longer word
+	commits, page, err := repository.Search(context.Background(), &utils.SearchQuery{Text: "FIX pars", Type: utils.SearchTypeCommits})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	require.Len(t, commits, 2)
+	assert.ElementsMatch(t, []string{"a1", "w1"}, []string{commits[0].Commit.SHA, commits[1].Commit.SHA})
+	for _, result := range commits {
+		assert.Equal(t, "commit", result.Type)
+		assert.Contains(t, strings.ToLower(result.Snippet), "<mark>fix</mark>")
+	}
+
+	scoped, page, err := repository.Search(context.Background(), &utils.SearchQuery{Text: "fix", Type: utils.SearchTypeCommits, Owner: "alice", RepoName: "api"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+	require.Len(t, scoped, 1)
+	assert.Equal(t, "a1", scoped[0].Commit.SHA)
+	assert.Equal(t, "alice", scoped[0].Owner)
+	assert.Equal(t, "api", scoped[0].RepositoryName)
+
+	paged, page, err := repository.Search(context.Background(), &utils.SearchQuery{Text: "fix", Type: utils.SearchTypeCommits, Page: 2, PerPage: 1})
+	require.NoError(t, err)
+	assert.Len(t, paged, 1)
+	assert.True(t, page.HasPrev)
+	assert.False(t, page.HasNext)
+
+	// repositories match on their name, description and language
+	repos, _, err := repository.Search(context.Background(), &utils.SearchQuery{Text: "parsing", Type: utils.SearchTypeRepositories})
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, "parsers", repos[0].RepositoryName)
+	assert.Equal(t, "repository", repos[0].Type)
+	assert.Contains(t, repos[0].Snippet, "<mark>Parsing</mark>")
+	repos, _, err = repository.Search(context.Background(), &utils.SearchQuery{Text: "go", Type: utils.SearchTypeRepositories, Owner: "bob"})
+	require.NoError(t, err)
+	require.Len(t, repos, 1)
+	assert.Equal(t, web.ID, repos[0].Repository.ID)
+
+	none, page, err := repository.Search(context.Background(), &utils.SearchQuery{Text: "fix readme", Type: utils.SearchTypeCommits})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	assert.Equal(t, int64(0), page.Total)
+}
+
+func testRepositoryMetrics(t *testing.T, repository database.DBRepository) {
+	alice := createTestUser(t, repository, "alice")
+	api := createTestRepository(t, repository, alice, 1, "api")
+	createTestRepository(t, repository, alice, 2, "web")
+	refreshed, err := repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+		ID: 1, Name: "api", StarsCount: 10, ForksCount: 2, UpdatedAt: "2024-07-01T00:00:00Z",
+	}, alice)
+	require.NoError(t, err)
+	assert.Equal(t, 10, refreshed.StarsCount)
+
+	now := time.Now()
+	history, err := repository.GetRepositoryMetricHistory(context.Background(), api, &utils.MetricHistoryQuery{
+		Metric: utils.MetricStars, Interval: utils.IntervalHour, From: now.Add(-time.Hour), To: now,
+	})
+	require.NoError(t, err)
+	// nothing was recorded before the repository was stored
+	require.Len(t, history.Points, 1)
+	assert.Equal(t, 10, history.Points[0].Value)
+	assert.Equal(t, utils.IntervalStart(now, utils.IntervalHour), history.Points[0].Time)
+
+	// growth counts from the first snapshot of repositories stored within the window; web didn't grow
+	trending, err := repository.GetTrendingRepositories(context.Background(), &utils.TrendingQuery{Metric: utils.MetricStars, Since: now.Add(-time.Hour), Top: 10})
+	require.NoError(t, err)
+	require.Len(t, trending, 1)
+	assert.Equal(t, "alice", trending[0].Owner)
+	assert.Equal(t, "api", trending[0].RepositoryName)
+	assert.Equal(t, 1, trending[0].Previous)
+	assert.Equal(t, 10, trending[0].Current)
+	assert.Equal(t, 9, trending[0].Growth)
+
+	trending, err = repository.GetTrendingRepositories(context.Background(), &utils.TrendingQuery{Metric: utils.MetricStars, Owner: "bob", Since: now.Add(-time.Hour), Top: 10})
+	require.NoError(t, err)
+	assert.Empty(t, trending)
+}
+
+func testRepositoryChangeEvents(t *testing.T, repository database.DBRepository) {
+	alice := createTestUser(t, repository, "alice")
+	bob := createTestUser(t, repository, "bob")
+	api := createTestRepository(t, repository, alice, 1, "api")
+	require.Len(t, api.Changes, 1)
+	assert.Equal(t, models.RepositoryEventDiscovered, api.Changes[0].Type)
+	// storing what is already stored changes nothing
+	unchanged := createTestRepository(t, repository, alice, 1, "api")
+	assert.Empty(t, unchanged.Changes)
+
+	updated, err := repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+		ID: 1, Name: "api-v2", Description: "the api", HtmlUrl: "https://github.com/alice/api-v2",
+		Language: "Rust", StarsCount: 6, UpdatedAt: "2024-07-02T00:00:00Z",
+	}, alice)
+	require.NoError(t, err)
+	assert.Len(t, updated.Changes, 4)
+	stored, err := repository.GetRepository(context.Background(), alice.ID, "api-v2")
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "https://github.com/alice/api-v2", stored.URL)
+	assert.Equal(t, "2024-07-02T00:00:00Z", stored.RemoteUpdatedAt)
+
+	events, page, err := repository.GetRepositoryEvents(context.Background(), api, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), page.Total)
+	types := []string{}
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []string{
+		models.RepositoryEventStarsChanged, models.RepositoryEventLanguageChanged,
+		models.RepositoryEventDescriptionChanged, models.RepositoryEventRenamed, models.RepositoryEventDiscovered,
+	}, types)
+	assert.Equal(t, "api", events[3].OldValue)
+	assert.Equal(t, "api-v2", events[3].NewValue)
+
+	stars, _, err := repository.GetRepositoryEvents(context.Background(), api, models.RepositoryEventStarsChanged, nil)
+	require.NoError(t, err)
+	require.Len(t, stars, 1)
+	assert.Equal(t, 5, stars[0].Delta)
+	assert.Equal(t, "stars +5", stars[0].Summary)
+
+	_, err = repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+		ID: 1, Name: "api-v2", Description: "the api", HtmlUrl: "https://github.com/bob/api-v2",
+		Language: "Rust", StarsCount: 6, UpdatedAt: "2024-07-03T00:00:00Z",
+	}, bob)
+	require.NoError(t, err)
+	transferred, _, err := repository.GetRepositoryEvents(context.Background(), api, models.RepositoryEventTransferred, nil)
+	require.NoError(t, err)
+	require.Len(t, transferred, 1)
+	assert.Equal(t, "alice", transferred[0].OldValue)
+	assert.Equal(t, "bob", transferred[0].NewValue)
+	moved, err := repository.GetRepository(context.Background(), bob.ID, "api-v2")
+	require.NoError(t, err)
+	assert.NotNil(t, moved)
+}
+
+func testWebhookDeliveries(t *testing.T, repository database.DBRepository) {
+	ctx := context.Background()
+	changes := &models.WebhookSubscription{URL: "https://example.com/changes", Secret: "s3cret", Events: []string{models.WebhookEventCommitCreated, models.WebhookEventRepositoryUpdated}}
+	discoveries := &models.WebhookSubscription{URL: "https://example.com/discoveries", Secret: "other", Events: []string{models.WebhookEventRepositoryDiscovered}}
+	require.NoError(t, repository.CreateWebhookSubscription(ctx, changes))
+	require.NoError(t, repository.CreateWebhookSubscription(ctx, discoveries))
+	stored, err := repository.GetWebhookSubscription(ctx, changes.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", stored.Secret)
+	assert.Equal(t, changes.Events, stored.Events)
+
+	alice := createTestUser(t, repository, "alice")
+	api := createTestRepository(t, repository, alice, 1, "api")
+	commits := &[]dto.CommitResponseDTO{{SHA: "a1", Message: "first"}, {SHA: "a2", Message: "second"}}
+	_, err = repository.StoreRepositoryCommits(ctx, commits, api)
+	require.NoError(t, err)
+	// only new commits are announced
+	_, err = repository.StoreRepositoryCommits(ctx, commits, api)
+	require.NoError(t, err)
+	_, err = repository.StoreRepositoryInfo(ctx, &dto.RepositoryInfoResponseDTO{
+		ID: 1, Name: "api", HtmlUrl: "https://github.com/alice/api", Language: "Go", StarsCount: 3, UpdatedAt: "2024-07-02T00:00:00Z",
+	}, alice)
+	require.NoError(t, err)
+
+	deliveries, page, err := repository.GetWebhookDeliveries(ctx, changes, "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, models.WebhookEventRepositoryUpdated, deliveries[0].Event)
+	assert.Equal(t, models.WebhookEventCommitCreated, deliveries[1].Event)
+	assert.Equal(t, models.WebhookDeliveryPending, deliveries[1].State)
+	var payload dto.WebhookEventPayloadDTO
+	require.NoError(t, json.Unmarshal([]byte(deliveries[1].Payload), &payload))
+	assert.Equal(t, "alice", payload.Owner)
+	assert.Equal(t, "api", payload.RepositoryName)
+	assert.Len(t, payload.Commits, 2)
+	require.NoError(t, json.Unmarshal([]byte(deliveries[0].Payload), &payload))
+	require.Len(t, payload.Changes, 1)
+	assert.Equal(t, models.RepositoryEventStarsChanged, payload.Changes[0].Type)
+
+	// every delivery is sent by its own job
+	job, err := repository.GetJob(ctx, deliveries[1].JobID)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, models.JobTypeDeliverWebhook, job.Type)
+	assert.JSONEq(t, fmt.Sprintf(`{"delivery_id": %d}`, deliveries[1].ID), job.Payload)
+
+	discovered, _, err := repository.GetWebhookDeliveries(ctx, discoveries, models.WebhookDeliveryPending, nil)
+	require.NoError(t, err)
+	require.Len(t, discovered, 1)
+	assert.Equal(t, models.WebhookEventRepositoryDiscovered, discovered[0].Event)
+
+	redelivery, err := repository.RedeliverWebhook(ctx, deliveries[1])
+	require.NoError(t, err)
+	assert.Equal(t, deliveries[1].Payload, redelivery.Payload)
+	assert.Equal(t, deliveries[1].ID, *redelivery.RedeliveryOf)
+	assert.NotEqual(t, deliveries[1].JobID, redelivery.JobID)
+	fetched, err := repository.GetWebhookDelivery(ctx, redelivery.ID)
+	require.NoError(t, err)
+	require.NotNil(t, fetched.Subscription)
+	assert.Equal(t, changes.URL, fetched.Subscription.URL)
+
+	fetched.State = models.WebhookDeliveryDelivered
+	fetched.Attempts = 1
+	fetched.StatusCode = 200
+	require.NoError(t, repository.UpdateWebhookDelivery(ctx, fetched))
+	delivered, _, err := repository.GetWebhookDeliveries(ctx, changes, models.WebhookDeliveryDelivered, nil)
+	require.NoError(t, err)
+	require.Len(t, delivered, 1)
+	assert.Equal(t, 200, delivered[0].StatusCode)
+
+	// deliveries outlive their subscription, which they then no longer find
+	require.NoError(t, repository.DeleteWebhookSubscription(ctx, discoveries))
+	orphan, err := repository.GetWebhookDelivery(ctx, discovered[0].ID)
+	require.NoError(t, err)
+	assert.Nil(t, orphan.Subscription)
+	subscriptions, err := repository.GetWebhookSubscriptions(ctx)
+	require.NoError(t, err)
+	assert.Len(t, subscriptions, 1)
+}
+
+func testRepositoryRefreshSchedule(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	scheduled := createTestRepository(t, repository, owner, 1, "scheduled")
+	later := createTestRepository(t, repository, owner, 2, "later")
+	createTestRepository(t, repository, owner, 3, "never-scheduled")
+	now := time.Now()
+
+	won, err := repository.RescheduleRepositoryRefresh(context.Background(), scheduled, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, won)
+	won, err = repository.RescheduleRepositoryRefresh(context.Background(), later, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, won)
+
+	// repositories that were never scheduled come first, those scheduled in the future not at all
+	due, err := repository.GetRepositoriesDueForRefresh(context.Background(), now, 10)
+	require.NoError(t, err)
+	require.Len(t, due, 2)
+	assert.Equal(t, "never-scheduled", due[0].Name)
+	assert.Equal(t, "scheduled", due[1].Name)
+	assert.NotNil(t, due[1].Owner)
+
+	// rescheduling from the value read back works once; a second scheduler holding the same value loses
+	stale := *due[1]
+	won, err = repository.RescheduleRepositoryRefresh(context.Background(), due[1], now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.True(t, won)
+	won, err = repository.RescheduleRepositoryRefresh(context.Background(), &stale, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.False(t, won)
+
+	limited, err := repository.GetRepositoriesDueForRefresh(context.Background(), now, 1)
+	require.NoError(t, err)
+	require.Len(t, limited, 1)
+	assert.Equal(t, "never-scheduled", limited[0].Name)
+}
+
+func testHTTPCacheEntries(t *testing.T, repository database.DBRepository) {
+	entry, err := repository.GetHTTPCacheEntry(context.Background(), "https://api.github.com/repos/alice/api")
+	assert.NoError(t, err)
+	assert.Nil(t, entry)
+
+	require.NoError(t, repository.StoreHTTPCacheEntry(context.Background(), "https://api.github.com/repos/alice/api", `"v1"`, ""))
+	require.NoError(t, repository.StoreHTTPCacheEntry(context.Background(), "https://api.github.com/repos/alice/api", `"v2"`, "Mon, 01 Jul 2024 00:00:00 GMT"))
+
+	entry, err = repository.GetHTTPCacheEntry(context.Background(), "https://api.github.com/repos/alice/api")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v2"`, entry.ETag)
+	assert.Equal(t, "Mon, 01 Jul 2024 00:00:00 GMT", entry.LastModified)
+}
+
+func testCommitBackfills(t *testing.T, repository database.DBRepository) {
+	owner := createTestUser(t, repository, "alice")
+	repo := createTestRepository(t, repository, owner, 1, "api")
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, repository.SetRepositorySyncSince(context.Background(), repo, &since))
+	fetched, err := repository.GetRepository(context.Background(), owner.ID, "api")
+	assert.NoError(t, err)
+	assert.True(t, since.Equal(*fetched.SyncCommitsSince))
+
+	backfill, err := repository.CreateCommitBackfill(context.Background(), repo, &since)
+	require.NoError(t, err)
+	backfill.NextPageURL = "https://api.github.com/repos/alice/api/commits?page=2"
+	backfill.PagesFetched = 1
+	require.NoError(t, repository.UpdateCommitBackfill(context.Background(), backfill))
+
+	checkpoint, err := repository.GetCommitBackfill(context.Background(), backfill.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, checkpoint)
+	assert.Equal(t, 1, checkpoint.PagesFetched)
+	assert.Equal(t, backfill.NextPageURL, checkpoint.NextPageURL)
+	assert.Equal(t, "alice", checkpoint.Repository.Owner.Username)
+
+	// restarting resets the checkpoint of the existing record
+	restarted, err := repository.CreateCommitBackfill(context.Background(), repo, nil)
+	require.NoError(t, err)
+	assert.Equal(t, backfill.ID, restarted.ID)
+	assert.Equal(t, 0, restarted.PagesFetched)
+	assert.Empty(t, restarted.NextPageURL)
+}
+
+func testJobQueueClaimsDueJobs(t *testing.T, repository database.DBRepository) {
+	now := time.Now()
+	due := enqueueTestJob(t, repository, models.JobTypeFetchRepo, `{"username":"alice"}`, now.Add(-time.Second))
+	enqueueTestJob(t, repository, models.JobTypeFetchRepo, `{}`, now.Add(time.Hour))
+	enqueueTestJob(t, repository, models.JobTypeSyncCommits, `{}`, now.Add(-time.Second))
+
+	job, err := repository.ClaimJob(context.Background(), []string{models.JobTypeFetchRepo}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, due.ID, job.ID)
+	assert.Equal(t, models.JobStateRunning, job.State)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, `{"username":"alice"}`, job.Payload)
+
+	// the only other fetch-repo job isn't due yet
+	next, err := repository.ClaimJob(context.Background(), []string{models.JobTypeFetchRepo}, "worker-2", time.Minute, 5)
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	require.NoError(t, repository.ExtendJobLease(context.Background(), job, 2*time.Minute))
+	require.NoError(t, repository.CompleteJob(context.Background(), job))
+	assert.Equal(t, models.JobStateSucceeded, job.State)
+	assert.NotNil(t, job.FinishedAt)
+
+	syncJob, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	require.NoError(t, repository.FailJob(context.Background(), syncJob, models.DeadLetterReasonNotFound, "boom"))
+	assert.Equal(t, models.JobStateFailed, syncJob.State)
+	assert.Equal(t, "boom", syncJob.LastError)
+	// the reason is kept on the job for whoever looks it up later
+	failed, err := repository.GetJob(context.Background(), syncJob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DeadLetterReasonNotFound, failed.FailureReason)
+}
+
+func testJobQueueReclaimsExpiredLeases(t *testing.T, repository database.DBRepository) {
+	enqueueTestJob(t, repository, models.JobTypeRefreshRepo, `{}`, time.Now().Add(-time.Second))
+
+	// a worker that died mid job leaves a lease that runs out
+	abandoned, err := repository.ClaimJob(context.Background(), []string{models.JobTypeRefreshRepo}, "worker-1", -time.Second, 5)
+	require.NoError(t, err)
+	require.NotNil(t, abandoned)
+
+	reclaimed, err := repository.ClaimJob(context.Background(), []string{models.JobTypeRefreshRepo}, "worker-2", time.Minute, 5)
+	require.NoError(t, err)
+	require.NotNil(t, reclaimed)
+	assert.Equal(t, abandoned.ID, reclaimed.ID)
+	assert.Equal(t, 2, reclaimed.Attempts)
+
+	// the original worker no longer owns the job
+	assert.ErrorIs(t, repository.CompleteJob(context.Background(), abandoned), database.ErrJobLeaseLost)
+	assert.NoError(t, repository.CompleteJob(context.Background(), reclaimed))
+}
+
+func testJobQueueDeadLettersExpiredLeasesOnTheLastAttempt(t *testing.T, repository database.DBRepository) {
+	enqueueTestJob(t, repository, models.JobTypeRefreshRepo, `{}`, time.Now().Add(-time.Second))
+
+	// every attempt takes its worker down, leaving the lease to run out
+	for attempt := 1; attempt <= 2; attempt++ {
+		abandoned, err := repository.ClaimJob(context.Background(), []string{models.JobTypeRefreshRepo}, "worker-1", -time.Second, 2)
+		require.NoError(t, err)
+		require.NotNil(t, abandoned)
+		assert.Equal(t, attempt, abandoned.Attempts)
+	}
+
+	// with the attempts used up the job isn't handed out again but dead-lettered
+	next, err := repository.ClaimJob(context.Background(), []string{models.JobTypeRefreshRepo}, "worker-2", time.Minute, 2)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	deadLetters, err := repository.GetDeadLetters(context.Background(), models.JobTypeRefreshRepo)
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, models.DeadLetterReasonLeaseExpired, deadLetters[0].Reason)
+	assert.Equal(t, 2, deadLetters[0].Attempts)
+	job, err := repository.GetJob(context.Background(), deadLetters[0].JobID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStateFailed, job.State)
+	assert.Equal(t, models.DeadLetterReasonLeaseExpired, job.FailureReason)
+	assert.NotNil(t, job.FinishedAt)
+}
+
+func testJobQueueReleasesInterruptedJobs(t *testing.T, repository database.DBRepository) {
+	enqueueTestJob(t, repository, models.JobTypeBackfillCommits, `{}`, time.Now().Add(-time.Second))
+	job, err := repository.ClaimJob(context.Background(), []string{models.JobTypeBackfillCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+
+	// a released job is claimable right away and the interrupted attempt doesn't count
+	require.NoError(t, repository.ReleaseJob(context.Background(), job))
+	assert.Equal(t, models.JobStatePending, job.State)
+	assert.ErrorIs(t, repository.ReleaseJob(context.Background(), job), database.ErrJobLeaseLost)
+	reclaimed, err := repository.ClaimJob(context.Background(), []string{models.JobTypeBackfillCommits}, "worker-2", time.Minute, 5)
+	require.NoError(t, err)
+	require.NotNil(t, reclaimed)
+	assert.Equal(t, job.ID, reclaimed.ID)
+	assert.Equal(t, 1, reclaimed.Attempts)
+}
+
+func testJobQueueRetriesAndDeadLetters(t *testing.T, repository database.DBRepository) {
+	enqueueTestJob(t, repository, models.JobTypeSyncCommits, `{}`, time.Now().Add(-time.Second))
+	job, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+
+	// a retried job goes back to pending and isn't claimable before its backoff has passed
+	require.NoError(t, repository.RetryJob(context.Background(), job, "timeout", time.Now().Add(time.Hour)))
+	assert.Equal(t, models.JobStatePending, job.State)
+	notDue, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	assert.NoError(t, err)
+	assert.Nil(t, notDue)
+	// the lease was released along with the job
+	assert.ErrorIs(t, repository.RetryJob(context.Background(), job, "timeout", time.Now()), database.ErrJobLeaseLost)
+
+	enqueueTestJob(t, repository, models.JobTypeFetchRepo, `{"username":"alice"}`, time.Now().Add(-time.Second))
+	deadJob, err := repository.ClaimJob(context.Background(), []string{models.JobTypeFetchRepo}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	require.NoError(t, repository.FailJob(context.Background(), deadJob, models.DeadLetterReasonRetriesExhausted, "unexpected status 502"))
+
+	deadLetters, err := repository.GetDeadLetters(context.Background(), "")
+	require.NoError(t, err)
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, deadJob.ID, deadLetters[0].JobID)
+	assert.Equal(t, models.DeadLetterReasonRetriesExhausted, deadLetters[0].Reason)
+	assert.Equal(t, 1, deadLetters[0].Attempts)
+	filtered, err := repository.GetDeadLetters(context.Background(), models.JobTypeSyncCommits)
+	assert.NoError(t, err)
+	assert.Empty(t, filtered)
+
+	deadLetter, err := repository.GetDeadLetter(context.Background(), deadLetters[0].ID)
+	require.NoError(t, err)
+	require.NotNil(t, deadLetter.Job)
+	assert.Equal(t, `{"username":"alice"}`, deadLetter.Job.Payload)
+
+	requeued, err := repository.RequeueDeadLetter(context.Background(), deadLetter)
+	require.NoError(t, err)
+	assert.Equal(t, deadJob.ID, requeued.ID)
+	assert.Equal(t, models.JobStatePending, requeued.State)
+	assert.Equal(t, 0, requeued.Attempts)
+	assert.Empty(t, requeued.FailureReason)
+	_, err = repository.RequeueDeadLetter(context.Background(), deadLetter)
+	assert.ErrorIs(t, err, database.ErrJobNotFailed)
+
+	gone, err := repository.GetDeadLetter(context.Background(), deadLetter.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, gone)
+	reclaimed, err := repository.ClaimJob(context.Background(), []string{models.JobTypeFetchRepo}, "worker-2", time.Minute, 5)
+	require.NoError(t, err)
+	require.NotNil(t, reclaimed)
+	assert.Equal(t, deadJob.ID, reclaimed.ID)
+}
+
+func testJobProgressAndStatus(t *testing.T, repository database.DBRepository) {
+	enqueueTestJob(t, repository, models.JobTypeSyncCommits, `{}`, time.Now().Add(-time.Second))
+	enqueueTestJob(t, repository, models.JobTypeSyncCommits, `{}`, time.Now().Add(time.Hour))
+	require.NoError(t, repository.EnqueueJob(context.Background(), &models.Job{Type: models.JobTypeSyncCommits, Owner: "bob", RunAt: time.Now()}))
+
+	job, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	job.PagesFetched = 2
+	job.CommitsStored = 150
+	require.NoError(t, repository.UpdateJobProgress(context.Background(), job))
+
+	fetched, err := repository.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", fetched.Owner)
+	assert.Equal(t, 2, fetched.PagesFetched)
+	assert.Equal(t, 150, fetched.CommitsStored)
+	missing, err := repository.GetJob(context.Background(), job.ID+100)
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	counts, err := repository.CountJobsByState(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{models.JobStateRunning: 1, models.JobStatePending: 1}, counts)
+
+	pending, err := repository.CountPendingJobs(context.Background(), models.JobTypeSyncCommits)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pending)
+	active, err := repository.CountActiveJobsByType(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]int64{
+		models.JobTypeSyncCommits: {models.JobStatePending: 2, models.JobStateRunning: 1},
+	}, active)
+
+	jobs, err := repository.GetJobsByOwner(context.Background(), "alice", 1)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.NotEqual(t, job.ID, jobs[0].ID, "newest first")
+
+	// progress belongs to an attempt, so it starts over when the job is claimed again
+	require.NoError(t, repository.RetryJob(context.Background(), job, "timeout", time.Now().Add(-time.Second)))
+	retried, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, retried.ID)
+	assert.Equal(t, 0, retried.PagesFetched)
+	fetched, err = repository.GetJob(context.Background(), job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, fetched.CommitsStored)
+}
+
+func testEnqueuePendingUniqueJob(t *testing.T, repository database.DBRepository) {
+	newJob := func() *models.Job {
+		return &models.Job{Type: models.JobTypeSyncCommits, Owner: "alice", Payload: `{}`, RunAt: time.Now().Add(-time.Second), DedupeKey: "sync-commits:1"}
+	}
+	first, queued, err := repository.EnqueuePendingUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.True(t, queued)
+	claimed, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	require.Equal(t, first.ID, claimed.ID)
+
+	// a running job doesn't stand in for the new one, which is queued behind it and then joined
+	behind, queued, err := repository.EnqueuePendingUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.True(t, queued)
+	assert.NotEqual(t, first.ID, behind.ID)
+	again, queued, err := repository.EnqueuePendingUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.Equal(t, behind.ID, again.ID)
+	assert.Error(t, repository.EnqueueJob(context.Background(), newJob()))
+
+	// when the running job goes back to the queue it leaves the key to the job queued behind it
+	require.NoError(t, repository.RetryJob(context.Background(), claimed, "timeout", time.Now()))
+	retried, err := repository.GetJob(context.Background(), first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.JobStatePending, retried.State)
+	assert.Empty(t, retried.DedupeKey)
+	again, queued, err = repository.EnqueuePendingUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.Equal(t, behind.ID, again.ID)
+
+	// with nothing queued behind it, a released job keeps its key
+	last, err := repository.ClaimJob(context.Background(), []string{models.JobTypeSyncCommits}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	require.Equal(t, behind.ID, last.ID)
+	require.NoError(t, repository.ReleaseJob(context.Background(), last))
+	released, err := repository.GetJob(context.Background(), behind.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "sync-commits:1", released.DedupeKey)
+}
+
+func testEnqueueUniqueJob(t *testing.T, repository database.DBRepository) {
+	newJob := func() *models.Job {
+		return &models.Job{Type: models.JobTypeFetchUserRepos, Owner: "alice", Payload: `{}`, RunAt: time.Now().Add(-time.Second), DedupeKey: "sync:user:alice"}
+	}
+	first, queued, err := repository.EnqueueUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.True(t, queued)
+
+	// while the first job is pending or running, the same key hands back that job
+	again, queued, err := repository.EnqueueUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.Equal(t, first.ID, again.ID)
+	// the unique index backs the check up when two enqueues race past it
+	assert.Error(t, repository.EnqueueJob(context.Background(), newJob()))
+	claimed, err := repository.ClaimJob(context.Background(), []string{models.JobTypeFetchUserRepos}, "worker-1", time.Minute, 5)
+	require.NoError(t, err)
+	again, queued, err = repository.EnqueueUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.False(t, queued)
+	assert.Equal(t, first.ID, again.ID)
+
+	// once it has finished the key is free again
+	require.NoError(t, repository.CompleteJob(context.Background(), claimed))
+	next, queued, err := repository.EnqueueUniqueJob(context.Background(), newJob())
+	require.NoError(t, err)
+	assert.True(t, queued)
+	assert.NotEqual(t, first.ID, next.ID)
+
+	// finished or not, the newest job with the key is found
+	latest, err := repository.GetLatestJobByDedupeKey(context.Background(), "alice", "sync:user:alice")
+	require.NoError(t, err)
+	assert.Equal(t, next.ID, latest.ID)
+	missing, err := repository.GetLatestJobByDedupeKey(context.Background(), "alice", "sync:user:bob")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -2,8 +2,9 @@ package database
 
 import (
 	"log"
+	"strings"
 
-	"github.com/midedickson/github-service/models"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -12,20 +13,32 @@ var (
 	DB *gorm.DB
 )
 
-func ConnectToDB() {
-	d, err := gorm.Open(sqlite.Open("db.sqlite"), &gorm.Config{})
+// pick the gorm driver for a DSN: postgres URLs and key=value strings go to postgres,
+// anything else is treated as a sqlite file path
+func dialectorFor(dsn string) gorm.Dialector {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") || strings.Contains(dsn, "host=") {
+		return postgres.Open(dsn)
+	}
+	if !strings.Contains(dsn, "?") {
+		// background workers write concurrently with requests, so wait for locks instead of failing
+		dsn += "?_busy_timeout=5000&_journal_mode=WAL"
+	}
+	return sqlite.Open(dsn)
+}
+
+func ConnectToDB(dsn string) {
+	d, err := gorm.Open(dialectorFor(dsn), &gorm.Config{})
 	if err != nil {
 		panic(err)
 	}
-	log.Println("Connected to database sucessfully")
+	log.Printf("Connected to %s database sucessfully", d.Dialector.Name())
 	DB = d
 }
 
-func AutoMigrate() {
-	log.Println("Auto Migrating Models...")
-	err := DB.AutoMigrate(&models.Repository{}, &models.Commit{})
-	if err != nil {
-		panic(err)
+// the DBRepository implementation matching the connection's driver
+func NewDBRepository(db *gorm.DB) DBRepository {
+	if db.Dialector.Name() == "postgres" {
+		return NewPostgresDBRepository(db)
 	}
-	log.Println("Migrated DB Successfully")
+	return NewSqliteDBRepository(db)
 }
@@ -0,0 +1,9 @@
+package database
+
+import "errors"
+
+// the job is no longer leased by this worker, usually because its lease expired and another worker took it over
+var ErrJobLeaseLost = errors.New("job lease lost")
+
+// the job behind a dead letter is not in the failed state, e.g. it has already been requeued
+var ErrJobNotFailed = errors.New("job is not failed")
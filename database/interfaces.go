@@ -1,18 +1,68 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	"github.com/midedickson/github-service/dto"
 	"github.com/midedickson/github-service/models"
 	"github.com/midedickson/github-service/utils"
 )
 
 type DBRepository interface {
-	CreateUser(createUserPaylod *dto.CreateUserPayloadDTO) (*models.User, error)
-	GetUser(username string) (*models.User, error)
-	StoreRepositoryInfo(remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) (*models.Repository, error)
-	GetRepository(ownerID uint, repoName string) (*models.Repository, error)
-	StoreRepositoryCommits(commitRepoInfos *[]dto.CommitResponseDTO, repoName string, owner *models.User) error
-	GetRepositoryCommits(repoName string) ([]*models.Commit, error)
-	GetAllRepositories() ([]*models.Repository, error)
-	SearchRepository(ownerID uint, repoSearchParams *utils.RepositorySearchParams) ([]*models.Repository, error)
+	CreateUser(ctx context.Context, createUserPaylod *dto.CreateUserPayloadDTO) (*models.User, error)
+	GetUser(ctx context.Context, username string) (*models.User, error)
+	StoreRepositoryInfo(ctx context.Context, remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) (*models.Repository, error)
+	GetRepository(ctx context.Context, ownerID uint, repoName string) (*models.Repository, error)
+	GetRepositoryInfoByRemoteId(ctx context.Context, remoteID int) (*models.Repository, error)
+	DeleteRepository(ctx context.Context, repo *models.Repository) error
+	StoreRepositoryCommits(ctx context.Context, commitRepoInfos *[]dto.CommitResponseDTO, repo *models.Repository) ([]*models.Commit, error)
+	UpdateRepositoryLatestCommitAt(ctx context.Context, repo *models.Repository, latestCommitAt time.Time) error
+	// list methods return one page of the list and where it sits in the full list
+	GetRepositoryCommits(ctx context.Context, owner, repoName string, filters *utils.CommitFilters, list *utils.ListParams) ([]*models.Commit, *utils.PageInfo, error)
+	GetRepositoryCommit(ctx context.Context, owner, repoName, sha string) (*models.Commit, error)
+	GetTopCommitAuthors(ctx context.Context, statsQuery *utils.AuthorStatsQuery) ([]*dto.AuthorStatsResponseDTO, error)
+	GetRepositoryMetricHistory(ctx context.Context, repo *models.Repository, historyQuery *utils.MetricHistoryQuery) (*dto.MetricHistoryResponseDTO, error)
+	GetRepositoryEvents(ctx context.Context, repo *models.Repository, eventType string, list *utils.ListParams) ([]*models.RepositoryEvent, *utils.PageInfo, error)
+	GetTrendingRepositories(ctx context.Context, trendingQuery *utils.TrendingQuery) ([]*dto.TrendingRepositoryResponseDTO, error)
+	GetAllRepositories(ctx context.Context) ([]*models.Repository, error)
+	GetRepositoriesDueForRefresh(ctx context.Context, now time.Time, limit int) ([]*models.Repository, error)
+	RescheduleRepositoryRefresh(ctx context.Context, repo *models.Repository, nextRefreshAt time.Time) (bool, error)
+	SearchRepository(ctx context.Context, ownerID uint, repoSearchParams *utils.RepositorySearchParams, list *utils.ListParams) ([]*models.Repository, *utils.PageInfo, error)
+	// full text search over commit messages or repositories, best matches first
+	Search(ctx context.Context, query *utils.SearchQuery) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error)
+	GetHTTPCacheEntry(ctx context.Context, url string) (*models.HTTPCacheEntry, error)
+	StoreHTTPCacheEntry(ctx context.Context, url, etag, lastModified string) error
+	SetRepositorySyncSince(ctx context.Context, repo *models.Repository, since *time.Time) error
+	CreateCommitBackfill(ctx context.Context, repo *models.Repository, since *time.Time) (*models.CommitBackfill, error)
+	UpdateCommitBackfill(ctx context.Context, backfill *models.CommitBackfill) error
+	GetCommitBackfill(ctx context.Context, id uint) (*models.CommitBackfill, error)
+	GetRepositoryByID(ctx context.Context, id uint) (*models.Repository, error)
+	EnqueueJob(ctx context.Context, job *models.Job) error
+	EnqueueUniqueJob(ctx context.Context, job *models.Job) (*models.Job, bool, error)
+	EnqueuePendingUniqueJob(ctx context.Context, job *models.Job) (*models.Job, bool, error)
+	GetJob(ctx context.Context, id uint) (*models.Job, error)
+	GetLatestJobByDedupeKey(ctx context.Context, owner, dedupeKey string) (*models.Job, error)
+	GetJobsByOwner(ctx context.Context, owner string, limit int) ([]*models.Job, error)
+	CountJobsByState(ctx context.Context, owner string) (map[string]int64, error)
+	CountPendingJobs(ctx context.Context, jobType string) (int64, error)
+	CountActiveJobsByType(ctx context.Context) (map[string]map[string]int64, error)
+	UpdateJobProgress(ctx context.Context, job *models.Job) error
+	ClaimJob(ctx context.Context, jobTypes []string, workerID string, lease time.Duration, maxAttempts int) (*models.Job, error)
+	ExtendJobLease(ctx context.Context, job *models.Job, lease time.Duration) error
+	CompleteJob(ctx context.Context, job *models.Job) error
+	FailJob(ctx context.Context, job *models.Job, reason, errMsg string) error
+	RetryJob(ctx context.Context, job *models.Job, errMsg string, runAt time.Time) error
+	ReleaseJob(ctx context.Context, job *models.Job) error
+	GetDeadLetters(ctx context.Context, jobType string) ([]*models.DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id uint) (*models.DeadLetter, error)
+	RequeueDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) (*models.Job, error)
+	CreateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetWebhookDeliveries(ctx context.Context, subscription *models.WebhookSubscription, state string, list *utils.ListParams) ([]*models.WebhookDelivery, *utils.PageInfo, error)
+	GetWebhookDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	RedeliverWebhook(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error)
 }
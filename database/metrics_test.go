@@ -0,0 +1,56 @@
+package database_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositoryMetricHistory_CarriesValuesAcrossIntervals(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	repository := database.NewDBRepository(db)
+	owner := createTestUser(t, repository, "alice")
+	api := createTestRepository(t, repository, owner, 1, "api")
+	for _, stars := range []int{5, 8} {
+		_, err := repository.StoreRepositoryInfo(context.Background(), &dto.RepositoryInfoResponseDTO{
+			ID: 1, Name: "api", StarsCount: stars, UpdatedAt: "2024-07-01T00:00:00Z",
+		}, owner)
+		require.NoError(t, err)
+	}
+	// the three snapshots taken on the 1st, 3rd and 6th of july
+	for id, takenAt := range map[int]time.Time{
+		1: time.Date(2024, 7, 1, 9, 0, 0, 0, time.UTC),
+		2: time.Date(2024, 7, 3, 9, 0, 0, 0, time.UTC),
+		3: time.Date(2024, 7, 6, 9, 0, 0, 0, time.UTC),
+	} {
+		require.NoError(t, db.Exec("UPDATE repository_snapshots SET taken_at = ? WHERE id = ?", takenAt, id).Error)
+	}
+
+	history, err := repository.GetRepositoryMetricHistory(context.Background(), api, &utils.MetricHistoryQuery{
+		Metric: utils.MetricStars, Interval: utils.IntervalDay,
+		From: time.Date(2024, 7, 2, 12, 0, 0, 0, time.UTC), To: time.Date(2024, 7, 6, 23, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	values := []int{}
+	for _, point := range history.Points {
+		values = append(values, point.Value)
+	}
+	// the 2nd starts from the snapshot before the range
+	assert.Equal(t, []int{1, 5, 5, 5, 8}, values)
+	assert.Equal(t, time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC), history.Points[0].Time)
+
+	trending, err := repository.GetTrendingRepositories(context.Background(), &utils.TrendingQuery{
+		Metric: utils.MetricStars, Since: time.Date(2024, 7, 4, 0, 0, 0, 0, time.UTC), Top: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, trending, 1)
+	assert.Equal(t, 5, trending[0].Previous)
+	assert.Equal(t, 3, trending[0].Growth)
+}
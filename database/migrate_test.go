@@ -0,0 +1,123 @@
+package database_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/database/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openMemoryDB(t *testing.T) *gorm.DB {
+	// a named shared-cache database so every pooled connection sees the same data
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	require.NoError(t, err)
+	return db
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	db := openMemoryDB(t)
+
+	require.NoError(t, database.MigrateUp(db))
+	version, err := database.SchemaVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, migrations.Latest(), version)
+	assert.True(t, db.Migrator().HasTable("commits"))
+	assert.True(t, db.Migrator().HasIndex("repositories", "idx_repositories_owner_stars"))
+	assert.True(t, db.Migrator().HasIndex("commits", "idx_commits_repository_date"))
+
+	// applying again is a no-op
+	require.NoError(t, database.MigrateUp(db))
+
+	// rolling back the job failure reason (0016) keeps the partial dedupe key index
+	require.NoError(t, database.MigrateDown(db, 1))
+	assert.True(t, db.Migrator().HasIndex("jobs", "idx_jobs_pending_dedupe_key"))
+	assert.True(t, db.Migrator().HasIndex("jobs", "idx_jobs_claim"))
+	require.NoError(t, database.MigrateUp(db))
+
+	// rolling back a migration that drops a column (0007) keeps the table's other indexes
+	require.NoError(t, database.MigrateDown(db, migrations.Latest()-6))
+	assert.False(t, db.Migrator().HasIndex("repositories", "idx_repositories_owner_stars"))
+	assert.True(t, db.Migrator().HasIndex("jobs", "idx_jobs_owner"))
+	assert.True(t, db.Migrator().HasIndex("jobs", "idx_jobs_claim"))
+	// and so does rolling back the repository refresh schedule (0006) and the job progress columns (0005)
+	require.NoError(t, database.MigrateDown(db, 1))
+	assert.True(t, db.Migrator().HasIndex("repositories", "idx_repositories_deleted_at"))
+	require.NoError(t, database.MigrateDown(db, 1))
+	assert.True(t, db.Migrator().HasIndex("jobs", "idx_jobs_claim"))
+	require.NoError(t, database.MigrateUp(db))
+
+	require.NoError(t, database.MigrateDown(db, len(migrations.All())))
+	version, err = database.SchemaVersion(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, version)
+	assert.False(t, db.Migrator().HasTable("commits"))
+
+	require.NoError(t, database.MigrateUp(db))
+	statuses, err := database.GetMigrationStatus(db)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Name)
+	}
+}
+
+func TestMigrateUp_ConvertsCommitDatesToTimestamps(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	// back to before 0009_commit_date_timestamp
+	require.NoError(t, database.MigrateDown(db, migrations.Latest()-8))
+
+	// commits stored while the date was github's text
+	require.NoError(t, db.Exec(`INSERT INTO users (username) VALUES ('alice')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO repositories (owner_id, name) VALUES (1, 'api')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO commits (repository_id, sha, date) VALUES
+		(1, 'a1', '2024-07-01T10:00:00Z'), (1, 'a2', '2024-07-02T12:00:00+02:00'), (1, 'a3', '')`).Error)
+	require.NoError(t, database.MigrateUp(db))
+	assert.True(t, db.Migrator().HasIndex("commits", "idx_commits_repository_sha"))
+	assert.True(t, db.Migrator().HasIndex("commits", "idx_commits_repository_date"))
+
+	commits, _, err := database.NewDBRepository(db).GetRepositoryCommits(context.Background(), "alice", "api", nil, nil)
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+	assert.Equal(t, time.Date(2024, 7, 2, 10, 0, 0, 0, time.UTC), commits[0].Date.UTC())
+	assert.Equal(t, time.Date(2024, 7, 1, 10, 0, 0, 0, time.UTC), commits[1].Date.UTC())
+	assert.True(t, commits[2].Date.IsZero())
+
+	// and back to text on the way down
+	require.NoError(t, database.MigrateDown(db, migrations.Latest()-8))
+	var dates []string
+	require.NoError(t, db.Raw(`SELECT date FROM commits ORDER BY sha`).Scan(&dates).Error)
+	assert.Equal(t, []string{"2024-07-01T10:00:00Z", "2024-07-02T10:00:00Z", "0001-01-01T00:00:00Z"}, dates)
+}
+
+func TestMigrateUp_SeedsRepositorySnapshots(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	// back to before 0011_repository_snapshots
+	require.NoError(t, database.MigrateDown(db, migrations.Latest()-10))
+	require.NoError(t, db.Exec(`INSERT INTO users (username) VALUES ('alice')`).Error)
+	require.NoError(t, db.Exec(`INSERT INTO repositories (owner_id, name, stars_count, updated_at) VALUES (1, 'api', 7, ?)`, time.Now().UTC()).Error)
+	require.NoError(t, database.MigrateUp(db))
+
+	var stars []int
+	require.NoError(t, db.Raw("SELECT stars_count FROM repository_snapshots WHERE repository_id = 1").Scan(&stars).Error)
+	assert.Equal(t, []int{7}, stars)
+}
+
+func TestCheckSchemaVersion_RefusesNewerSchema(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	require.NoError(t, db.Exec("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'future', CURRENT_TIMESTAMP)", migrations.Latest()+1).Error)
+
+	assert.ErrorIs(t, database.CheckSchemaVersion(db), database.ErrUnknownSchemaVersion)
+	assert.ErrorIs(t, database.MigrateUp(db), database.ErrUnknownSchemaVersion)
+}
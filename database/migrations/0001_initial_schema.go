@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// snapshots of the models as they were when this migration was written;
+// migrations must never depend on the live models, which keep changing
+
+type userV1 struct {
+	gorm.Model
+	FullName string
+	Username string
+}
+
+func (userV1) TableName() string { return "users" }
+
+type repositoryV1 struct {
+	gorm.Model
+	RemoteID         int
+	OwnerID          uint
+	Name             string
+	Description      string
+	URL              string
+	Language         string
+	ForksCount       int
+	StarsCount       int
+	OpenIssues       int
+	Watchers         int
+	RemoteCreatedAt  string
+	RemoteUpdatedAt  string
+	DefaultBranch    string
+	LatestCommitAt   *time.Time
+	SyncCommitsSince *time.Time
+}
+
+func (repositoryV1) TableName() string { return "repositories" }
+
+type commitV1 struct {
+	gorm.Model
+	RepositoryName string
+	Message        string
+	Author         string
+	Date           string
+	URL            string
+	SHA            string
+}
+
+func (commitV1) TableName() string { return "commits" }
+
+type httpCacheEntryV1 struct {
+	gorm.Model
+	URL          string `gorm:"uniqueIndex"`
+	ETag         string
+	LastModified string
+}
+
+func (httpCacheEntryV1) TableName() string { return "http_cache_entries" }
+
+type commitBackfillV1 struct {
+	gorm.Model
+	RepositoryID   uint `gorm:"uniqueIndex"`
+	Since          *time.Time
+	NextPageURL    string
+	Status         string
+	PagesFetched   int
+	CommitsFetched int
+	LastError      string
+}
+
+func (commitBackfillV1) TableName() string { return "commit_backfills" }
+
+func init() {
+	register(&Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		// AutoMigrate rather than CreateTable so databases created before versioned
+		// migrations existed are adopted as they are
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&userV1{}, &repositoryV1{}, &commitV1{}, &httpCacheEntryV1{}, &commitBackfillV1{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&commitBackfillV1{}, &httpCacheEntryV1{}, &commitV1{}, &repositoryV1{}, &userV1{})
+		},
+	})
+}
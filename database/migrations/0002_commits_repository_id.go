@@ -0,0 +1,77 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(&Migration{
+		Version: 2,
+		Name:    "commits_repository_id",
+		Up:      linkCommitsToRepositoryID,
+		Down:    linkCommitsToRepositoryName,
+	})
+}
+
+// re-associate commits stored against a repository name with the repository's id.
+// commits whose name matches a single repository are linked directly; for names shared
+// by several owners the commit url (github.com/{owner}/{repo}/commit/{sha}) decides.
+// rows that still can't be placed are dropped and the affected repositories are
+// flagged for a full commit resync.
+func linkCommitsToRepositoryID(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn("commits", "repository_id") {
+		if err := tx.Exec("ALTER TABLE commits ADD COLUMN repository_id integer").Error; err != nil {
+			return err
+		}
+	}
+	return execAll(tx,
+		// names owned by exactly one repository
+		`UPDATE commits SET repository_id = (
+			SELECT repositories.id FROM repositories
+			WHERE repositories.name = commits.repository_name AND repositories.deleted_at IS NULL
+		)
+		WHERE (repository_id IS NULL OR repository_id = 0) AND repository_name IN (
+			SELECT name FROM repositories WHERE deleted_at IS NULL GROUP BY name HAVING COUNT(*) = 1
+		)`,
+		// shared names, resolved through the owner in the commit url
+		`UPDATE commits SET repository_id = (
+			SELECT repositories.id FROM repositories
+			JOIN users ON users.id = repositories.owner_id
+			WHERE repositories.name = commits.repository_name
+			AND repositories.deleted_at IS NULL
+			AND commits.url LIKE '%/' || users.username || '/' || repositories.name || '/commit/%'
+		)
+		WHERE repository_id IS NULL OR repository_id = 0`,
+		// commits that can't be placed will be fetched again by the next sync
+		`UPDATE repositories SET latest_commit_at = NULL WHERE name IN (
+			SELECT repository_name FROM commits WHERE repository_id IS NULL OR repository_id = 0
+		)`,
+		`DELETE FROM commits WHERE repository_id IS NULL OR repository_id = 0`,
+		// the old name-based dedupe allowed the same sha twice per repository; keep the first copy
+		`DELETE FROM commits WHERE id NOT IN (
+			SELECT MIN(id) FROM commits GROUP BY repository_id, sha
+		)`,
+		`ALTER TABLE commits DROP COLUMN repository_name`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_commits_repository_sha ON commits (repository_id, sha)`,
+	)
+}
+
+func linkCommitsToRepositoryName(tx *gorm.DB) error {
+	return execAll(tx,
+		`ALTER TABLE commits ADD COLUMN repository_name text`,
+		`UPDATE commits SET repository_name = (
+			SELECT repositories.name FROM repositories WHERE repositories.id = commits.repository_id
+		)`,
+		`DROP INDEX IF EXISTS idx_commits_repository_sha`,
+		`ALTER TABLE commits DROP COLUMN repository_id`,
+	)
+}
+
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
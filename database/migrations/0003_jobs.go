@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type jobV3 struct {
+	gorm.Model
+	Type           string `gorm:"index:idx_jobs_claim,priority:2"`
+	Payload        string
+	State          string `gorm:"index:idx_jobs_claim,priority:1"`
+	Attempts       int
+	RunAt          time.Time `gorm:"index:idx_jobs_claim,priority:3"`
+	LeaseOwner     string
+	LeaseExpiresAt *time.Time
+	LastError      string
+	FinishedAt     *time.Time
+}
+
+func (jobV3) TableName() string { return "jobs" }
+
+func init() {
+	register(&Migration{
+		Version: 3,
+		Name:    "jobs",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&jobV3{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&jobV3{})
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type deadLetterV4 struct {
+	gorm.Model
+	JobID    uint   `gorm:"index"`
+	JobType  string `gorm:"index"`
+	Reason   string
+	Attempts int
+	Error    string
+}
+
+func (deadLetterV4) TableName() string { return "dead_letters" }
+
+func init() {
+	register(&Migration{
+		Version: 4,
+		Name:    "dead_letters",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&deadLetterV4{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&deadLetterV4{})
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type jobV5 struct {
+	gorm.Model
+	Type           string `gorm:"index:idx_jobs_claim,priority:2"`
+	Owner          string `gorm:"index"`
+	Payload        string
+	State          string `gorm:"index:idx_jobs_claim,priority:1"`
+	Attempts       int
+	RunAt          time.Time `gorm:"index:idx_jobs_claim,priority:3"`
+	LeaseOwner     string
+	LeaseExpiresAt *time.Time
+	LastError      string
+	FinishedAt     *time.Time
+	PagesFetched   int
+	CommitsStored  int
+}
+
+func (jobV5) TableName() string { return "jobs" }
+
+func init() {
+	register(&Migration{
+		Version: 5,
+		Name:    "job_progress",
+		Up: func(tx *gorm.DB) error {
+			for _, column := range []string{"Owner", "PagesFetched", "CommitsStored"} {
+				if err := tx.Migrator().AddColumn(&jobV5{}, column); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&jobV5{}, "Owner")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&jobV5{}, "Owner"); err != nil {
+				return err
+			}
+			for _, column := range []string{"Owner", "PagesFetched", "CommitsStored"} {
+				if err := tx.Migrator().DropColumn(&jobV5{}, column); err != nil {
+					return err
+				}
+			}
+			return restoreIndexes(tx, &jobV3{})
+		},
+	})
+}
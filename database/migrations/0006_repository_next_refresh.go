@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type repositoryV6 struct {
+	gorm.Model
+	NextRefreshAt *time.Time `gorm:"index"`
+}
+
+func (repositoryV6) TableName() string { return "repositories" }
+
+func init() {
+	register(&Migration{
+		Version: 6,
+		Name:    "repository_next_refresh",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&repositoryV6{}, "NextRefreshAt"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&repositoryV6{}, "NextRefreshAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&repositoryV6{}, "NextRefreshAt"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&repositoryV6{}, "NextRefreshAt"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &repositoryV1{})
+		},
+	})
+}
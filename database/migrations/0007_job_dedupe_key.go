@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type jobV7 struct {
+	gorm.Model
+	DedupeKey string
+}
+
+func (jobV7) TableName() string { return "jobs" }
+
+func init() {
+	register(&Migration{
+		Version: 7,
+		Name:    "job_dedupe_key",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&jobV7{}, "DedupeKey"); err != nil {
+				return err
+			}
+			// at most one pending or running job per key; finished jobs keep their key for reference.
+			// partial indexes work the same in sqlite and postgres
+			return tx.Exec(`CREATE UNIQUE INDEX idx_jobs_active_dedupe_key ON jobs (dedupe_key) WHERE dedupe_key <> '' AND state IN ('pending', 'running')`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec(`DROP INDEX idx_jobs_active_dedupe_key`).Error; err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&jobV7{}, "DedupeKey"); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &jobV5{})
+		},
+	})
+}
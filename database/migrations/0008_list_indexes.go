@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// every sort of the repository and commit lists, scoped to the owner or repository they are listed for
+type repositoryV8 struct {
+	gorm.Model
+	OwnerID         uint       `gorm:"index:idx_repositories_owner_stars,priority:1;index:idx_repositories_owner_forks,priority:1;index:idx_repositories_owner_name,priority:1;index:idx_repositories_owner_updated,priority:1;index:idx_repositories_owner_created,priority:1"`
+	Name            string     `gorm:"index:idx_repositories_owner_name,priority:2"`
+	ForksCount      int        `gorm:"index:idx_repositories_owner_forks,priority:2"`
+	StarsCount      int        `gorm:"index:idx_repositories_owner_stars,priority:2"`
+	RemoteCreatedAt string     `gorm:"index:idx_repositories_owner_created,priority:2"`
+	RemoteUpdatedAt string     `gorm:"index:idx_repositories_owner_updated,priority:2"`
+	NextRefreshAt   *time.Time `gorm:"index"`
+}
+
+func (repositoryV8) TableName() string { return "repositories" }
+
+type commitV8 struct {
+	gorm.Model
+	RepositoryID uint   `gorm:"uniqueIndex:idx_commits_repository_sha;index:idx_commits_repository_date,priority:1"`
+	Date         string `gorm:"index:idx_commits_repository_date,priority:2"`
+	SHA          string `gorm:"uniqueIndex:idx_commits_repository_sha"`
+}
+
+func (commitV8) TableName() string { return "commits" }
+
+var repositoryListIndexes = []string{
+	"idx_repositories_owner_stars",
+	"idx_repositories_owner_forks",
+	"idx_repositories_owner_name",
+	"idx_repositories_owner_updated",
+	"idx_repositories_owner_created",
+}
+
+func init() {
+	register(&Migration{
+		Version: 8,
+		Name:    "list_indexes",
+		Up: func(tx *gorm.DB) error {
+			for _, name := range repositoryListIndexes {
+				if err := tx.Migrator().CreateIndex(&repositoryV8{}, name); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&commitV8{}, "idx_commits_repository_date")
+		},
+		Down: func(tx *gorm.DB) error {
+			for _, name := range repositoryListIndexes {
+				if err := tx.Migrator().DropIndex(&repositoryV8{}, name); err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().DropIndex(&commitV8{}, "idx_commits_repository_date")
+		},
+	})
+}
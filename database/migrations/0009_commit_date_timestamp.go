@@ -0,0 +1,66 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type commitV9 struct {
+	gorm.Model
+	RepositoryID uint `gorm:"uniqueIndex:idx_commits_repository_sha;index:idx_commits_repository_date,priority:1"`
+	Message      string
+	Author       string
+	Date         time.Time `gorm:"index:idx_commits_repository_date,priority:2"`
+	URL          string
+	SHA          string `gorm:"uniqueIndex:idx_commits_repository_sha"`
+}
+
+func (commitV9) TableName() string { return "commits" }
+
+// commits without a date get the zero time, which is what a time.Time column reads back as empty
+const zeroTimestamp = "0001-01-01 00:00:00+00:00"
+
+func init() {
+	register(&Migration{
+		Version: 9,
+		Name:    "commit_date_timestamp",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "postgres" {
+				return execAll(tx,
+					`UPDATE commits SET date = '`+zeroTimestamp+`' WHERE date IS NULL OR date = ''`,
+					`ALTER TABLE commits ALTER COLUMN date TYPE timestamptz USING date::timestamptz`,
+				)
+			}
+			// sqlite keeps timestamps as text, so they only compare correctly in the one format the
+			// driver writes them in: rewrite github's RFC3339 dates into it, in UTC
+			if err := tx.Migrator().AlterColumn(&commitV9{}, "Date"); err != nil {
+				return err
+			}
+			if err := execAll(tx,
+				`UPDATE commits SET date = strftime('%Y-%m-%d %H:%M:%S', date) || '+00:00' WHERE strftime('%Y-%m-%d %H:%M:%S', date) IS NOT NULL`,
+				`UPDATE commits SET date = '`+zeroTimestamp+`' WHERE date IS NULL OR strftime('%Y-%m-%d %H:%M:%S', date) IS NULL`,
+			); err != nil {
+				return err
+			}
+			// rebuilding the table for the new column type drops its indexes
+			return restoreIndexes(tx, &commitV9{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "postgres" {
+				return execAll(tx,
+					`ALTER TABLE commits ALTER COLUMN date TYPE text USING to_char(date AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"')`,
+				)
+			}
+			if err := tx.Migrator().AlterColumn(&commitV8{}, "Date"); err != nil {
+				return err
+			}
+			if err := execAll(tx,
+				`UPDATE commits SET date = strftime('%Y-%m-%dT%H:%M:%SZ', date)`,
+			); err != nil {
+				return err
+			}
+			return restoreIndexes(tx, &commitV8{})
+		},
+	})
+}
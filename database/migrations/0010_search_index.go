@@ -0,0 +1,34 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// postgres searches tsvectors of the searched text, which these expression indexes cover; the
+// queries have to build their tsvectors exactly the same way for the indexes to be used.
+// sqlite's fts5 tables aren't created here, as only some builds of the driver support them
+// (see database/search-fts5.go)
+func init() {
+	register(&Migration{
+		Version: 10,
+		Name:    "search_index",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "postgres" {
+				return nil
+			}
+			return execAll(tx,
+				`CREATE INDEX IF NOT EXISTS idx_commits_message_search ON commits
+					USING GIN (to_tsvector('simple', message))`,
+				`CREATE INDEX IF NOT EXISTS idx_repositories_search ON repositories
+					USING GIN (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(description, '') || ' ' || coalesce(language, '')))`,
+			)
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "postgres" {
+				return nil
+			}
+			return execAll(tx,
+				"DROP INDEX IF EXISTS idx_commits_message_search",
+				"DROP INDEX IF EXISTS idx_repositories_search",
+			)
+		},
+	})
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type repositorySnapshotV11 struct {
+	gorm.Model
+	RepositoryID uint `gorm:"index:idx_repository_snapshots_repository_taken,priority:1"`
+	StarsCount   int
+	ForksCount   int
+	OpenIssues   int
+	Watchers     int
+	TakenAt      time.Time `gorm:"index:idx_repository_snapshots_repository_taken,priority:2"`
+}
+
+func (repositorySnapshotV11) TableName() string { return "repository_snapshots" }
+
+func init() {
+	register(&Migration{
+		Version: 11,
+		Name:    "repository_snapshots",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&repositorySnapshotV11{}); err != nil {
+				return err
+			}
+			// the history of stored repositories starts with the metrics they were last stored with
+			return tx.Exec(`INSERT INTO repository_snapshots
+				(created_at, updated_at, repository_id, stars_count, forks_count, open_issues, watchers, taken_at)
+				SELECT updated_at, updated_at, id, stars_count, forks_count, open_issues, watchers, updated_at
+				FROM repositories WHERE deleted_at IS NULL`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&repositorySnapshotV11{})
+		},
+	})
+}
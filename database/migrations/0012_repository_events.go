@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type repositoryEventV12 struct {
+	gorm.Model
+	RepositoryID uint   `gorm:"index"`
+	Type         string `gorm:"index"`
+	Field        string
+	OldValue     string
+	NewValue     string
+	Delta        int
+	Summary      string
+}
+
+func (repositoryEventV12) TableName() string { return "repository_events" }
+
+func init() {
+	register(&Migration{
+		Version: 12,
+		Name:    "repository_events",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&repositoryEventV12{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&repositoryEventV12{})
+		},
+	})
+}
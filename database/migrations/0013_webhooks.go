@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webhookSubscriptionV13 struct {
+	gorm.Model
+	URL    string
+	Secret string
+	// a json array of event names
+	Events string
+}
+
+func (webhookSubscriptionV13) TableName() string { return "webhook_subscriptions" }
+
+type webhookDeliveryV13 struct {
+	gorm.Model
+	SubscriptionID uint `gorm:"index"`
+	JobID          uint `gorm:"index"`
+	Event          string
+	Payload        string
+	State          string `gorm:"index"`
+	Attempts       int
+	StatusCode     int
+	Error          string
+	LastAttemptAt  *time.Time
+	DeliveredAt    *time.Time
+	RedeliveryOf   *uint
+}
+
+func (webhookDeliveryV13) TableName() string { return "webhook_deliveries" }
+
+func init() {
+	register(&Migration{
+		Version: 13,
+		Name:    "webhooks",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&webhookSubscriptionV13{}, &webhookDeliveryV13{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&webhookDeliveryV13{}, &webhookSubscriptionV13{})
+		},
+	})
+}
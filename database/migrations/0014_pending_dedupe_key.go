@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+func init() {
+	register(&Migration{
+		Version: 14,
+		Name:    "pending_dedupe_key",
+		Up: func(tx *gorm.DB) error {
+			// a job queued while one with the same key is running may hold the key alongside it, so
+			// only pending jobs are kept unique; EnqueueUniqueJob still checks running jobs itself
+			if err := tx.Exec(`DROP INDEX idx_jobs_active_dedupe_key`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`CREATE UNIQUE INDEX idx_jobs_pending_dedupe_key ON jobs (dedupe_key) WHERE dedupe_key <> '' AND state = 'pending'`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Exec(`DROP INDEX idx_jobs_pending_dedupe_key`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`CREATE UNIQUE INDEX idx_jobs_active_dedupe_key ON jobs (dedupe_key) WHERE dedupe_key <> '' AND state IN ('pending', 'running')`).Error
+		},
+	})
+}
@@ -0,0 +1,61 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// the fts5 tables sqlite searches through, keyed by the rowid of the commit or repository they
+// index. only a sqlite driver built with fts5 can create them; on any other database, or a driver
+// without it, this is a no-op and search falls back to LIKE. postgres has its own indexes (0010)
+
+func init() {
+	register(&Migration{
+		Version: 15,
+		Name:    "sqlite_search_index",
+		Up: func(tx *gorm.DB) error {
+			if !fts5Available(tx) {
+				return nil
+			}
+			statements := []string{
+				// databases that ran earlier builds may already have the tables, set up on first use
+				"CREATE VIRTUAL TABLE IF NOT EXISTS commits_fts USING fts5(message)",
+				"CREATE VIRTUAL TABLE IF NOT EXISTS repositories_fts USING fts5(name, description, language)",
+				// commits never change once stored, so only the ones not indexed yet are added
+				`INSERT INTO commits_fts (rowid, message)
+					SELECT id, message FROM commits
+					WHERE deleted_at IS NULL AND id NOT IN (SELECT rowid FROM commits_fts)`,
+				// repositories are updated in place, so they are indexed afresh
+				"DELETE FROM repositories_fts",
+				`INSERT INTO repositories_fts (rowid, name, description, language)
+					SELECT id, name, description, language FROM repositories WHERE deleted_at IS NULL`,
+			}
+			for _, statement := range statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if !fts5Available(tx) {
+				return nil
+			}
+			if err := tx.Exec("DROP TABLE IF EXISTS repositories_fts").Error; err != nil {
+				return err
+			}
+			return tx.Exec("DROP TABLE IF EXISTS commits_fts").Error
+		},
+	})
+}
+
+// whether the database is sqlite with fts5 compiled into its driver (the sqlite_fts5 build tag)
+func fts5Available(tx *gorm.DB) bool {
+	if tx.Dialector.Name() != "sqlite" {
+		return false
+	}
+	var enabled int
+	if err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return false
+	}
+	return enabled == 1
+}
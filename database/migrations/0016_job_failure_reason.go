@@ -0,0 +1,32 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+type jobV16 struct {
+	gorm.Model
+	FailureReason string
+}
+
+func (jobV16) TableName() string { return "jobs" }
+
+func init() {
+	register(&Migration{
+		Version: 16,
+		Name:    "job_failure_reason",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&jobV16{}, "FailureReason")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropColumn(&jobV16{}, "FailureReason"); err != nil {
+				return err
+			}
+			if err := restoreIndexes(tx, &jobV5{}); err != nil {
+				return err
+			}
+			// sqlite rebuilds the table to drop a column, which loses the partial index 0014 made too
+			return tx.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_pending_dedupe_key ON jobs (dedupe_key) WHERE dedupe_key <> '' AND state = 'pending'`).Error
+		},
+	})
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// a numbered schema change; Up and Down run inside a transaction together with
+// the bookkeeping in schema_migrations
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+var registry = map[int]*Migration{}
+
+func register(migration *Migration) {
+	if _, exists := registry[migration.Version]; exists {
+		panic("duplicate migration version")
+	}
+	registry[migration.Version] = migration
+}
+
+// every known migration, ordered by version
+func All() []*Migration {
+	all := make([]*Migration, 0, len(registry))
+	for _, migration := range registry {
+		all = append(all, migration)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// the newest schema version this binary knows about
+func Latest() int {
+	latest := 0
+	for version := range registry {
+		if version > latest {
+			latest = version
+		}
+	}
+	return latest
+}
+
+// sqlite drops a table's indexes when it rebuilds the table to drop a column, so migrations
+// that drop columns recreate the indexes declared by the schema snapshot they roll back to
+func restoreIndexes(tx *gorm.DB, model interface{}) error {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if tx.Migrator().HasIndex(model, index.Name) {
+			continue
+		}
+		if err := tx.Migrator().CreateIndex(model, index.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package database
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+)
+
+// a field a list can be sorted by. value reads the field of a row for cursors, and parse turns
+// it back into what the column is compared with when paging past a cursor; nil compares text
+type sortField[T any] struct {
+	column string
+	parse  func(string) (interface{}, error)
+	desc   bool // default order
+	value  func(T) string
+}
+
+func parseIntCursor(value string) (interface{}, error) {
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func parseTimeCursor(value string) (interface{}, error) {
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	return parsed.UTC(), err
+}
+
+// the position after (or, with Before, ahead of) the row a cursor was handed out for. the
+// sort and order are kept in it so a cursor can't be replayed against a different ordering
+type pageCursor struct {
+	Sort   string `json:"s"`
+	Desc   bool   `json:"d,omitempty"`
+	Value  string `json:"v"`
+	ID     uint   `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+func encodeCursor(c *pageCursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, utils.ErrInvalidCursor
+	}
+	c := &pageCursor{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, utils.ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// run query for one page of list. rows are ordered by the sort field and then by idColumn,
+// so rows with equal values keep a stable order across pages. query must have its model set
+func paginate[T any](query *gorm.DB, list *utils.ListParams, defaultSort string, fields map[string]sortField[T], idColumn string, id func(T) uint) ([]T, *utils.PageInfo, error) {
+	if list == nil {
+		list = &utils.ListParams{}
+	}
+	perPage := list.PerPage
+	if perPage <= 0 {
+		perPage = utils.DefaultPerPage
+	}
+	var cursor *pageCursor
+	if list.Cursor != "" {
+		var err error
+		if cursor, err = decodeCursor(list.Cursor); err != nil {
+			return nil, nil, err
+		}
+	}
+	sort := list.Sort
+	if sort == "" {
+		sort = defaultSort
+		if cursor != nil {
+			sort = cursor.Sort
+		}
+	}
+	field, ok := fields[sort]
+	if !ok {
+		return nil, nil, utils.ErrInvalidSort
+	}
+	desc := field.desc
+	if list.Order != "" {
+		desc = list.Order == "desc"
+	} else if cursor != nil {
+		desc = cursor.Desc
+	}
+	if cursor != nil && (cursor.Sort != sort || cursor.Desc != desc) {
+		return nil, nil, utils.ErrInvalidCursor
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, nil, err
+	}
+	info := &utils.PageInfo{Total: total, Page: list.Page, PerPage: perPage}
+	var rows []T
+
+	if list.Page > 0 {
+		err := orderBy(query, field.column, idColumn, desc).
+			Offset((list.Page - 1) * perPage).Limit(perPage).
+			Find(&rows).Error
+		if err != nil {
+			return nil, nil, err
+		}
+		info.HasPrev = list.Page > 1
+		info.HasNext = int64(list.Page*perPage) < total
+		return rows, info, nil
+	}
+
+	// paging backwards walks the list in reverse and flips the rows back afterwards
+	before := cursor != nil && cursor.Before
+	scanDesc := desc != before
+	if cursor != nil {
+		var value interface{} = cursor.Value
+		if field.parse != nil {
+			parsed, err := field.parse(cursor.Value)
+			if err != nil {
+				return nil, nil, utils.ErrInvalidCursor
+			}
+			value = parsed
+		}
+		op := ">"
+		if scanDesc {
+			op = "<"
+		}
+		query = query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s %s ?))", field.column, op, field.column, idColumn, op), value, value, cursor.ID)
+	}
+	// one extra row tells whether there is another page in this direction
+	if err := orderBy(query, field.column, idColumn, scanDesc).Limit(perPage + 1).Find(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	more := len(rows) > perPage
+	if more {
+		rows = rows[:perPage]
+	}
+	if before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	// coming from a page means there is one in the direction we came from
+	info.HasNext = more && !before || before
+	info.HasPrev = more && before || cursor != nil && !before
+	if len(rows) == 0 {
+		return rows, info, nil
+	}
+	if info.HasNext {
+		last := rows[len(rows)-1]
+		info.NextCursor = encodeCursor(&pageCursor{Sort: sort, Desc: desc, Value: field.value(last), ID: id(last)})
+	}
+	if info.HasPrev {
+		first := rows[0]
+		info.PrevCursor = encodeCursor(&pageCursor{Sort: sort, Desc: desc, Value: field.value(first), ID: id(first), Before: true})
+	}
+	return rows, info, nil
+}
+
+func orderBy(query *gorm.DB, column, idColumn string, desc bool) *gorm.DB {
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	return query.Order(column + direction).Order(idColumn + direction)
+}
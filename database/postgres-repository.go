@@ -0,0 +1,116 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresDBRepository shares the gorm implementation with SqliteDBRepository and
+// only overrides the queries whose semantics differ between the two databases
+type PostgresDBRepository struct {
+	*SqliteDBRepository
+}
+
+func NewPostgresDBRepository(db *gorm.DB) *PostgresDBRepository {
+	return &PostgresDBRepository{SqliteDBRepository: NewSqliteDBRepository(db)}
+}
+
+func (p *PostgresDBRepository) SearchRepository(ctx context.Context, ownerID uint, repoSearchParams *utils.RepositorySearchParams, list *utils.ListParams) ([]*models.Repository, *utils.PageInfo, error) {
+	// LIKE is case-insensitive in sqlite but not in postgres, so use ILIKE to match
+	return p.searchRepository(ctx, ownerID, repoSearchParams, list, "ILIKE")
+}
+
+func (p *PostgresDBRepository) GetRepositoryCommits(ctx context.Context, owner, repoName string, filters *utils.CommitFilters, list *utils.ListParams) ([]*models.Commit, *utils.PageInfo, error) {
+	return p.getRepositoryCommits(ctx, owner, repoName, filters, list, "ILIKE")
+}
+
+func (p *PostgresDBRepository) ClaimJob(ctx context.Context, jobTypes []string, workerID string, lease time.Duration, maxAttempts int) (*models.Job, error) {
+	// SKIP LOCKED lets concurrent workers on other replicas claim different jobs instead of queueing on the same row
+	var claimed *models.Job
+	err := p.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := deadLetterAbandonedJobs(tx, jobTypes, maxAttempts, now); err != nil {
+			return err
+		}
+		job := &models.Job{}
+		err := claimableJobs(tx, jobTypes, now).
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			First(job).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		won, err := leaseJob(tx, job, workerID, now.Add(lease))
+		if err != nil || !won {
+			return err
+		}
+		claimed = job
+		return nil
+	})
+	return claimed, err
+}
+
+// these have to match the expressions indexed by the search_index migration
+const (
+	commitSearchDocument     = "to_tsvector('simple', commits.message)"
+	repositorySearchText     = "coalesce(repositories.name, '') || ' ' || coalesce(repositories.description, '') || ' ' || coalesce(repositories.language, '')"
+	repositorySearchDocument = "to_tsvector('simple', " + repositorySearchText + ")"
+	searchHeadlineOptions    = "StartSel=" + highlightStart + ", StopSel=" + highlightEnd + ", MaxWords=20, MinWords=8"
+)
+
+func (p *PostgresDBRepository) Search(ctx context.Context, query *utils.SearchQuery) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return p.SqliteDBRepository.Search(ctx, query)
+	}
+	// every term has to match as a prefix of a word, like the fts5 search does
+	prefixes := make([]string, 0, len(terms))
+	for _, term := range terms {
+		prefixes = append(prefixes, term+":*")
+	}
+	tsQuery := strings.Join(prefixes, " & ")
+
+	if query.Type == utils.SearchTypeRepositories {
+		base := p.DB.WithContext(ctx).Model(&models.Repository{}).
+			Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL").
+			Where(repositorySearchDocument+" @@ to_tsquery('simple', ?)", tsQuery)
+		var rows []*repositorySearchRow
+		page, err := scanSearchPage(scopeSearch(base, query), query, func(db *gorm.DB) *gorm.DB {
+			return db.Select("repositories.*, users.username AS owner_name, repositories.name AS repository_name, "+
+				"ts_rank("+repositorySearchDocument+", to_tsquery('simple', ?)) AS search_rank, "+
+				"ts_headline('simple', "+repositorySearchText+", to_tsquery('simple', ?), ?) AS snippet",
+				tsQuery, tsQuery, searchHeadlineOptions).
+				Order("search_rank DESC").Order("repositories.id DESC")
+		}, &rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		return repositoryResults(rows), page, nil
+	}
+
+	base := p.DB.WithContext(ctx).Model(&models.Commit{}).
+		Joins("JOIN repositories ON repositories.id = commits.repository_id AND repositories.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL").
+		Where(commitSearchDocument+" @@ to_tsquery('simple', ?)", tsQuery)
+	var rows []*commitSearchRow
+	page, err := scanSearchPage(scopeSearch(base, query), query, func(db *gorm.DB) *gorm.DB {
+		return db.Select("commits.*, users.username AS owner_name, repositories.name AS repository_name, "+
+			"ts_rank("+commitSearchDocument+", to_tsquery('simple', ?)) AS search_rank, "+
+			"ts_headline('simple', commits.message, to_tsquery('simple', ?), ?) AS snippet",
+			tsQuery, tsQuery, searchHeadlineOptions).
+			Order("search_rank DESC").Order("commits.id DESC")
+	}, &rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return commitResults(rows), page, nil
+}
@@ -0,0 +1,108 @@
+//go:build sqlite_fts5
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+)
+
+// the sqlite driver was built with fts5, so searches go through the commits_fts and
+// repositories_fts tables created by the sqlite_search_index migration, which are written in the
+// same transaction as the rows they index. a database migrated by a build without fts5 has no
+// such tables, and is searched with LIKE
+
+const fts5Enabled = true
+
+func (s *SqliteDBRepository) searchIndexReady(db *gorm.DB) bool {
+	if s.searchIndexFound.Load() {
+		return true
+	}
+	if db.Dialector.Name() != "sqlite" {
+		return false
+	}
+	found := db.Migrator().HasTable("commits_fts") && db.Migrator().HasTable("repositories_fts")
+	s.searchIndexFound.Store(found)
+	return found
+}
+
+func (s *SqliteDBRepository) indexRepository(tx *gorm.DB, repo *models.Repository) error {
+	if !s.searchIndexReady(tx) {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM repositories_fts WHERE rowid = ?", repo.ID).Error; err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO repositories_fts (rowid, name, description, language) VALUES (?, ?, ?, ?)",
+		repo.ID, repo.Name, repo.Description, repo.Language).Error
+}
+
+func (s *SqliteDBRepository) indexCommits(tx *gorm.DB, commits []*models.Commit) error {
+	if len(commits) == 0 || !s.searchIndexReady(tx) {
+		return nil
+	}
+	for _, commit := range commits {
+		if err := tx.Exec("INSERT INTO commits_fts (rowid, message) VALUES (?, ?)", commit.ID, commit.Message).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// every term has to match as a prefix of a word, so "fix pars" finds "fixed the parser"
+func matchExpression(terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, fmt.Sprintf(`"%s"*`, term))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// bm25 scores better matches lower, so ranks are its negation to have the best match ranked highest
+func (s *SqliteDBRepository) searchCommits(ctx context.Context, query *utils.SearchQuery, terms []string) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	if !s.searchIndexReady(s.DB.WithContext(ctx)) {
+		return s.searchCommitsLike(ctx, query, terms)
+	}
+	base := s.DB.WithContext(ctx).Table("commits_fts").
+		Joins("JOIN commits ON commits.id = commits_fts.rowid AND commits.deleted_at IS NULL").
+		Joins("JOIN repositories ON repositories.id = commits.repository_id AND repositories.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL").
+		Where("commits_fts MATCH ?", matchExpression(terms))
+	var rows []*commitSearchRow
+	page, err := scanSearchPage(scopeSearch(base, query), query, func(db *gorm.DB) *gorm.DB {
+		return db.Select("commits.*, users.username AS owner_name, repositories.name AS repository_name, "+
+			"-bm25(commits_fts) AS search_rank, snippet(commits_fts, 0, ?, ?, '…', 16) AS snippet", highlightStart, highlightEnd).
+			Order("bm25(commits_fts)").Order("commits.id DESC")
+	}, &rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return commitResults(rows), page, nil
+}
+
+func (s *SqliteDBRepository) searchRepositories(ctx context.Context, query *utils.SearchQuery, terms []string) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	if !s.searchIndexReady(s.DB.WithContext(ctx)) {
+		return s.searchRepositoriesLike(ctx, query, terms)
+	}
+	base := s.DB.WithContext(ctx).Table("repositories_fts").
+		Joins("JOIN repositories ON repositories.id = repositories_fts.rowid AND repositories.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL").
+		Where("repositories_fts MATCH ?", matchExpression(terms))
+	var rows []*repositorySearchRow
+	page, err := scanSearchPage(scopeSearch(base, query), query, func(db *gorm.DB) *gorm.DB {
+		// the snippet comes from whichever column matched best
+		return db.Select("repositories.*, users.username AS owner_name, repositories.name AS repository_name, "+
+			"-bm25(repositories_fts) AS search_rank, snippet(repositories_fts, -1, ?, ?, '…', 16) AS snippet", highlightStart, highlightEnd).
+			Order("bm25(repositories_fts)").Order("repositories.id DESC")
+	}, &rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repositoryResults(rows), page, nil
+}
@@ -0,0 +1,71 @@
+//go:build sqlite_fts5
+
+package database_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/database/migrations"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRanked_WithTheFTS5Index(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	assert.True(t, database.SearchRanked(db))
+}
+
+func TestSearch_RanksWithTheFTS5Index(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	repository := database.NewDBRepository(db)
+	owner := createTestUser(t, repository, "alice")
+	api := createTestRepository(t, repository, owner, 1, "api")
+	_, err := repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "a1", Message: "cache the cache of the cache", Date: "2024-07-01T00:00:00Z"},
+		{SHA: "a2", Message: "a long message that mentions the cache only once among many other words", Date: "2024-07-02T00:00:00Z"},
+	}, api)
+	require.NoError(t, err)
+	// stored before the index existed, the way an earlier build would have; the migration that
+	// creates the index picks it up
+	require.NoError(t, database.MigrateDown(db, migrations.Latest()-14))
+	require.NoError(t, db.Exec(`INSERT INTO commits (repository_id, sha, message, date) VALUES (?, 'a0', 'tidy up', '2024-06-30 00:00:00+00:00')`, api.ID).Error)
+	require.NoError(t, database.MigrateUp(db))
+	repository = database.NewDBRepository(db)
+
+	results, _, err := repository.Search(context.Background(), &utils.SearchQuery{Text: "cache", Type: utils.SearchTypeCommits})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	// the better match comes first even though it's older
+	assert.Equal(t, "a1", results[0].Commit.SHA)
+	assert.Greater(t, results[0].Rank, results[1].Rank)
+	assert.Greater(t, results[1].Rank, 0.0)
+
+	results, _, err = repository.Search(context.Background(), &utils.SearchQuery{Text: "tidy", Type: utils.SearchTypeCommits})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "<mark>tidy</mark> up", results[0].Snippet)
+}
+
+func TestStoreRepositoryCommits_FailsWithTheSearchIndex(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	repository := database.NewDBRepository(db)
+	owner := createTestUser(t, repository, "alice")
+	api := createTestRepository(t, repository, owner, 1, "api")
+
+	// a commit that can't be indexed isn't stored either, so the next sync fetches it again
+	require.NoError(t, db.Exec("DROP TABLE commits_fts").Error)
+	_, err := repository.StoreRepositoryCommits(context.Background(), &[]dto.CommitResponseDTO{
+		{SHA: "a1", Message: "fix the cache", Date: "2024-07-01T00:00:00Z"},
+	}, api)
+	assert.Error(t, err)
+	var stored int64
+	require.NoError(t, db.Table("commits").Where("sha = ?", "a1").Count(&stored).Error)
+	assert.Zero(t, stored)
+}
@@ -0,0 +1,33 @@
+//go:build !sqlite_fts5
+
+package database
+
+import (
+	"context"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+)
+
+// the sqlite driver was built without fts5, so there is no index to keep up to date and
+// searches fall back to LIKE
+
+const fts5Enabled = false
+
+func (s *SqliteDBRepository) searchCommits(ctx context.Context, query *utils.SearchQuery, terms []string) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	return s.searchCommitsLike(ctx, query, terms)
+}
+
+func (s *SqliteDBRepository) searchRepositories(ctx context.Context, query *utils.SearchQuery, terms []string) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	return s.searchRepositoriesLike(ctx, query, terms)
+}
+
+func (s *SqliteDBRepository) indexRepository(tx *gorm.DB, repo *models.Repository) error {
+	return nil
+}
+
+func (s *SqliteDBRepository) indexCommits(tx *gorm.DB, commits []*models.Commit) error {
+	return nil
+}
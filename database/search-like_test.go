@@ -0,0 +1,17 @@
+//go:build !sqlite_fts5
+
+package database_test
+
+import (
+	"testing"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchRanked_WithoutFTS5(t *testing.T) {
+	db := openMemoryDB(t)
+	require.NoError(t, database.MigrateUp(db))
+	assert.False(t, database.SearchRanked(db))
+}
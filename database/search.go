@@ -0,0 +1,227 @@
+package database
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+)
+
+// full text search over commit messages and repository names, descriptions and languages.
+// sqlite searches fts5 tables when the driver is built with the sqlite_fts5 tag (search-fts5.go)
+// and falls back to LIKE without it (search-like.go); postgres searches tsvector expression indexes.
+// the fts5 tables are written together with the rows they index, so a write fails as a whole
+
+const (
+	highlightStart = "<mark>"
+	highlightEnd   = "</mark>"
+	// characters of context kept either side of the first match in snippets built here
+	snippetContext = 60
+)
+
+// whether searches are ranked. sqlite needs a driver built with fts5 and the tables the
+// sqlite_search_index migration creates with it; without them it searches with LIKE
+func SearchRanked(db *gorm.DB) bool {
+	if db.Dialector.Name() != "sqlite" {
+		return true
+	}
+	return fts5Enabled && db.Migrator().HasTable("commits_fts") && db.Migrator().HasTable("repositories_fts")
+}
+
+// what a search row carries besides the commit or repository it found
+type searchMatch struct {
+	OwnerName      string
+	RepositoryName string
+	Rank           float64 `gorm:"column:search_rank"`
+	Snippet        string
+}
+
+// gorm only scans into exported fields, hence the named, embedded match
+type commitSearchRow struct {
+	models.Commit
+	Match searchMatch `gorm:"embedded"`
+}
+
+type repositorySearchRow struct {
+	models.Repository
+	Match searchMatch `gorm:"embedded"`
+}
+
+func (s *SqliteDBRepository) Search(ctx context.Context, query *utils.SearchQuery) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return []*dto.SearchResultResponseDTO{}, &utils.PageInfo{Page: searchPageNumber(query), PerPage: searchPerPage(query)}, nil
+	}
+	if query.Type == utils.SearchTypeRepositories {
+		return s.searchRepositories(ctx, query, terms)
+	}
+	return s.searchCommits(ctx, query, terms)
+}
+
+// the words of a search, lowercased and split on anything but letters and digits so they are
+// safe to put into the query syntax of every backend
+func searchTerms(text string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, term := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+func searchPageNumber(query *utils.SearchQuery) int {
+	if query.Page < 1 {
+		return 1
+	}
+	return query.Page
+}
+
+func searchPerPage(query *utils.SearchQuery) int {
+	if query.PerPage < 1 {
+		return utils.DefaultPerPage
+	}
+	return query.PerPage
+}
+
+// limit a search joined with repositories and users to the query's owner and repository
+func scopeSearch(db *gorm.DB, query *utils.SearchQuery) *gorm.DB {
+	if query.Owner != "" {
+		db = db.Where("users.username =?", query.Owner)
+	}
+	if query.RepoName != "" {
+		db = db.Where("repositories.name =?", query.RepoName)
+	}
+	return db
+}
+
+// count the matches of base, then scan the page of them selected and ordered by page into rows
+func scanSearchPage(base *gorm.DB, query *utils.SearchQuery, page func(*gorm.DB) *gorm.DB, rows interface{}) (*utils.PageInfo, error) {
+	info := &utils.PageInfo{Page: searchPageNumber(query), PerPage: searchPerPage(query)}
+	if err := base.Session(&gorm.Session{}).Count(&info.Total).Error; err != nil {
+		return nil, err
+	}
+	err := page(base).Offset((info.Page - 1) * info.PerPage).Limit(info.PerPage).Scan(rows).Error
+	if err != nil {
+		return nil, err
+	}
+	info.HasPrev = info.Page > 1
+	info.HasNext = int64(info.Page*info.PerPage) < info.Total
+	return info, nil
+}
+
+func commitResults(rows []*commitSearchRow) []*dto.SearchResultResponseDTO {
+	results := make([]*dto.SearchResultResponseDTO, 0, len(rows))
+	for _, row := range rows {
+		commit := row.Commit
+		results = append(results, &dto.SearchResultResponseDTO{
+			Type: "commit", Owner: row.Match.OwnerName, RepositoryName: row.Match.RepositoryName,
+			Rank: row.Match.Rank, Snippet: row.Match.Snippet, Commit: &commit,
+		})
+	}
+	return results
+}
+
+func repositoryResults(rows []*repositorySearchRow) []*dto.SearchResultResponseDTO {
+	results := make([]*dto.SearchResultResponseDTO, 0, len(rows))
+	for _, row := range rows {
+		repo := row.Repository
+		results = append(results, &dto.SearchResultResponseDTO{
+			Type: "repository", Owner: row.Match.OwnerName, RepositoryName: row.Name,
+			Rank: row.Match.Rank, Snippet: row.Match.Snippet, Repository: &repo,
+		})
+	}
+	return results
+}
+
+// without an index every term has to appear somewhere in the searched fields; results come
+// newest first and aren't ranked
+func (s *SqliteDBRepository) searchCommitsLike(ctx context.Context, query *utils.SearchQuery, terms []string) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	base := s.DB.WithContext(ctx).Model(&models.Commit{}).
+		Joins("JOIN repositories ON repositories.id = commits.repository_id AND repositories.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL")
+	for _, term := range terms {
+		base = base.Where("commits.message LIKE ?", "%"+term+"%")
+	}
+	var rows []*commitSearchRow
+	page, err := scanSearchPage(scopeSearch(base, query), query, func(db *gorm.DB) *gorm.DB {
+		return db.Select("commits.*, users.username AS owner_name, repositories.name AS repository_name").
+			Order("commits.date DESC").Order("commits.id DESC")
+	}, &rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		row.Match.Snippet = highlightSnippet(row.Message, terms)
+	}
+	return commitResults(rows), page, nil
+}
+
+func (s *SqliteDBRepository) searchRepositoriesLike(ctx context.Context, query *utils.SearchQuery, terms []string) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	base := s.DB.WithContext(ctx).Model(&models.Repository{}).
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL")
+	for _, term := range terms {
+		pattern := "%" + term + "%"
+		base = base.Where("(repositories.name LIKE ? OR repositories.description LIKE ? OR repositories.language LIKE ?)", pattern, pattern, pattern)
+	}
+	var rows []*repositorySearchRow
+	page, err := scanSearchPage(scopeSearch(base, query), query, func(db *gorm.DB) *gorm.DB {
+		return db.Select("repositories.*, users.username AS owner_name, repositories.name AS repository_name").
+			Order("repositories.stars_count DESC").Order("repositories.id DESC")
+	}, &rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, row := range rows {
+		row.Match.Snippet = highlightSnippet(row.Name, terms)
+		for _, text := range []string{row.Description, row.Language} {
+			if snippet := highlightSnippet(text, terms); strings.Contains(snippet, highlightStart) {
+				row.Match.Snippet = snippet
+				break
+			}
+		}
+	}
+	return repositoryResults(rows), page, nil
+}
+
+// the text around the first match of any term, with every match in it highlighted the way the
+// fts5 and postgres snippets are
+func highlightSnippet(text string, terms []string) string {
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+	matcher := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	start, end := 0, len(text)
+	if match := matcher.FindStringIndex(text); match != nil {
+		start = max(0, match[0]-snippetContext)
+		end = min(len(text), match[1]+snippetContext)
+	} else {
+		end = min(len(text), 2*snippetContext)
+	}
+	// don't cut through a multi-byte character
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+	snippet := matcher.ReplaceAllString(text[start:end], highlightStart+"$0"+highlightEnd)
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(text) {
+		snippet += "…"
+	}
+	return snippet
+}
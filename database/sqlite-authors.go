@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// the authors with the most stored commits, ties going to whoever committed last
+func (s *SqliteDBRepository) GetTopCommitAuthors(ctx context.Context, statsQuery *utils.AuthorStatsQuery) ([]*dto.AuthorStatsResponseDTO, error) {
+	query := s.DB.WithContext(ctx).Model(&models.Commit{}).
+		Select("commits.author AS author, COUNT(*) AS commit_count, COUNT(DISTINCT commits.repository_id) AS repository_count, " +
+			"MIN(commits.date) AS first_commit_at, MAX(commits.date) AS last_commit_at").
+		Joins("JOIN repositories ON repositories.id = commits.repository_id AND repositories.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL")
+	if statsQuery.Owner != "" {
+		query = query.Where("users.username =?", statsQuery.Owner)
+	}
+	if statsQuery.RepoName != "" {
+		query = query.Where("repositories.name =?", statsQuery.RepoName)
+	}
+	if statsQuery.Since != nil {
+		query = query.Where("commits.date >= ?", statsQuery.Since.UTC())
+	}
+	if statsQuery.Until != nil {
+		query = query.Where("commits.date <= ?", statsQuery.Until.UTC())
+	}
+	// aggregated dates come back as text from sqlite and as timestamps from postgres, both of which scan into strings
+	var rows []struct {
+		Author          string
+		CommitCount     int64
+		RepositoryCount int64
+		FirstCommitAt   string
+		LastCommitAt    string
+	}
+	err := query.Group("commits.author").
+		Order("commit_count DESC").Order("last_commit_at DESC").Order("author").
+		Limit(statsQuery.Top).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	authors := make([]*dto.AuthorStatsResponseDTO, 0, len(rows))
+	for _, row := range rows {
+		first, err := parseAggregateTime(row.FirstCommitAt)
+		if err != nil {
+			return nil, err
+		}
+		last, err := parseAggregateTime(row.LastCommitAt)
+		if err != nil {
+			return nil, err
+		}
+		authors = append(authors, &dto.AuthorStatsResponseDTO{
+			Author:        row.Author,
+			Commits:       row.CommitCount,
+			Repositories:  row.RepositoryCount,
+			FirstCommitAt: first,
+			LastCommitAt:  last,
+		})
+	}
+	return authors, nil
+}
+
+// the layouts timestamps are written in by the sqlite driver and read back as by database/sql
+var aggregateTimeLayouts = []string{"2006-01-02 15:04:05.999999999-07:00", time.RFC3339Nano}
+
+func parseAggregateTime(value string) (time.Time, error) {
+	for _, layout := range aggregateTimeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected timestamp %q", value)
+}
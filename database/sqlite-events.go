@@ -0,0 +1,26 @@
+package database
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// events are written in the order they happen, so their ids order them by time
+var repositoryEventSortFields = map[string]sortField[*models.RepositoryEvent]{
+	"created": {column: "repository_events.id", parse: parseIntCursor, desc: true, value: func(e *models.RepositoryEvent) string { return strconv.FormatUint(uint64(e.ID), 10) }},
+}
+
+func repositoryEventID(e *models.RepositoryEvent) uint { return e.ID }
+
+// a page of a repository's change events, newest first unless ordered otherwise; an empty
+// eventType lists every type
+func (s *SqliteDBRepository) GetRepositoryEvents(ctx context.Context, repo *models.Repository, eventType string, list *utils.ListParams) ([]*models.RepositoryEvent, *utils.PageInfo, error) {
+	query := s.DB.WithContext(ctx).Model(&models.RepositoryEvent{}).Where("repository_id =?", repo.ID)
+	if eventType != "" {
+		query = query.Where("type =?", eventType)
+	}
+	return paginate(query, list, "created", repositoryEventSortFields, "repository_events.id", repositoryEventID)
+}
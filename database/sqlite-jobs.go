@@ -0,0 +1,441 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/midedickson/github-service/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// add a job to the queue as pending; the caller fills in its type, owner, payload and run at
+func (s *SqliteDBRepository) EnqueueJob(ctx context.Context, job *models.Job) error {
+	job.State = models.JobStatePending
+	return s.DB.WithContext(ctx).Create(job).Error
+}
+
+// add a job unless a pending or running job has the same dedupe key, in which case that job is
+// returned instead; the bool reports whether the job was added
+func (s *SqliteDBRepository) EnqueueUniqueJob(ctx context.Context, job *models.Job) (*models.Job, bool, error) {
+	return s.enqueueUniqueJob(ctx, job, models.JobStatePending, models.JobStateRunning)
+}
+
+// like EnqueueUniqueJob, but only a pending job with the same key is returned instead. a running
+// job may have read what it works from before the caller saw a change, so another is queued behind it
+func (s *SqliteDBRepository) EnqueuePendingUniqueJob(ctx context.Context, job *models.Job) (*models.Job, bool, error) {
+	return s.enqueueUniqueJob(ctx, job, models.JobStatePending)
+}
+
+func (s *SqliteDBRepository) enqueueUniqueJob(ctx context.Context, job *models.Job, states ...string) (*models.Job, bool, error) {
+	existing, err := s.jobByDedupeKey(ctx, job.DedupeKey, states)
+	if err != nil || existing != nil {
+		return existing, false, err
+	}
+	if err := s.EnqueueJob(ctx, job); err != nil {
+		// a concurrent enqueue of the same key got in first and the unique index turned this one away
+		if existing, findErr := s.jobByDedupeKey(ctx, job.DedupeKey, states); findErr == nil && existing != nil {
+			return existing, false, nil
+		}
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+func (s *SqliteDBRepository) jobByDedupeKey(ctx context.Context, dedupeKey string, states []string) (*models.Job, error) {
+	job := &models.Job{}
+	err := s.DB.WithContext(ctx).
+		Where("dedupe_key =?", dedupeKey).
+		Where("state IN ?", states).
+		First(job).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// the newest job with the dedupe key, whatever its state; the owner narrows the lookup to the
+// owner index, as only active jobs are indexed by key
+func (s *SqliteDBRepository) GetLatestJobByDedupeKey(ctx context.Context, owner, dedupeKey string) (*models.Job, error) {
+	job := &models.Job{}
+	err := s.DB.WithContext(ctx).Where("owner =?", owner).Where("dedupe_key =?", dedupeKey).Order("id DESC").First(job).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *SqliteDBRepository) GetJob(ctx context.Context, id uint) (*models.Job, error) {
+	job := &models.Job{}
+	err := s.DB.WithContext(ctx).First(job, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return job, nil
+}
+
+// the most recent jobs of an owner, newest first
+func (s *SqliteDBRepository) GetJobsByOwner(ctx context.Context, owner string, limit int) ([]*models.Job, error) {
+	jobs := []*models.Job{}
+	err := s.DB.WithContext(ctx).Where("owner =?", owner).Order("id DESC").Limit(limit).Find(&jobs).Error
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// how many of an owner's jobs are in each state
+func (s *SqliteDBRepository) CountJobsByState(ctx context.Context, owner string) (map[string]int64, error) {
+	var rows []struct {
+		State string
+		Count int64
+	}
+	err := s.DB.WithContext(ctx).Model(&models.Job{}).Select("state, COUNT(*) AS count").Where("owner =?", owner).Group("state").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]int64{}
+	for _, row := range rows {
+		counts[row.State] = row.Count
+	}
+	return counts, nil
+}
+
+func (s *SqliteDBRepository) CountPendingJobs(ctx context.Context, jobType string) (int64, error) {
+	var count int64
+	err := s.DB.WithContext(ctx).Model(&models.Job{}).Where("state =?", models.JobStatePending).Where("type =?", jobType).Count(&count).Error
+	return count, err
+}
+
+// how many jobs of each type are pending and running, keyed by type and then state
+func (s *SqliteDBRepository) CountActiveJobsByType(ctx context.Context) (map[string]map[string]int64, error) {
+	var rows []struct {
+		Type  string
+		State string
+		Count int64
+	}
+	err := s.DB.WithContext(ctx).Model(&models.Job{}).
+		Select("type, state, COUNT(*) AS count").
+		Where("state IN ?", []string{models.JobStatePending, models.JobStateRunning}).
+		Group("type").Group("state").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := map[string]map[string]int64{}
+	for _, row := range rows {
+		if counts[row.Type] == nil {
+			counts[row.Type] = map[string]int64{}
+		}
+		counts[row.Type][row.State] = row.Count
+	}
+	return counts, nil
+}
+
+// record how far the worker holding the job has got
+func (s *SqliteDBRepository) UpdateJobProgress(ctx context.Context, job *models.Job) error {
+	result := ownedJob(s.DB.WithContext(ctx), job).Updates(map[string]interface{}{
+		"pages_fetched":  job.PagesFetched,
+		"commits_stored": job.CommitsStored,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	return nil
+}
+
+// jobs that are due, or running under a lease that has run out
+func claimableJobs(db *gorm.DB, jobTypes []string, now time.Time) *gorm.DB {
+	return db.Where("type IN ?", jobTypes).
+		Where("(state = ? AND run_at <= ?) OR (state = ? AND lease_expires_at < ?)",
+			models.JobStatePending, now, models.JobStateRunning, now).
+		Order("run_at").Order("id")
+}
+
+// take a lease on the next due job of the given types; returns nil when there is nothing to do.
+// jobs whose lease ran out on their last attempt, out of maxAttempts, are dead-lettered instead
+func (s *SqliteDBRepository) ClaimJob(ctx context.Context, jobTypes []string, workerID string, lease time.Duration, maxAttempts int) (*models.Job, error) {
+	var claimed *models.Job
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := deadLetterAbandonedJobs(tx, jobTypes, maxAttempts, now); err != nil {
+			return err
+		}
+		job := &models.Job{}
+		err := claimableJobs(tx, jobTypes, now).First(job).Error
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		won, err := leaseJob(tx, job, workerID, now.Add(lease))
+		if err != nil || !won {
+			return err
+		}
+		claimed = job
+		return nil
+	})
+	return claimed, err
+}
+
+// give up on running jobs whose lease ran out on their last attempt. the worker running them most
+// likely died with them, through a panic, running out of memory or a crash, so handing them to
+// another worker would only take that one down too
+func deadLetterAbandonedJobs(tx *gorm.DB, jobTypes []string, maxAttempts int, now time.Time) error {
+	if maxAttempts <= 0 {
+		return nil
+	}
+	abandoned := []*models.Job{}
+	err := tx.Where("type IN ?", jobTypes).
+		Where("state = ? AND lease_expires_at < ? AND attempts >= ?", models.JobStateRunning, now, maxAttempts).
+		Find(&abandoned).Error
+	if err != nil {
+		return err
+	}
+	for _, job := range abandoned {
+		errMsg := fmt.Sprintf("lease expired on attempt %d without the job finishing", job.Attempts)
+		// matched on attempts like leaseJob, so when two workers get here only one gives up on the job
+		result := tx.Model(&models.Job{}).
+			Where("id = ? AND state = ? AND attempts = ?", job.ID, models.JobStateRunning, job.Attempts).
+			Updates(map[string]interface{}{
+				"state":            models.JobStateFailed,
+				"failure_reason":   models.DeadLetterReasonLeaseExpired,
+				"last_error":       errMsg,
+				"finished_at":      now,
+				"lease_owner":      "",
+				"lease_expires_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		err := tx.Create(&models.DeadLetter{
+			JobID:    job.ID,
+			JobType:  job.Type,
+			Reason:   models.DeadLetterReasonLeaseExpired,
+			Attempts: job.Attempts,
+			Error:    errMsg,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// move a job to running under workerID; attempts doubles as a version number, so when two
+// workers read the same job only the first update matches and the other one backs off
+func leaseJob(tx *gorm.DB, job *models.Job, workerID string, leaseExpiresAt time.Time) (bool, error) {
+	result := tx.Model(&models.Job{}).
+		Where("id = ? AND state = ? AND attempts = ?", job.ID, job.State, job.Attempts).
+		Updates(map[string]interface{}{
+			"state":            models.JobStateRunning,
+			"attempts":         job.Attempts + 1,
+			"lease_owner":      workerID,
+			"lease_expires_at": leaseExpiresAt,
+			"pages_fetched":    0,
+			"commits_stored":   0,
+		})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	job.State = models.JobStateRunning
+	job.Attempts++
+	job.LeaseOwner = workerID
+	job.LeaseExpiresAt = &leaseExpiresAt
+	job.PagesFetched = 0
+	job.CommitsStored = 0
+	return true, nil
+}
+
+// push the lease of a long running job forward so it isn't handed to another worker
+func (s *SqliteDBRepository) ExtendJobLease(ctx context.Context, job *models.Job, lease time.Duration) error {
+	leaseExpiresAt := time.Now().Add(lease)
+	result := ownedJob(s.DB.WithContext(ctx), job).Update("lease_expires_at", leaseExpiresAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	job.LeaseExpiresAt = &leaseExpiresAt
+	return nil
+}
+
+func (s *SqliteDBRepository) CompleteJob(ctx context.Context, job *models.Job) error {
+	return finishJob(s.DB.WithContext(ctx), job, models.JobStateSucceeded, "", "")
+}
+
+// give up on a job and move it to the dead letters
+func (s *SqliteDBRepository) FailJob(ctx context.Context, job *models.Job, reason, errMsg string) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := finishJob(tx, job, models.JobStateFailed, reason, errMsg); err != nil {
+			return err
+		}
+		return tx.Create(&models.DeadLetter{
+			JobID:    job.ID,
+			JobType:  job.Type,
+			Reason:   reason,
+			Attempts: job.Attempts,
+			Error:    errMsg,
+		}).Error
+	})
+}
+
+// release a failed job back to the queue to be tried again at runAt
+func (s *SqliteDBRepository) RetryJob(ctx context.Context, job *models.Job, errMsg string, runAt time.Time) error {
+	result := ownedJob(s.DB.WithContext(ctx), job).Updates(map[string]interface{}{
+		"state":            models.JobStatePending,
+		"run_at":           runAt,
+		"last_error":       errMsg,
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+		"dedupe_key":       yieldedDedupeKey(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	job.State = models.JobStatePending
+	job.RunAt = runAt
+	job.LastError = errMsg
+	job.LeaseOwner = ""
+	job.LeaseExpiresAt = nil
+	return nil
+}
+
+// hand an interrupted job back to the queue straight away; the attempt it was on doesn't
+// count, since the job didn't fail, the worker running it was stopped
+func (s *SqliteDBRepository) ReleaseJob(ctx context.Context, job *models.Job) error {
+	runAt := time.Now()
+	result := ownedJob(s.DB.WithContext(ctx), job).Updates(map[string]interface{}{
+		"state":            models.JobStatePending,
+		"attempts":         gorm.Expr("attempts - 1"),
+		"run_at":           runAt,
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+		"dedupe_key":       yieldedDedupeKey(),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	job.State = models.JobStatePending
+	job.Attempts--
+	job.RunAt = runAt
+	job.LeaseOwner = ""
+	job.LeaseExpiresAt = nil
+	return nil
+}
+
+// a job going back to the queue gives up its dedupe key when another job with the key was queued
+// behind it while it ran; only one pending job may hold a key, and that one does the same work
+func yieldedDedupeKey() clause.Expr {
+	return gorm.Expr("CASE WHEN EXISTS (SELECT 1 FROM jobs AS queued WHERE queued.dedupe_key = jobs.dedupe_key AND queued.dedupe_key <> '' AND queued.state = ? AND queued.id <> jobs.id) THEN '' ELSE jobs.dedupe_key END", models.JobStatePending)
+}
+
+func finishJob(db *gorm.DB, job *models.Job, state, reason, errMsg string) error {
+	finishedAt := time.Now()
+	result := ownedJob(db, job).Updates(map[string]interface{}{
+		"state":            state,
+		"failure_reason":   reason,
+		"last_error":       errMsg,
+		"finished_at":      finishedAt,
+		"lease_owner":      "",
+		"lease_expires_at": nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrJobLeaseLost
+	}
+	job.State = state
+	job.FailureReason = reason
+	job.LastError = errMsg
+	job.FinishedAt = &finishedAt
+	job.LeaseOwner = ""
+	job.LeaseExpiresAt = nil
+	return nil
+}
+
+// scope updates to the job while it is still leased by the worker holding it
+func ownedJob(db *gorm.DB, job *models.Job) *gorm.DB {
+	return db.Model(&models.Job{}).
+		Where("id = ? AND state = ? AND lease_owner = ?", job.ID, models.JobStateRunning, job.LeaseOwner)
+}
+
+// dead letters, newest first, optionally only those of one job type
+func (s *SqliteDBRepository) GetDeadLetters(ctx context.Context, jobType string) ([]*models.DeadLetter, error) {
+	deadLetters := []*models.DeadLetter{}
+	dbQueryBuilder := s.DB.WithContext(ctx).Order("id DESC")
+	if jobType != "" {
+		dbQueryBuilder = dbQueryBuilder.Where("job_type =?", jobType)
+	}
+	if err := dbQueryBuilder.Find(&deadLetters).Error; err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+func (s *SqliteDBRepository) GetDeadLetter(ctx context.Context, id uint) (*models.DeadLetter, error) {
+	deadLetter := &models.DeadLetter{}
+	err := s.DB.WithContext(ctx).Preload("Job").First(deadLetter, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return deadLetter, nil
+}
+
+// put a dead job back in the queue with a fresh retry budget; the dead letter is removed
+func (s *SqliteDBRepository) RequeueDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) (*models.Job, error) {
+	job := &models.Job{}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Job{}).
+			Where("id = ? AND state = ?", deadLetter.JobID, models.JobStateFailed).
+			Updates(map[string]interface{}{
+				"state":          models.JobStatePending,
+				"attempts":       0,
+				"run_at":         time.Now(),
+				"failure_reason": "",
+				"finished_at":    nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrJobNotFailed
+		}
+		if err := tx.Delete(deadLetter).Error; err != nil {
+			return err
+		}
+		return tx.First(job, deadLetter.JobID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+)
+
+// the repositories and repository_snapshots column of every metric
+var metricColumns = map[string]string{
+	utils.MetricStars:      "stars_count",
+	utils.MetricForks:      "forks_count",
+	utils.MetricOpenIssues: "open_issues",
+	utils.MetricWatchers:   "watchers",
+}
+
+func metricValue(snapshot *models.RepositorySnapshot, metric string) int {
+	switch metric {
+	case utils.MetricForks:
+		return snapshot.ForksCount
+	case utils.MetricOpenIssues:
+		return snapshot.OpenIssues
+	case utils.MetricWatchers:
+		return snapshot.Watchers
+	default:
+		return snapshot.StarsCount
+	}
+}
+
+func repositorySnapshot(repo *models.Repository) *models.RepositorySnapshot {
+	return &models.RepositorySnapshot{
+		RepositoryID: repo.ID,
+		StarsCount:   repo.StarsCount,
+		ForksCount:   repo.ForksCount,
+		OpenIssues:   repo.OpenIssues,
+		Watchers:     repo.Watchers,
+		// sqlite compares timestamps as text, so they are all kept in utc
+		TakenAt: time.Now().UTC(),
+	}
+}
+
+func (s *SqliteDBRepository) GetRepositoryMetricHistory(ctx context.Context, repo *models.Repository, historyQuery *utils.MetricHistoryQuery) (*dto.MetricHistoryResponseDTO, error) {
+	from := utils.IntervalStart(historyQuery.From, historyQuery.Interval)
+	to := historyQuery.To.UTC()
+	snapshots := s.DB.WithContext(ctx).Model(&models.RepositorySnapshot{}).Where("repository_id =?", repo.ID)
+
+	// the value going into the range is the last one recorded before it
+	var before []*models.RepositorySnapshot
+	err := snapshots.Session(&gorm.Session{}).Where("taken_at < ?", from).
+		Order("taken_at DESC").Order("id DESC").Limit(1).Find(&before).Error
+	if err != nil {
+		return nil, err
+	}
+	var within []*models.RepositorySnapshot
+	err = snapshots.Session(&gorm.Session{}).Where("taken_at >= ? AND taken_at <= ?", from, to).
+		Order("taken_at").Order("id").Find(&within).Error
+	if err != nil {
+		return nil, err
+	}
+
+	history := &dto.MetricHistoryResponseDTO{
+		Metric: historyQuery.Metric, Interval: historyQuery.Interval, From: from, To: to,
+		Points: []*dto.MetricPointDTO{},
+	}
+	var latest *models.RepositorySnapshot
+	if len(before) > 0 {
+		latest = before[0]
+	}
+	next := 0
+	for start := from; !start.After(to); start = utils.NextInterval(start, historyQuery.Interval) {
+		end := utils.NextInterval(start, historyQuery.Interval)
+		for next < len(within) && within[next].TakenAt.Before(end) {
+			latest = within[next]
+			next++
+		}
+		if latest != nil {
+			history.Points = append(history.Points, &dto.MetricPointDTO{Time: start, Value: metricValue(latest, historyQuery.Metric)})
+		}
+	}
+	return history, nil
+}
+
+type trendingRow struct {
+	models.Repository
+	OwnerName     string
+	PreviousValue int
+	CurrentValue  int
+}
+
+// growth is measured from the last snapshot taken before the window started, or from the first
+// one for repositories first stored within the window; repositories that didn't grow are left out
+func (s *SqliteDBRepository) GetTrendingRepositories(ctx context.Context, trendingQuery *utils.TrendingQuery) ([]*dto.TrendingRepositoryResponseDTO, error) {
+	column, ok := metricColumns[trendingQuery.Metric]
+	if !ok {
+		return nil, fmt.Errorf("unknown metric %q", trendingQuery.Metric)
+	}
+	previous := fmt.Sprintf(`COALESCE(
+		(SELECT snapshots.%[1]s FROM repository_snapshots snapshots
+			WHERE snapshots.repository_id = repositories.id AND snapshots.deleted_at IS NULL AND snapshots.taken_at <= ?
+			ORDER BY snapshots.taken_at DESC, snapshots.id DESC LIMIT 1),
+		(SELECT snapshots.%[1]s FROM repository_snapshots snapshots
+			WHERE snapshots.repository_id = repositories.id AND snapshots.deleted_at IS NULL
+			ORDER BY snapshots.taken_at, snapshots.id LIMIT 1),
+		repositories.%[1]s)`, column)
+	repositories := s.DB.WithContext(ctx).Model(&models.Repository{}).
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL").
+		Select("repositories.*, users.username AS owner_name, repositories."+column+" AS current_value, "+previous+" AS previous_value", trendingQuery.Since.UTC())
+	if trendingQuery.Owner != "" {
+		repositories = repositories.Where("users.username =?", trendingQuery.Owner)
+	}
+
+	var rows []*trendingRow
+	err := s.DB.WithContext(ctx).Table("(?) AS trending", repositories).
+		Where("current_value > previous_value").
+		Order("current_value - previous_value DESC").Order("id").
+		Limit(trendingQuery.Top).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	trending := make([]*dto.TrendingRepositoryResponseDTO, 0, len(rows))
+	for _, row := range rows {
+		repo := row.Repository
+		trending = append(trending, &dto.TrendingRepositoryResponseDTO{
+			Owner: row.OwnerName, RepositoryName: repo.Name, Metric: trendingQuery.Metric,
+			Previous: row.PreviousValue, Current: row.CurrentValue, Growth: row.CurrentValue - row.PreviousValue,
+			Repository: &repo,
+		})
+	}
+	return trending, nil
+}
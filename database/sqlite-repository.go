@@ -1,75 +1,74 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/midedickson/github-service/dto"
 	"github.com/midedickson/github-service/models"
 	"github.com/midedickson/github-service/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SqliteDBRepository struct {
 	DB *gorm.DB
+	// whether the fts5 search tables have been found; see search-fts5.go
+	searchIndexFound atomic.Bool
 }
 
 func NewSqliteDBRepository(db *gorm.DB) *SqliteDBRepository {
 	return &SqliteDBRepository{DB: db}
 }
 
-func (s *SqliteDBRepository) CreateUser(createUserPaylod *dto.CreateUserPayloadDTO) (*models.User, error) {
+func (s *SqliteDBRepository) CreateUser(ctx context.Context, createUserPaylod *dto.CreateUserPayloadDTO) (*models.User, error) {
 	// Create a user from payload
-	existingUser, err := s.GetUser(createUserPaylod.Username)
+	existingUser, err := s.GetUser(ctx, createUserPaylod.Username)
 	if err != nil {
 		return nil, err
 	}
 	if existingUser != nil {
 		// user already exists, update existing record;
 		existingUser.FullName = createUserPaylod.FullName
-		return existingUser, s.DB.Save(existingUser).Error
+		return existingUser, s.DB.WithContext(ctx).Save(existingUser).Error
 	}
 	newUser := &models.User{
 		Username: createUserPaylod.Username,
 		FullName: createUserPaylod.FullName,
 	}
 	// add users into the pool to get more
-	return newUser, s.DB.Create(newUser).Error
+	return newUser, s.DB.WithContext(ctx).Create(newUser).Error
 }
 
-func (s *SqliteDBRepository) GetUser(username string) (*models.User, error) {
+func (s *SqliteDBRepository) GetUser(ctx context.Context, username string) (*models.User, error) {
 	// Get user by username
 	var user models.User
-	err := s.DB.Where("username =?", username).First(&user).Error
+	err := s.DB.WithContext(ctx).Where("username =?", username).First(&user).Error
 	if err == gorm.ErrRecordNotFound {
 		return nil, nil
 	}
 	return &user, nil
 }
 
-func (s *SqliteDBRepository) StoreRepositoryInfo(remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) (*models.Repository, error) {
+func (s *SqliteDBRepository) StoreRepositoryInfo(ctx context.Context, remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) (*models.Repository, error) {
 	//  logic to store repository info in the database
 
 	// check if this remote repository already exists in our database
-	existingRepo, err := s.GetRepositoryInfoByRemoteId(remoteRepoInfo.ID)
+	existingRepo, err := s.GetRepositoryInfoByRemoteId(ctx, remoteRepoInfo.ID)
 	if err != nil {
 		return nil, err
 	}
 	if existingRepo != nil {
-		// repository already exists, update existing record;
-		if existingRepo.RemoteUpdatedAt != remoteRepoInfo.UpdatedAt {
-			// but if only there has been an update
-			return existingRepo, nil
-		}
-		existingRepo.Name = remoteRepoInfo.Name
-		existingRepo.Description = remoteRepoInfo.Description
-		existingRepo.URL = remoteRepoInfo.URL
-		existingRepo.Language = remoteRepoInfo.Language
-		existingRepo.ForksCount = remoteRepoInfo.ForksCount
-		existingRepo.StarsCount = remoteRepoInfo.StarsCount
-		existingRepo.OpenIssues = remoteRepoInfo.OpenIssues
-		existingRepo.Watchers = remoteRepoInfo.Watchers
-		return existingRepo, s.DB.Save(existingRepo).Error
+		// repository already exists, update existing record with whatever changed
+		if err := s.updateRepositoryInfo(ctx, existingRepo, remoteRepoInfo, owner); err != nil {
+			return nil, err
+		}
+		return existingRepo, nil
 	}
 	newRepo := &models.Repository{
 		RemoteID:        remoteRepoInfo.ID,
@@ -84,19 +83,104 @@ func (s *SqliteDBRepository) StoreRepositoryInfo(remoteRepoInfo *dto.RepositoryI
 		Watchers:        remoteRepoInfo.Watchers,
 		RemoteCreatedAt: remoteRepoInfo.CreatedAt,
 		RemoteUpdatedAt: remoteRepoInfo.UpdatedAt,
+		DefaultBranch:   remoteRepoInfo.DefaultBranch,
 	}
-	err = s.DB.Create(newRepo).Error
+	err = s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(newRepo).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(repositorySnapshot(newRepo)).Error; err != nil {
+			return err
+		}
+		discovered := &models.RepositoryEvent{
+			RepositoryID: newRepo.ID, Type: models.RepositoryEventDiscovered,
+			Summary: fmt.Sprintf("discovered %s/%s", owner.Username, newRepo.Name),
+		}
+		if err := tx.Create(discovered).Error; err != nil {
+			return err
+		}
+		if err := s.indexRepository(tx, newRepo); err != nil {
+			return err
+		}
+		newRepo.Changes = []*models.RepositoryEvent{discovered}
+		payload, err := repositoryWebhookPayload(tx, models.WebhookEventRepositoryDiscovered, newRepo, owner)
+		if err != nil {
+			return err
+		}
+		return queueWebhooks(tx, payload)
+	})
 	if err != nil {
 		return nil, err
 	}
-
 	return newRepo, nil
 }
 
-func (s *SqliteDBRepository) GetRepositoryInfoByRemoteId(remoteID int) (*models.Repository, error) {
+// store what github now reports for a repository, logging an event for every change and a
+// snapshot when its metrics moved. nothing is written when nothing changed
+func (s *SqliteDBRepository) updateRepositoryInfo(ctx context.Context, repo *models.Repository, remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) error {
+	events := detectRepositoryChanges(repo, remoteRepoInfo)
+	if owner != nil && owner.ID != repo.OwnerID {
+		previousOwner := &models.User{}
+		if err := s.DB.WithContext(ctx).Unscoped().Where("id =?", repo.OwnerID).Limit(1).Find(previousOwner).Error; err != nil {
+			return err
+		}
+		events = append(events, &models.RepositoryEvent{
+			RepositoryID: repo.ID, Type: models.RepositoryEventTransferred, Field: "owner",
+			OldValue: previousOwner.Username, NewValue: owner.Username,
+			Summary: fmt.Sprintf("transferred from %s to %s", previousOwner.Username, owner.Username),
+		})
+		repo.OwnerID = owner.ID
+		repo.Owner = owner
+	}
+	if len(events) == 0 && repo.RemoteUpdatedAt == remoteRepoInfo.UpdatedAt && repo.URL == remoteRepoInfo.HtmlUrl {
+		return nil
+	}
+	repo.Name = remoteRepoInfo.Name
+	repo.Description = remoteRepoInfo.Description
+	repo.URL = remoteRepoInfo.HtmlUrl
+	repo.Language = remoteRepoInfo.Language
+	repo.ForksCount = remoteRepoInfo.ForksCount
+	repo.StarsCount = remoteRepoInfo.StarsCount
+	repo.OpenIssues = remoteRepoInfo.OpenIssues
+	repo.Watchers = remoteRepoInfo.Watchers
+	repo.DefaultBranch = remoteRepoInfo.DefaultBranch
+	repo.RemoteUpdatedAt = remoteRepoInfo.UpdatedAt
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Owner").Save(repo).Error; err != nil {
+			return err
+		}
+		if err := s.indexRepository(tx, repo); err != nil {
+			return err
+		}
+		if metricsChanged(events) {
+			if err := tx.Create(repositorySnapshot(repo)).Error; err != nil {
+				return err
+			}
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		if err := tx.Create(&events).Error; err != nil {
+			return err
+		}
+		payload, err := repositoryWebhookPayload(tx, models.WebhookEventRepositoryUpdated, repo, owner)
+		if err != nil {
+			return err
+		}
+		payload.Changes = events
+		return queueWebhooks(tx, payload)
+	})
+	if err != nil {
+		return err
+	}
+	repo.Changes = events
+	return nil
+}
+
+func (s *SqliteDBRepository) GetRepositoryInfoByRemoteId(ctx context.Context, remoteID int) (*models.Repository, error) {
 	//  logic to retrieve repository info from the database by remote ID
 	repo := &models.Repository{}
-	err := s.DB.Where("remote_id =?", remoteID).First(repo).Error
+	err := s.DB.WithContext(ctx).Where("remote_id =?", remoteID).Preload("Owner").First(repo).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -106,10 +190,15 @@ func (s *SqliteDBRepository) GetRepositoryInfoByRemoteId(remoteID int) (*models.
 	return repo, nil
 }
 
-func (s *SqliteDBRepository) GetRepository(ownerID uint, repoName string) (*models.Repository, error) {
+// a repository deleted on github; its commits stay stored but are no longer listed
+func (s *SqliteDBRepository) DeleteRepository(ctx context.Context, repo *models.Repository) error {
+	return s.DB.WithContext(ctx).Delete(repo).Error
+}
+
+func (s *SqliteDBRepository) GetRepository(ctx context.Context, ownerID uint, repoName string) (*models.Repository, error) {
 	//  logic to retrieve repository info from the database by ID
 	repo := &models.Repository{}
-	err := s.DB.Where("owner_id =?", ownerID).Where("name =?", repoName).Preload("Owner").First(repo).Error
+	err := s.DB.WithContext(ctx).Where("owner_id =?", ownerID).Where("name =?", repoName).Preload("Owner").First(repo).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -119,79 +208,196 @@ func (s *SqliteDBRepository) GetRepository(ownerID uint, repoName string) (*mode
 	return repo, nil
 }
 
-func (s *SqliteDBRepository) SearchRepository(ownerID uint, repoSearchParams *utils.RepositorySearchParams) ([]*models.Repository, error) {
-	//  logic to retrieve all repositories from the database
-	repos := &[]*models.Repository{}
-	dbQueryBuilder := s.DB.Preload("Owner").Where("owner_id =?", ownerID)
-	if repoSearchParams.TopStarsCount > 0 {
-		dbQueryBuilder = dbQueryBuilder.Order("stars_count DESC").Limit(repoSearchParams.TopStarsCount)
+func (s *SqliteDBRepository) GetRepositoryByID(ctx context.Context, id uint) (*models.Repository, error) {
+	repo := &models.Repository{}
+	err := s.DB.WithContext(ctx).Preload("Owner").First(repo, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
 	}
+	return repo, nil
+}
+
+// the author date github reports for a commit, in UTC so sqlite compares the stored text correctly;
+// the zero time when it doesn't parse
+func commitDate(value string) time.Time {
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if value != "" {
+			log.Printf("Ignoring invalid commit date %q", value)
+		}
+		return time.Time{}
+	}
+	return date.UTC()
+}
+
+var repositorySortFields = map[string]sortField[*models.Repository]{
+	"stars": {column: "repositories.stars_count", parse: parseIntCursor, desc: true, value: func(r *models.Repository) string { return strconv.Itoa(r.StarsCount) }},
+	"forks": {column: "repositories.forks_count", parse: parseIntCursor, desc: true, value: func(r *models.Repository) string { return strconv.Itoa(r.ForksCount) }},
+	"name":  {column: "repositories.name", value: func(r *models.Repository) string { return r.Name }},
+	// github's timestamps are RFC3339 in UTC, so they sort as text
+	"updated": {column: "repositories.remote_updated_at", desc: true, value: func(r *models.Repository) string { return r.RemoteUpdatedAt }},
+	"created": {column: "repositories.remote_created_at", desc: true, value: func(r *models.Repository) string { return r.RemoteCreatedAt }},
+}
+
+func repositoryID(r *models.Repository) uint { return r.ID }
+
+func (s *SqliteDBRepository) SearchRepository(ctx context.Context, ownerID uint, repoSearchParams *utils.RepositorySearchParams, list *utils.ListParams) ([]*models.Repository, *utils.PageInfo, error) {
+	//  logic to retrieve a page of a user's repositories from the database
+	return s.searchRepository(ctx, ownerID, repoSearchParams, list, "LIKE")
+}
+
+// shared by the sqlite and postgres implementations, which differ in the operator that matches names case-insensitively
+func (s *SqliteDBRepository) searchRepository(ctx context.Context, ownerID uint, repoSearchParams *utils.RepositorySearchParams, list *utils.ListParams, likeOperator string) ([]*models.Repository, *utils.PageInfo, error) {
+	dbQueryBuilder := s.DB.WithContext(ctx).Model(&models.Repository{}).Preload("Owner").Where("owner_id =?", ownerID)
 	if repoSearchParams.Name != "" {
-		dbQueryBuilder = dbQueryBuilder.Where("name LIKE?", "%"+repoSearchParams.Name+"%")
+		dbQueryBuilder = dbQueryBuilder.Where("name "+likeOperator+" ?", "%"+repoSearchParams.Name+"%")
 	}
 	if repoSearchParams.Language != "" {
 		dbQueryBuilder = dbQueryBuilder.Where("language =?", repoSearchParams.Language)
 	}
-
-	err := dbQueryBuilder.Find(&repos).Error
-	if err != nil {
-		return nil, err
+	if repoSearchParams.TopStarsCount > 0 {
+		// shorthand for the first page of the most starred repositories
+		list = &utils.ListParams{Page: 1, PerPage: repoSearchParams.TopStarsCount, Sort: "stars", Order: "desc"}
 	}
-	return *repos, nil
+	return paginate(dbQueryBuilder, list, "name", repositorySortFields, "repositories.id", repositoryID)
 }
 
-func (s *SqliteDBRepository) GetAllRepositories() ([]*models.Repository, error) {
+func (s *SqliteDBRepository) GetAllRepositories(ctx context.Context) ([]*models.Repository, error) {
 	//  logic to retrieve all repositories from the database
 	repos := &[]*models.Repository{}
-	err := s.DB.Preload("Owner").Find(&repos).Error
+	err := s.DB.WithContext(ctx).Preload("Owner").Find(&repos).Error
 	if err != nil {
 		return nil, err
 	}
 	return *repos, nil
 }
 
-func (s *SqliteDBRepository) StoreRepositoryCommits(commitRepoInfos *[]dto.CommitResponseDTO, repoName string, owner *models.User) error {
-	//  logic to store commit info in the database
-	repo, err := s.GetRepository(owner.ID, repoName)
-
+// repositories whose next update check is due, the ones never scheduled first
+func (s *SqliteDBRepository) GetRepositoriesDueForRefresh(ctx context.Context, now time.Time, limit int) ([]*models.Repository, error) {
+	repos := []*models.Repository{}
+	err := s.DB.WithContext(ctx).Preload("Owner").
+		Where("next_refresh_at IS NULL OR next_refresh_at <= ?", now.UTC()).
+		Order("next_refresh_at IS NOT NULL").Order("next_refresh_at").Order("id").
+		Limit(limit).
+		Find(&repos).Error
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if repo == nil {
-		return fmt.Errorf("repository not found for owner %v and repo %v", owner.Username, repoName)
+	return repos, nil
+}
+
+// move the next update check of a repository; like job leases this only succeeds if the
+// check is still where repo says it is, so schedulers on several replicas don't both queue it
+func (s *SqliteDBRepository) RescheduleRepositoryRefresh(ctx context.Context, repo *models.Repository, nextRefreshAt time.Time) (bool, error) {
+	// stored in utc at microsecond precision so the value read back compares equal in either database
+	nextRefreshAt = nextRefreshAt.UTC().Truncate(time.Microsecond)
+	dbQueryBuilder := s.DB.WithContext(ctx).Model(&models.Repository{}).Where("id =?", repo.ID)
+	if repo.NextRefreshAt == nil {
+		dbQueryBuilder = dbQueryBuilder.Where("next_refresh_at IS NULL")
+	} else {
+		dbQueryBuilder = dbQueryBuilder.Where("next_refresh_at =?", repo.NextRefreshAt.UTC())
+	}
+	result := dbQueryBuilder.Update("next_refresh_at", nextRefreshAt)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	repo.NextRefreshAt = &nextRefreshAt
+	return true, nil
+}
+
+// store the commits that aren't stored yet, returning the ones that were new
+func (s *SqliteDBRepository) StoreRepositoryCommits(ctx context.Context, commitRepoInfos *[]dto.CommitResponseDTO, repo *models.Repository) ([]*models.Commit, error) {
+	//  logic to store commit info in the database
+	if repo == nil || repo.ID == 0 {
+		return nil, fmt.Errorf("cannot store commits for a repository that has not been saved")
 	}
+	// look up which of these commits we already have in a single query
+	shas := make([]string, 0, len(*commitRepoInfos))
 	for _, commit := range *commitRepoInfos {
-		// check if this commit already exists in our database
-		existingCommit, err := s.GetCommitBySHA(commit.SHA)
+		shas = append(shas, commit.SHA)
+	}
+	existingSHAs := map[string]bool{}
+	if len(shas) > 0 {
+		var storedSHAs []string
+		err := s.DB.WithContext(ctx).Model(&models.Commit{}).Where("repository_id =?", repo.ID).Where("sha IN ?", shas).Pluck("sha", &storedSHAs).Error
 		if err != nil {
-			log.Println("Error in checking existing commits by sha")
-			continue
-		}
-		if existingCommit != nil {
-			// commit already exists, skip;
-			log.Printf("Commit with SHA: %s already exists; skipping", existingCommit.SHA)
-			continue
-		}
-		newCommit := &models.Commit{
-			RepositoryName: repoName,
-			SHA:            commit.SHA,
-			Message:        commit.Message,
-			Author:         commit.Author,
-			Date:           commit.Date,
-		}
-		log.Printf("New commit to be created: %v", newCommit)
-		err = s.DB.Create(newCommit).Error
+			return nil, err
+		}
+		for _, sha := range storedSHAs {
+			existingSHAs[sha] = true
+		}
+	}
+
+	newCommits := []*models.Commit{}
+	// the commits and the webhooks announcing them are stored together
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, commit := range *commitRepoInfos {
+			if existingSHAs[commit.SHA] {
+				// commit already exists, skip;
+				log.Printf("Commit with SHA: %s already exists; skipping", commit.SHA)
+				continue
+			}
+			newCommit := &models.Commit{
+				RepositoryID: repo.ID,
+				SHA:          commit.SHA,
+				Message:      commit.Message,
+				Author:       commit.Author,
+				Date:         commitDate(commit.Date),
+				URL:          commit.URL,
+			}
+			log.Printf("New commit to be created: %v", newCommit)
+			// another worker may have stored the same commit in the meantime; the unique index settles it
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(newCommit)
+			if result.Error != nil {
+				log.Printf("Error in saving commits with SHA: %s", newCommit.SHA)
+				return result.Error
+			}
+			existingSHAs[commit.SHA] = true
+			if result.RowsAffected > 0 {
+				newCommits = append(newCommits, newCommit)
+			}
+		}
+		if len(newCommits) == 0 {
+			return nil
+		}
+		if err := s.indexCommits(tx, newCommits); err != nil {
+			return err
+		}
+		payload, err := repositoryWebhookPayload(tx, models.WebhookEventCommitCreated, repo, repo.Owner)
 		if err != nil {
-			log.Printf("Error in saving commits with SHA: %s", newCommit.SHA)
 			return err
 		}
+		payload.Commits = newCommits
+		return queueWebhooks(tx, payload)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newCommits, nil
+}
+
+// remember the newest stored commit so the next sync only asks github for what came after it;
+// the date only ever moves forward
+func (s *SqliteDBRepository) UpdateRepositoryLatestCommitAt(ctx context.Context, repo *models.Repository, latestCommitAt time.Time) error {
+	err := s.DB.WithContext(ctx).Model(&models.Repository{}).
+		Where("id =?", repo.ID).
+		Where("latest_commit_at IS NULL OR latest_commit_at < ?", latestCommitAt).
+		Update("latest_commit_at", latestCommitAt).Error
+	if err != nil {
+		return err
+	}
+	if repo.LatestCommitAt == nil || latestCommitAt.After(*repo.LatestCommitAt) {
+		repo.LatestCommitAt = &latestCommitAt
 	}
 	return nil
 }
 
-func (s *SqliteDBRepository) GetCommitBySHA(sha string) (*models.Commit, error) {
+func (s *SqliteDBRepository) GetCommitBySHA(ctx context.Context, repoID uint, sha string) (*models.Commit, error) {
 	commit := &models.Commit{}
-	err := s.DB.Where("sha =?", sha).First(commit).Error
+	err := s.DB.WithContext(ctx).Where("repository_id =?", repoID).Where("sha =?", sha).First(commit).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return nil, nil
@@ -201,13 +407,129 @@ func (s *SqliteDBRepository) GetCommitBySHA(sha string) (*models.Commit, error)
 	return commit, nil
 }
 
-func (s *SqliteDBRepository) GetRepositoryCommits(repoName string) ([]*models.Commit, error) {
-	//  logic to retrieve commit info from the database by repository name
-	commits := &[]*models.Commit{}
-	err := s.DB.Where("repository_name =?", repoName).Find(commits).Error
+var commitSortFields = map[string]sortField[*models.Commit]{
+	"date": {column: "commits.date", parse: parseTimeCursor, desc: true, value: func(c *models.Commit) string { return c.Date.UTC().Format(time.RFC3339Nano) }},
+}
+
+func commitID(c *models.Commit) uint { return c.ID }
+
+func (s *SqliteDBRepository) GetRepositoryCommits(ctx context.Context, owner, repoName string, filters *utils.CommitFilters, list *utils.ListParams) ([]*models.Commit, *utils.PageInfo, error) {
+	//  logic to retrieve a page of commits from the database by owner and repository name
+	return s.getRepositoryCommits(ctx, owner, repoName, filters, list, "LIKE")
+}
+
+// shared by the sqlite and postgres implementations, which differ in the operator that matches messages case-insensitively
+func (s *SqliteDBRepository) getRepositoryCommits(ctx context.Context, owner, repoName string, filters *utils.CommitFilters, list *utils.ListParams, likeOperator string) ([]*models.Commit, *utils.PageInfo, error) {
+	query := s.repositoryCommitsQuery(ctx, owner, repoName)
+	if filters != nil {
+		if filters.Author != "" {
+			query = query.Where("LOWER(commits.author) = LOWER(?)", filters.Author)
+		}
+		if filters.Message != "" {
+			query = query.Where("commits.message "+likeOperator+" ?", "%"+filters.Message+"%")
+		}
+		if filters.SHAPrefix != "" {
+			query = query.Where("commits.sha LIKE ?", filters.SHAPrefix+"%")
+		}
+		if filters.Since != nil {
+			query = query.Where("commits.date >= ?", filters.Since.UTC())
+		}
+		if filters.Until != nil {
+			query = query.Where("commits.date <= ?", filters.Until.UTC())
+		}
+	}
+	commits, page, err := paginate(query, list, "date", commitSortFields, "commits.id", commitID)
 	if err != nil {
 		log.Printf("%v", err)
+		return nil, nil, err
+	}
+	return commits, page, nil
+}
+
+func (s *SqliteDBRepository) GetRepositoryCommit(ctx context.Context, owner, repoName, sha string) (*models.Commit, error) {
+	commit := &models.Commit{}
+	err := s.repositoryCommitsQuery(ctx, owner, repoName).Where("commits.sha =?", strings.ToLower(sha)).First(commit).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return commit, nil
+}
+
+// the commits of a repository, looked up by its owner's username and its name
+func (s *SqliteDBRepository) repositoryCommitsQuery(ctx context.Context, owner, repoName string) *gorm.DB {
+	return s.DB.WithContext(ctx).Model(&models.Commit{}).
+		Joins("JOIN repositories ON repositories.id = commits.repository_id AND repositories.deleted_at IS NULL").
+		Joins("JOIN users ON users.id = repositories.owner_id AND users.deleted_at IS NULL").
+		Where("users.username =?", owner).
+		Where("repositories.name =?", repoName)
+}
+
+func (s *SqliteDBRepository) GetHTTPCacheEntry(ctx context.Context, url string) (*models.HTTPCacheEntry, error) {
+	entry := &models.HTTPCacheEntry{}
+	err := s.DB.WithContext(ctx).Where("url =?", url).First(entry).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *SqliteDBRepository) StoreHTTPCacheEntry(ctx context.Context, url, etag, lastModified string) error {
+	existingEntry, err := s.GetHTTPCacheEntry(ctx, url)
+	if err != nil {
+		return err
+	}
+	if existingEntry != nil {
+		existingEntry.ETag = etag
+		existingEntry.LastModified = lastModified
+		return s.DB.WithContext(ctx).Save(existingEntry).Error
+	}
+	return s.DB.WithContext(ctx).Create(&models.HTTPCacheEntry{URL: url, ETag: etag, LastModified: lastModified}).Error
+}
+
+func (s *SqliteDBRepository) SetRepositorySyncSince(ctx context.Context, repo *models.Repository, since *time.Time) error {
+	repo.SyncCommitsSince = since
+	return s.DB.WithContext(ctx).Model(repo).Update("sync_commits_since", since).Error
+}
+
+func (s *SqliteDBRepository) CreateCommitBackfill(ctx context.Context, repo *models.Repository, since *time.Time) (*models.CommitBackfill, error) {
+	// a repository has a single backfill record; starting a new backfill resets it
+	backfill := &models.CommitBackfill{}
+	err := s.DB.WithContext(ctx).Where("repository_id =?", repo.ID).First(backfill).Error
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	backfill.RepositoryID = repo.ID
+	backfill.Since = since
+	backfill.NextPageURL = ""
+	backfill.Status = models.BackfillStatusPending
+	backfill.PagesFetched = 0
+	backfill.CommitsFetched = 0
+	backfill.LastError = ""
+	if err := s.DB.WithContext(ctx).Save(backfill).Error; err != nil {
+		return nil, err
+	}
+	backfill.Repository = repo
+	return backfill, nil
+}
+
+func (s *SqliteDBRepository) UpdateCommitBackfill(ctx context.Context, backfill *models.CommitBackfill) error {
+	return s.DB.WithContext(ctx).Omit("Repository").Save(backfill).Error
+}
+
+func (s *SqliteDBRepository) GetCommitBackfill(ctx context.Context, id uint) (*models.CommitBackfill, error) {
+	backfill := &models.CommitBackfill{}
+	err := s.DB.WithContext(ctx).Preload("Repository.Owner").First(backfill, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
 		return nil, err
 	}
-	return *commits, nil
+	return backfill, nil
 }
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"gorm.io/gorm"
+)
+
+// payload of the deliver-webhook job queued with every delivery, read back as a tasks.WebhookDeliveryRequest
+type webhookJobPayload struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+func (s *SqliteDBRepository) CreateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return s.DB.WithContext(ctx).Create(subscription).Error
+}
+
+func (s *SqliteDBRepository) GetWebhookSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	subscriptions := []*models.WebhookSubscription{}
+	if err := s.DB.WithContext(ctx).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func (s *SqliteDBRepository) GetWebhookSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	subscription := &models.WebhookSubscription{}
+	err := s.DB.WithContext(ctx).First(subscription, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// deliveries still queued for the subscription fail once their job finds it gone
+func (s *SqliteDBRepository) DeleteWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	return s.DB.WithContext(ctx).Delete(subscription).Error
+}
+
+// deliveries are queued in the order the events happen, so their ids order them by time
+var webhookDeliverySortFields = map[string]sortField[*models.WebhookDelivery]{
+	"created": {column: "webhook_deliveries.id", parse: parseIntCursor, desc: true, value: func(d *models.WebhookDelivery) string { return strconv.FormatUint(uint64(d.ID), 10) }},
+}
+
+func webhookDeliveryID(d *models.WebhookDelivery) uint { return d.ID }
+
+// a page of a subscription's deliveries, newest first unless ordered otherwise; an empty state
+// lists deliveries in every state
+func (s *SqliteDBRepository) GetWebhookDeliveries(ctx context.Context, subscription *models.WebhookSubscription, state string, list *utils.ListParams) ([]*models.WebhookDelivery, *utils.PageInfo, error) {
+	query := s.DB.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("subscription_id =?", subscription.ID)
+	if state != "" {
+		query = query.Where("state =?", state)
+	}
+	return paginate(query, list, "created", webhookDeliverySortFields, "webhook_deliveries.id", webhookDeliveryID)
+}
+
+// a delivery along with its subscription, which is nil once the subscription is deleted
+func (s *SqliteDBRepository) GetWebhookDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := s.DB.WithContext(ctx).Preload("Subscription").First(delivery, id).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (s *SqliteDBRepository) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.DB.WithContext(ctx).Omit("Subscription").Save(delivery).Error
+}
+
+// send a delivery's payload again as a new delivery, leaving the original as it was
+func (s *SqliteDBRepository) RedeliverWebhook(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	redelivery := &models.WebhookDelivery{
+		SubscriptionID: delivery.SubscriptionID,
+		Event:          delivery.Event,
+		Payload:        delivery.Payload,
+		RedeliveryOf:   &delivery.ID,
+	}
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return queueWebhookDelivery(tx, redelivery)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return redelivery, nil
+}
+
+// the payload of an event about a repository; owner is looked up when it isn't at hand
+func repositoryWebhookPayload(tx *gorm.DB, event string, repo *models.Repository, owner *models.User) (*dto.WebhookEventPayloadDTO, error) {
+	if owner == nil || owner.ID != repo.OwnerID {
+		owner = &models.User{}
+		if err := tx.Unscoped().Where("id =?", repo.OwnerID).Limit(1).Find(owner).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &dto.WebhookEventPayloadDTO{Event: event, Owner: owner.Username, RepositoryName: repo.Name, Repository: repo}, nil
+}
+
+// queue a delivery of the event to every subscription to it. this runs in the transaction that
+// stores what the event is about, so an event is only sent for changes that were kept and can't
+// be lost between storing the change and queueing its deliveries
+func queueWebhooks(tx *gorm.DB, payload *dto.WebhookEventPayloadDTO) error {
+	subscriptions := []*models.WebhookSubscription{}
+	if err := tx.Find(&subscriptions).Error; err != nil {
+		return err
+	}
+	var data []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Subscribes(payload.Event) {
+			continue
+		}
+		if data == nil {
+			var err error
+			if data, err = json.Marshal(payload); err != nil {
+				return err
+			}
+		}
+		delivery := &models.WebhookDelivery{SubscriptionID: subscription.ID, Event: payload.Event, Payload: string(data)}
+		if err := queueWebhookDelivery(tx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queueWebhookDelivery(tx *gorm.DB, delivery *models.WebhookDelivery) error {
+	delivery.State = models.WebhookDeliveryPending
+	if err := tx.Create(delivery).Error; err != nil {
+		return err
+	}
+	jobPayload, err := json.Marshal(&webhookJobPayload{DeliveryID: delivery.ID})
+	if err != nil {
+		return err
+	}
+	job := &models.Job{Type: models.JobTypeDeliverWebhook, Payload: string(jobPayload), State: models.JobStatePending, RunAt: time.Now()}
+	if err := tx.Create(job).Error; err != nil {
+		return err
+	}
+	delivery.JobID = job.ID
+	return tx.Model(delivery).Update("job_id", job.ID).Error
+}
@@ -0,0 +1,24 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/midedickson/github-service/models"
+)
+
+// the data of the repository and commit events on the event stream: the repository, plus the
+// commits that were stored for commit.created and what changed for repository.updated
+type RepositoryActivityDTO struct {
+	RepositoryName string                    `json:"repository_name"`
+	Repository     *models.Repository        `json:"repository"`
+	Commits        []*models.Commit          `json:"commits,omitempty"`
+	Changes        []*models.RepositoryEvent `json:"changes,omitempty"`
+}
+
+// the data of a job.failed event; a job that is retried says when, one that isn't says why
+type JobFailureDTO struct {
+	Job              *models.Job `json:"job"`
+	Error            string      `json:"error"`
+	RetryAt          *time.Time  `json:"retry_at,omitempty"`
+	DeadLetterReason string      `json:"dead_letter_reason,omitempty"`
+}
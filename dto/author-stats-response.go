@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+type AuthorStatsResponseDTO struct {
+	Author  string `json:"author"`
+	Commits int64  `json:"commits"`
+	// number of repositories the author committed to
+	Repositories  int64     `json:"repositories"`
+	FirstCommitAt time.Time `json:"first_commit_at"`
+	LastCommitAt  time.Time `json:"last_commit_at"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type CreateWebhookPayloadDTO struct {
+	URL string `json:"url"`
+	// signs every payload sent to the url, see the X-Hub-Signature-256 header
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+import "encoding/json"
+
+// the parts of github's webhook payloads the hook receiver reads. the repository is decoded
+// separately for each event, as push events give its timestamps as unix times where the
+// others give them as strings
+type GithubHookPayloadDTO struct {
+	Action string `json:"action"`
+	// sent with ping events
+	Zen string `json:"zen"`
+	// the ref pushed to, or the branch or tag created or deleted
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+	// a push that deleted the ref
+	Deleted    bool                  `json:"deleted"`
+	Commits    []GithubPushCommitDTO `json:"commits"`
+	Repository json.RawMessage       `json:"repository"`
+}
+
+// the fields of a hook's repository that read the same in every event
+type GithubHookRepositoryDTO struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	DefaultBranch string `json:"default_branch"`
+	Owner         struct {
+		Login string `json:"login"`
+	} `json:"owner"`
+}
+
+// a commit as push events list them
+type GithubPushCommitDTO struct {
+	ID        string `json:"id"`
+	Message   string `json:"message"`
+	Timestamp string `json:"timestamp"`
+	URL       string `json:"url"`
+	Author    struct {
+		Name string `json:"name"`
+	} `json:"author"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import "time"
+
+// the value of a metric at the end of the interval starting at Time
+type MetricPointDTO struct {
+	Time  time.Time `json:"time"`
+	Value int       `json:"value"`
+}
+
+// intervals before the first recorded value of the metric have no point
+type MetricHistoryResponseDTO struct {
+	Metric   string            `json:"metric"`
+	Interval string            `json:"interval"`
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"`
+	Points   []*MetricPointDTO `json:"points"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+type QueueStatsResponseDTO struct {
+	Type    string `json:"type"`
+	Workers int    `json:"workers"`
+	Pending int64  `json:"pending"`
+	Running int64  `json:"running"`
+	// maximum number of pending jobs; 0 means no limit
+	Limit int64 `json:"limit"`
+	// jobs turned away because the queue was full, since this instance started
+	Rejected int64 `json:"rejected"`
+}
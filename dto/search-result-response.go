@@ -0,0 +1,16 @@
+package dto
+
+import "github.com/midedickson/github-service/models"
+
+type SearchResultResponseDTO struct {
+	// "commit" or "repository"
+	Type           string `json:"type"`
+	Owner          string `json:"owner"`
+	RepositoryName string `json:"repository_name"`
+	// higher ranks match better; only comparable within one response
+	Rank float64 `json:"rank"`
+	// the matching text with the matched terms wrapped in <mark></mark>
+	Snippet    string             `json:"snippet"`
+	Commit     *models.Commit     `json:"commit,omitempty"`
+	Repository *models.Repository `json:"repository,omitempty"`
+}
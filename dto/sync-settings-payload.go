@@ -0,0 +1,11 @@
+package dto
+
+type SyncSettingsPayloadDTO struct {
+	// date commit syncs start from (YYYY-MM-DD or RFC3339); null falls back to the global setting
+	SyncCommitsSince *string `json:"syncCommitsSince"`
+}
+
+type BackfillPayloadDTO struct {
+	// optional date to stop the backfill at; defaults to the repository's sync start date
+	Since *string `json:"since"`
+}
@@ -0,0 +1,12 @@
+package dto
+
+import "github.com/midedickson/github-service/models"
+
+type SyncStatusResponseDTO struct {
+	Owner string `json:"owner"`
+	// true while any of the owner's jobs are pending or running
+	Syncing bool `json:"syncing"`
+	// number of the owner's jobs in each state
+	JobCounts  map[string]int64 `json:"jobCounts"`
+	RecentJobs []*models.Job    `json:"recentJobs"`
+}
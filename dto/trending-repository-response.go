@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/midedickson/github-service/models"
+
+// how much a repository's metric grew over a window: Previous is its value when the window
+// started, or when the repository was first stored if that was later
+type TrendingRepositoryResponseDTO struct {
+	Owner          string             `json:"owner"`
+	RepositoryName string             `json:"repository_name"`
+	Metric         string             `json:"metric"`
+	Previous       int                `json:"previous"`
+	Current        int                `json:"current"`
+	Growth         int                `json:"growth"`
+	Repository     *models.Repository `json:"repository"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "github.com/midedickson/github-service/models"
+
+// the body sent to webhook subscriptions: the repository the event is about, plus the commits
+// that were stored for commit.created and what changed for repository.updated
+type WebhookEventPayloadDTO struct {
+	Event          string                    `json:"event"`
+	Owner          string                    `json:"owner"`
+	RepositoryName string                    `json:"repository_name"`
+	Repository     *models.Repository        `json:"repository"`
+	Commits        []*models.Commit          `json:"commits,omitempty"`
+	Changes        []*models.RepositoryEvent `json:"changes,omitempty"`
+}
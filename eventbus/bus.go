@@ -0,0 +1,136 @@
+package eventbus
+
+import (
+	"sync"
+	"time"
+)
+
+// the activity the task workers publish, named like the webhook events where they match
+const (
+	EventRepositoryDiscovered = "repository.discovered"
+	EventRepositoryUpdated    = "repository.updated"
+	EventCommitCreated        = "commit.created"
+	EventJobFailed            = "job.failed"
+)
+
+// something that happened in this process. ids only increase, but restart from 1 with the process
+type Event struct {
+	ID    uint64      `json:"id"`
+	Type  string      `json:"type"`
+	Owner string      `json:"owner"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data"`
+}
+
+// fans events out to subscribers in this process. publishing never blocks: each subscriber has
+// a buffer of its own, and one that lets it fill up is dropped rather than holding up the
+// workers or the other subscribers
+type Bus struct {
+	bufferSize int
+
+	mu          sync.Mutex
+	lastID      uint64
+	subscribers map[*Subscription]struct{}
+	closed      bool
+}
+
+type Subscription struct {
+	// only events about this owner are delivered; all of them when empty
+	owner  string
+	events chan Event
+	// set when the subscription was dropped for falling behind; guarded by the bus
+	evicted bool
+}
+
+func New(bufferSize int) *Bus {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &Bus{bufferSize: bufferSize, subscribers: map[*Subscription]struct{}{}}
+}
+
+// hand the event to every subscriber interested in its owner. a nil bus drops it, so
+// publishers don't need one to work
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event.ID = b.lastID
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	for sub := range b.subscribers {
+		if sub.owner != "" && sub.owner != event.Owner {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			// the subscriber stopped keeping up; it misses this and every later event
+			sub.evicted = true
+			b.remove(sub)
+		}
+	}
+}
+
+// start receiving the events about owner, or all events when owner is empty. the subscription's
+// channel is closed once it is unsubscribed, evicted or the bus is closed
+func (b *Bus) Subscribe(owner string) *Subscription {
+	sub := &Subscription{owner: owner, events: make(chan Event, b.bufferSize)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.events)
+		return sub
+	}
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// end every subscription, e.g. so open streams don't hold up shutdown; later subscriptions
+// end straight away
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// the number of current subscriptions
+func (b *Bus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers)
+}
+
+// must be called with b.mu held; removing a subscription twice is a no-op
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
+
+// the subscription's events; closed when it ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// whether the subscription ended because its buffer filled up. only meaningful once Events is closed
+func (s *Subscription) Evicted() bool {
+	// the channel is closed under the bus's lock after evicted is set, so receiving the close
+	// makes the write visible here
+	return s.evicted
+}
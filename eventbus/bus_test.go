@@ -0,0 +1,86 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the events left in a subscription's buffer, and whether it has ended
+func drain(sub *Subscription) ([]Event, bool) {
+	events := []Event{}
+	for {
+		select {
+		case event, ok := <-sub.Events():
+			if !ok {
+				return events, true
+			}
+			events = append(events, event)
+		default:
+			return events, false
+		}
+	}
+}
+
+func TestPublishFiltersByOwner(t *testing.T) {
+	bus := New(10)
+	alice := bus.Subscribe("alice")
+	everyone := bus.Subscribe("")
+
+	bus.Publish(Event{Type: EventCommitCreated, Owner: "alice"})
+	bus.Publish(Event{Type: EventJobFailed, Owner: "bob"})
+
+	events, ended := drain(alice)
+	assert.False(t, ended)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventCommitCreated, events[0].Type)
+		assert.EqualValues(t, 1, events[0].ID)
+		assert.False(t, events[0].Time.IsZero())
+	}
+	events, _ = drain(everyone)
+	if assert.Len(t, events, 2) {
+		assert.EqualValues(t, 2, events[1].ID)
+	}
+}
+
+func TestPublishEvictsSlowSubscribers(t *testing.T) {
+	bus := New(2)
+	slow := bus.Subscribe("")
+	fast := bus.Subscribe("")
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(Event{Type: EventCommitCreated, Owner: "alice"})
+		drain(fast)
+	}
+
+	// the slow subscriber keeps what it had buffered, then ends
+	events, ended := drain(slow)
+	assert.Len(t, events, 2)
+	assert.True(t, ended)
+	assert.True(t, slow.Evicted())
+	assert.False(t, fast.Evicted())
+	assert.Equal(t, 1, bus.Subscribers())
+
+	// unsubscribing an evicted subscription is harmless
+	bus.Unsubscribe(slow)
+	bus.Unsubscribe(fast)
+	_, ended = drain(fast)
+	assert.True(t, ended)
+	assert.False(t, fast.Evicted())
+}
+
+func TestCloseEndsSubscriptions(t *testing.T) {
+	bus := New(1)
+	sub := bus.Subscribe("alice")
+	bus.Close()
+
+	_, ended := drain(sub)
+	assert.True(t, ended)
+	_, ended = drain(bus.Subscribe("alice"))
+	assert.True(t, ended)
+	assert.Equal(t, 0, bus.Subscribers())
+
+	// a nil bus drops what is published to it
+	var nilBus *Bus
+	nilBus.Publish(Event{Type: EventJobFailed})
+}
@@ -8,46 +8,63 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
-	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/midedickson/github-service/config"
 	"github.com/midedickson/github-service/controllers"
 	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/eventbus"
 	"github.com/midedickson/github-service/requester"
 	"github.com/midedickson/github-service/routes"
 	"github.com/midedickson/github-service/tasks"
 )
 
 func main() {
-	log.Println("Starting server...")
+	cfg := config.Load()
+	database.ConnectToDB(cfg.DatabaseURL)
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrateCommand(os.Args[2:])
+		return
+	}
 
-	database.ConnectToDB()
-	database.AutoMigrate()
+	log.Println("Starting server...")
+	if cfg.AutoMigrate {
+		err := database.MigrateUp(database.DB)
+		if err != nil {
+			log.Fatalf("Could not migrate database: %v", err)
+		}
+	} else if err := database.CheckSchemaVersion(database.DB); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+	if !database.SearchRanked(database.DB) {
+		log.Println("Search results are unranked: sqlite ranks them through fts5, which needs the driver built with -tags sqlite_fts5 (make build)")
+	}
 	// Use a WaitGroup to manage goroutines
 	var wg sync.WaitGroup
 
-	repoRequester := requester.NewRepositoryRequester()
-	dbRepository := database.NewSqliteDBRepository(database.DB)
-	tasks := tasks.NewAsyncTask(repoRequester, dbRepository)
-	controller := controllers.NewController(repoRequester, dbRepository, tasks)
+	dbRepository := database.NewDBRepository(database.DB)
+	repoRequester := requester.NewRepositoryRequester(cfg, dbRepository)
+	eventBus := eventbus.New(cfg.EventStreamBuffer)
+	tasks := tasks.NewAsyncTask(repoRequester, dbRepository, cfg, eventBus)
+	controller := controllers.NewController(repoRequester, dbRepository, tasks, cfg.GithubWebhookSecret, eventBus)
 
-	// Start goroutines to fetch repositories and check for updates
-	wg.Add(1)
-	go tasks.GetAllRepoForUser(&wg)
-	wg.Add(1)
-	go tasks.FetchNewlyRequestedRepo(&wg)
+	// cancelled on SIGINT/SIGTERM, which stops the workers claiming new jobs
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Start the job queue workers and the scheduler of recurring jobs
+	tasks.StartWorkers(ctx, &wg)
 	wg.Add(1)
-	go tasks.CheckForUpdateOnAllRepo(&wg)
-	go tasks.AddSignalToCheckForUpdateOnAllRepoQueue()
+	go tasks.RunScheduler(ctx, &wg)
 
 	// create mux router
 	r := mux.NewRouter()
 	routes.ConnectRoutes(r, controller)
 
-	// Graceful shutdown
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 	server := &http.Server{Addr: ":8080", Handler: r}
+	// event streams never finish on their own; end them so they don't hold up shutdown
+	server.RegisterOnShutdown(eventBus.Close)
 
 	go func() {
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -56,23 +73,22 @@ func main() {
 	}()
 
 	log.Println("Server started on :8080")
-	<-stop
+	<-ctx.Done()
+	// a second signal kills the process straight away
+	stop()
 	log.Println("Shutting down server...")
 
 	// Create a deadline to wait for.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	// Close channels to signal workers to stop
-	close(tasks.GetAllRepoForUserQueue)
-	close(tasks.FetchNewlyRequestedRepoQueue)
-	close(tasks.CheckForUpdateOnAllRepoQueue)
-
-	// Wait for all goroutines to complete
-	wg.Wait()
-
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	// let in-flight requests finish, then the running jobs; jobs still running at the
+	// deadline are interrupted and released, and queued jobs stay in the database for the next start
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+	if err := tasks.Shutdown(shutdownCtx, &wg); err != nil {
+		log.Printf("Workers forced to shutdown: %v", err)
 	}
 
 	log.Println("Server exiting")
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/midedickson/github-service/database"
+)
+
+// handle `migrate up`, `migrate down [steps]` and `migrate status`
+func runMigrateCommand(args []string) {
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+	switch command {
+	case "up":
+		if err := database.MigrateUp(database.DB); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		log.Println("Migrated DB Successfully")
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed < 1 {
+				log.Fatalf("Invalid number of steps %q", args[1])
+			}
+			steps = parsed
+		}
+		if err := database.MigrateDown(database.DB, steps); err != nil {
+			log.Fatalf("Rollback failed: %v", err)
+		}
+		log.Printf("Rolled back %d migration(s)", steps)
+	case "status":
+		statuses, err := database.GetMigrationStatus(database.DB)
+		if err != nil {
+			log.Fatalf("Could not read migration status: %v", err)
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(writer, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		writer.Flush()
+	default:
+		log.Fatalf("Unknown migrate command %q, expected up, down or status", command)
+	}
+}
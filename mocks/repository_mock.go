@@ -1,6 +1,9 @@
 package mocks
 
 import (
+	"context"
+	"time"
+
 	"github.com/midedickson/github-service/dto"
 	"github.com/midedickson/github-service/models"
 	"github.com/midedickson/github-service/utils"
@@ -11,12 +14,12 @@ type MockDBRepository struct {
 	mock.Mock
 }
 
-func (m *MockDBRepository) CreateUser(createUserPayload *dto.CreateUserPayloadDTO) (*models.User, error) {
+func (m *MockDBRepository) CreateUser(ctx context.Context, createUserPayload *dto.CreateUserPayloadDTO) (*models.User, error) {
 	args := m.Called(createUserPayload)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockDBRepository) GetUser(username string) (*models.User, error) {
+func (m *MockDBRepository) GetUser(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(username)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -24,12 +27,12 @@ func (m *MockDBRepository) GetUser(username string) (*models.User, error) {
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
-func (m *MockDBRepository) StoreRepositoryInfo(remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) (*models.Repository, error) {
+func (m *MockDBRepository) StoreRepositoryInfo(ctx context.Context, remoteRepoInfo *dto.RepositoryInfoResponseDTO, owner *models.User) (*models.Repository, error) {
 	args := m.Called(remoteRepoInfo, owner)
 	return args.Get(0).(*models.Repository), args.Error(1)
 }
 
-func (m *MockDBRepository) GetRepository(ownerID uint, repoName string) (*models.Repository, error) {
+func (m *MockDBRepository) GetRepository(ctx context.Context, ownerID uint, repoName string) (*models.Repository, error) {
 	args := m.Called(ownerID, repoName)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -37,22 +40,328 @@ func (m *MockDBRepository) GetRepository(ownerID uint, repoName string) (*models
 	return args.Get(0).(*models.Repository), args.Error(1)
 }
 
-func (m *MockDBRepository) StoreRepositoryCommits(commitRepoInfos *[]dto.CommitResponseDTO, repoName string, owner *models.User) error {
-	args := m.Called(commitRepoInfos, repoName, owner)
+func (m *MockDBRepository) GetRepositoryInfoByRemoteId(ctx context.Context, remoteID int) (*models.Repository, error) {
+	args := m.Called(remoteID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Repository), args.Error(1)
+}
+
+func (m *MockDBRepository) DeleteRepository(ctx context.Context, repo *models.Repository) error {
+	args := m.Called(repo)
 	return args.Error(0)
 }
 
-func (m *MockDBRepository) GetRepositoryCommits(repoName string) ([]*models.Commit, error) {
-	args := m.Called(repoName)
+func (m *MockDBRepository) StoreRepositoryCommits(ctx context.Context, commitRepoInfos *[]dto.CommitResponseDTO, repo *models.Repository) ([]*models.Commit, error) {
+	args := m.Called(commitRepoInfos, repo)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
 	return args.Get(0).([]*models.Commit), args.Error(1)
 }
 
-func (m *MockDBRepository) GetAllRepositories() ([]*models.Repository, error) {
+func (m *MockDBRepository) UpdateRepositoryLatestCommitAt(ctx context.Context, repo *models.Repository, latestCommitAt time.Time) error {
+	args := m.Called(repo, latestCommitAt)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetRepositoryCommits(ctx context.Context, owner, repoName string, filters *utils.CommitFilters, list *utils.ListParams) ([]*models.Commit, *utils.PageInfo, error) {
+	args := m.Called(owner, repoName, filters, list)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*models.Commit), nil, args.Error(2)
+	}
+	return args.Get(0).([]*models.Commit), args.Get(1).(*utils.PageInfo), args.Error(2)
+}
+
+func (m *MockDBRepository) GetRepositoryCommit(ctx context.Context, owner, repoName, sha string) (*models.Commit, error) {
+	args := m.Called(owner, repoName, sha)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Commit), args.Error(1)
+}
+
+func (m *MockDBRepository) GetTopCommitAuthors(ctx context.Context, statsQuery *utils.AuthorStatsQuery) ([]*dto.AuthorStatsResponseDTO, error) {
+	args := m.Called(statsQuery)
+	return args.Get(0).([]*dto.AuthorStatsResponseDTO), args.Error(1)
+}
+
+func (m *MockDBRepository) GetRepositoryMetricHistory(ctx context.Context, repo *models.Repository, historyQuery *utils.MetricHistoryQuery) (*dto.MetricHistoryResponseDTO, error) {
+	args := m.Called(repo, historyQuery)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.MetricHistoryResponseDTO), args.Error(1)
+}
+
+func (m *MockDBRepository) GetRepositoryEvents(ctx context.Context, repo *models.Repository, eventType string, list *utils.ListParams) ([]*models.RepositoryEvent, *utils.PageInfo, error) {
+	args := m.Called(repo, eventType, list)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*models.RepositoryEvent), nil, args.Error(2)
+	}
+	return args.Get(0).([]*models.RepositoryEvent), args.Get(1).(*utils.PageInfo), args.Error(2)
+}
+
+func (m *MockDBRepository) GetTrendingRepositories(ctx context.Context, trendingQuery *utils.TrendingQuery) ([]*dto.TrendingRepositoryResponseDTO, error) {
+	args := m.Called(trendingQuery)
+	return args.Get(0).([]*dto.TrendingRepositoryResponseDTO), args.Error(1)
+}
+
+func (m *MockDBRepository) GetAllRepositories(ctx context.Context) ([]*models.Repository, error) {
 	args := m.Called()
 	return args.Get(0).([]*models.Repository), args.Error(1)
 }
 
-func (m *MockDBRepository) SearchRepository(ownerID uint, repoSearchParams *utils.RepositorySearchParams) ([]*models.Repository, error) {
-	args := m.Called(ownerID, repoSearchParams)
+func (m *MockDBRepository) SearchRepository(ctx context.Context, ownerID uint, repoSearchParams *utils.RepositorySearchParams, list *utils.ListParams) ([]*models.Repository, *utils.PageInfo, error) {
+	args := m.Called(ownerID, repoSearchParams, list)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*models.Repository), nil, args.Error(2)
+	}
+	return args.Get(0).([]*models.Repository), args.Get(1).(*utils.PageInfo), args.Error(2)
+}
+
+func (m *MockDBRepository) Search(ctx context.Context, query *utils.SearchQuery) ([]*dto.SearchResultResponseDTO, *utils.PageInfo, error) {
+	args := m.Called(query)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*dto.SearchResultResponseDTO), nil, args.Error(2)
+	}
+	return args.Get(0).([]*dto.SearchResultResponseDTO), args.Get(1).(*utils.PageInfo), args.Error(2)
+}
+
+func (m *MockDBRepository) GetHTTPCacheEntry(ctx context.Context, url string) (*models.HTTPCacheEntry, error) {
+	args := m.Called(url)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HTTPCacheEntry), args.Error(1)
+}
+
+func (m *MockDBRepository) StoreHTTPCacheEntry(ctx context.Context, url, etag, lastModified string) error {
+	args := m.Called(url, etag, lastModified)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) SetRepositorySyncSince(ctx context.Context, repo *models.Repository, since *time.Time) error {
+	args := m.Called(repo, since)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) CreateCommitBackfill(ctx context.Context, repo *models.Repository, since *time.Time) (*models.CommitBackfill, error) {
+	args := m.Called(repo, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CommitBackfill), args.Error(1)
+}
+
+func (m *MockDBRepository) UpdateCommitBackfill(ctx context.Context, backfill *models.CommitBackfill) error {
+	args := m.Called(backfill)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetCommitBackfill(ctx context.Context, id uint) (*models.CommitBackfill, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CommitBackfill), args.Error(1)
+}
+
+func (m *MockDBRepository) GetRepositoryByID(ctx context.Context, id uint) (*models.Repository, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Repository), args.Error(1)
+}
+
+func (m *MockDBRepository) GetLatestJobByDedupeKey(ctx context.Context, owner, dedupeKey string) (*models.Job, error) {
+	args := m.Called(owner, dedupeKey)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockDBRepository) EnqueueJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetJob(ctx context.Context, id uint) (*models.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockDBRepository) GetJobsByOwner(ctx context.Context, owner string, limit int) ([]*models.Job, error) {
+	args := m.Called(owner, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Job), args.Error(1)
+}
+
+func (m *MockDBRepository) CountJobsByState(ctx context.Context, owner string) (map[string]int64, error) {
+	args := m.Called(owner)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]int64), args.Error(1)
+}
+
+func (m *MockDBRepository) CountPendingJobs(ctx context.Context, jobType string) (int64, error) {
+	args := m.Called(jobType)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockDBRepository) CountActiveJobsByType(ctx context.Context) (map[string]map[string]int64, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]map[string]int64), args.Error(1)
+}
+
+func (m *MockDBRepository) UpdateJobProgress(ctx context.Context, job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) ClaimJob(ctx context.Context, jobTypes []string, workerID string, lease time.Duration, maxAttempts int) (*models.Job, error) {
+	args := m.Called(jobTypes, workerID, lease, maxAttempts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockDBRepository) ExtendJobLease(ctx context.Context, job *models.Job, lease time.Duration) error {
+	args := m.Called(job, lease)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) CompleteJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) FailJob(ctx context.Context, job *models.Job, reason, errMsg string) error {
+	args := m.Called(job, reason, errMsg)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) RetryJob(ctx context.Context, job *models.Job, errMsg string, runAt time.Time) error {
+	args := m.Called(job, errMsg, runAt)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetDeadLetters(ctx context.Context, jobType string) ([]*models.DeadLetter, error) {
+	args := m.Called(jobType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.DeadLetter), args.Error(1)
+}
+
+func (m *MockDBRepository) GetDeadLetter(ctx context.Context, id uint) (*models.DeadLetter, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.DeadLetter), args.Error(1)
+}
+
+func (m *MockDBRepository) RequeueDeadLetter(ctx context.Context, deadLetter *models.DeadLetter) (*models.Job, error) {
+	args := m.Called(deadLetter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockDBRepository) ReleaseJob(ctx context.Context, job *models.Job) error {
+	args := m.Called(job)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetRepositoriesDueForRefresh(ctx context.Context, now time.Time, limit int) ([]*models.Repository, error) {
+	args := m.Called(now, limit)
 	return args.Get(0).([]*models.Repository), args.Error(1)
 }
+
+func (m *MockDBRepository) RescheduleRepositoryRefresh(ctx context.Context, repo *models.Repository, nextRefreshAt time.Time) (bool, error) {
+	args := m.Called(repo, nextRefreshAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockDBRepository) EnqueueUniqueJob(ctx context.Context, job *models.Job) (*models.Job, bool, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*models.Job), args.Bool(1), args.Error(2)
+}
+
+func (m *MockDBRepository) EnqueuePendingUniqueJob(ctx context.Context, job *models.Job) (*models.Job, bool, error) {
+	args := m.Called(job)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*models.Job), args.Bool(1), args.Error(2)
+}
+
+func (m *MockDBRepository) CreateWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetWebhookSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	args := m.Called()
+	return args.Get(0).([]*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockDBRepository) GetWebhookSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookSubscription), args.Error(1)
+}
+
+func (m *MockDBRepository) DeleteWebhookSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	args := m.Called(subscription)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) GetWebhookDeliveries(ctx context.Context, subscription *models.WebhookSubscription, state string, list *utils.ListParams) ([]*models.WebhookDelivery, *utils.PageInfo, error) {
+	args := m.Called(subscription, state, list)
+	if args.Get(1) == nil {
+		return args.Get(0).([]*models.WebhookDelivery), nil, args.Error(2)
+	}
+	return args.Get(0).([]*models.WebhookDelivery), args.Get(1).(*utils.PageInfo), args.Error(2)
+}
+
+func (m *MockDBRepository) GetWebhookDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockDBRepository) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockDBRepository) RedeliverWebhook(ctx context.Context, delivery *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	args := m.Called(delivery)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.WebhookDelivery), args.Error(1)
+}
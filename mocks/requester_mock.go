@@ -10,7 +10,10 @@
 package mocks
 
 import (
+	"context"
 	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/requester"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -23,7 +26,7 @@ type MockRequester struct {
 
 
 // GetAllUserRepositories mocks base method.
-func (m *MockRequester) GetAllUserRepositories(owner string) (*[]dto.RepositoryInfoResponseDTO, error) {
+func (m *MockRequester) GetAllUserRepositories(ctx context.Context, owner string) (*[]dto.RepositoryInfoResponseDTO, error) {
 	args := m.Called(owner)
 	return args.Get(0).(*[]dto.RepositoryInfoResponseDTO), args.Error(1)
 }
@@ -31,14 +34,56 @@ func (m *MockRequester) GetAllUserRepositories(owner string) (*[]dto.RepositoryI
 
 
 // GetRepositoryCommits mocks base method.
-func (m *MockRequester) GetRepositoryCommits(owner, repo string) (*[]dto.CommitResponseDTO, error) {
-	args := m.Called(owner, repo)
+func (m *MockRequester) GetRepositoryCommits(ctx context.Context, owner, repo string, query *requester.CommitsQuery) (*[]dto.CommitResponseDTO, error) {
+	args := m.Called(owner, repo, query)
 	return args.Get(0).(*[]dto.CommitResponseDTO), args.Error(1)
 }
 
 
 // GetRepositoryInfo mocks base method.
-func (m *MockRequester) GetRepositoryInfo(owner, repo string) (*dto.RepositoryInfoResponseDTO, error) {
+func (m *MockRequester) GetRepositoryInfo(ctx context.Context, owner, repo string) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error) {
+	args := m.Called(owner, repo)
+	return args.Get(0).(*dto.RepositoryInfoResponseDTO), args.Get(1).(*models.HTTPCacheEntry), args.Error(2)
+}
+
+// StreamRepositoryCommits mocks base method.
+func (m *MockRequester) StreamRepositoryCommits(ctx context.Context, owner, repo string, query *requester.CommitsQuery, handler func(page *[]dto.CommitResponseDTO) error) error {
+	args := m.Called(owner, repo, query, handler)
+	return args.Error(0)
+}
+
+// StreamUserRepositories mocks base method.
+func (m *MockRequester) StreamUserRepositories(ctx context.Context, owner string, handler func(page *[]dto.RepositoryInfoResponseDTO) error) error {
+	args := m.Called(owner, handler)
+	return args.Error(0)
+}
+
+// GetRepositoryInfoIfModified mocks base method.
+func (m *MockRequester) GetRepositoryInfoIfModified(ctx context.Context, owner, repo string) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error) {
 	args := m.Called(owner, repo)
-	return args.Get(0).(*dto.RepositoryInfoResponseDTO), args.Error(1)
-}
\ No newline at end of file
+	if args.Get(0) == nil {
+		return nil, nil, args.Error(2)
+	}
+	return args.Get(0).(*dto.RepositoryInfoResponseDTO), args.Get(1).(*models.HTTPCacheEntry), args.Error(2)
+}
+
+// StoreValidators mocks base method.
+func (m *MockRequester) StoreValidators(ctx context.Context, validators *models.HTTPCacheEntry) error {
+	args := m.Called(validators)
+	return args.Error(0)
+}
+
+// RepositoryCommitsURL mocks base method.
+func (m *MockRequester) RepositoryCommitsURL(owner, repo string, query *requester.CommitsQuery) string {
+	args := m.Called(owner, repo, query)
+	return args.String(0)
+}
+
+// GetRepositoryCommitsPage mocks base method.
+func (m *MockRequester) GetRepositoryCommitsPage(ctx context.Context, pageURL string) (*[]dto.CommitResponseDTO, string, error) {
+	args := m.Called(pageURL)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.Error(2)
+	}
+	return args.Get(0).(*[]dto.CommitResponseDTO), args.String(1), args.Error(2)
+}
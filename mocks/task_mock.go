@@ -1,6 +1,9 @@
 package mocks
 
 import (
+	"context"
+
+	"github.com/midedickson/github-service/dto"
 	"github.com/midedickson/github-service/models"
 	"github.com/stretchr/testify/mock"
 )
@@ -9,10 +12,74 @@ type MockTask struct {
 	mock.Mock
 }
 
-func (m *MockTask) AddUserToGetAllRepoQueue(user *models.User) {
-	m.Called(user)
+func (m *MockTask) AddUserToGetAllRepoQueue(ctx context.Context, user *models.User) (*models.Job, error) {
+	args := m.Called(user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockTask) AddRequestToFetchNewlyRequestedRepoQueue(ctx context.Context, username, repoName string) (*models.Job, error) {
+	args := m.Called(username, repoName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockTask) SyncUser(ctx context.Context, user *models.User, full bool) (*models.Job, bool, error) {
+	args := m.Called(user, full)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*models.Job), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTask) RefreshRepositoryFromHook(ctx context.Context, repo *models.Repository) (*models.Job, error) {
+	args := m.Called(repo)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockTask) SyncRepository(ctx context.Context, repo *models.Repository, full bool) (*models.Job, bool, error) {
+	args := m.Called(repo, full)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*models.Job), args.Bool(1), args.Error(2)
+}
+
+func (m *MockTask) AddRepositoryToBackfillQueue(ctx context.Context, backfill *models.CommitBackfill) (*models.Job, error) {
+	args := m.Called(backfill)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockTask) GetJob(ctx context.Context, id uint) (*models.Job, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Job), args.Error(1)
+}
+
+func (m *MockTask) GetSyncStatus(ctx context.Context, owner string) (*dto.SyncStatusResponseDTO, error) {
+	args := m.Called(owner)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*dto.SyncStatusResponseDTO), args.Error(1)
 }
 
-func (m *MockTask) AddRequestToFetchNewlyRequestedRepoQueue(username, repoName string) {
-	m.Called(username, repoName)
+func (m *MockTask) GetQueueStats(ctx context.Context) ([]*dto.QueueStatsResponseDTO, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*dto.QueueStatsResponseDTO), args.Error(1)
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	BackfillStatusPending   = "pending"
+	BackfillStatusRunning   = "running"
+	BackfillStatusCompleted = "completed"
+	BackfillStatusFailed    = "failed"
+)
+
+// checkpoint of a full history backfill, updated after every page so it can be resumed after a restart
+type CommitBackfill struct {
+	gorm.Model
+	RepositoryID   uint        `gorm:"uniqueIndex" json:"repository_id"`
+	Repository     *Repository `gorm:"foreignKey:RepositoryID" json:"-"`
+	Since          *time.Time  `json:"since"`
+	NextPageURL    string      `json:"-"`
+	Status         string      `json:"status"`
+	PagesFetched   int         `json:"pages_fetched"`
+	CommitsFetched int         `json:"commits_fetched"`
+	LastError      string      `json:"last_error"`
+}
@@ -1,16 +1,18 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Commit struct {
 	gorm.Model
-	RepositoryName string      `gorm:"repository_name"`
-	Repository     *Repository `gorm:"foreignKey:RepositoryName"`
-	Message        string      `gorm:"message" json:"message"`
-	Author         string      `gorm:"author" json:"author"`
-	Date           string      `gorm:"string" json:"date"`
-	URL            string      `gorm:"html_url" json:"html_url"`
-	SHA            string      `gorm:"sha" json:"sha"`
+	RepositoryID uint        `gorm:"uniqueIndex:idx_commits_repository_sha;index:idx_commits_repository_date,priority:1" json:"repository_id"`
+	Repository   *Repository `gorm:"foreignKey:RepositoryID" json:"-"`
+	Message      string      `gorm:"message" json:"message"`
+	Author       string      `gorm:"author" json:"author"`
+	Date         time.Time   `gorm:"index:idx_commits_repository_date,priority:2" json:"date"`
+	URL          string      `gorm:"html_url" json:"html_url"`
+	SHA          string      `gorm:"uniqueIndex:idx_commits_repository_sha" json:"sha"`
 }
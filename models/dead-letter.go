@@ -0,0 +1,28 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+const (
+	// the error can't be fixed by trying again, e.g. the repository doesn't exist on github
+	DeadLetterReasonPermanent = "permanent-error"
+	// github doesn't have the repository; like a permanent error, but kept apart so requests for
+	// the repository can be answered without asking github again
+	DeadLetterReasonNotFound = "not-found"
+	// the job kept failing until its retry policy gave up on it
+	DeadLetterReasonRetriesExhausted = "retries-exhausted"
+	// the job's lease ran out on its last attempt, most likely because it took its worker down
+	DeadLetterReasonLeaseExpired = "lease-expired"
+)
+
+// a job that failed for good, kept for inspection until it is requeued
+type DeadLetter struct {
+	gorm.Model
+	JobID    uint   `gorm:"index" json:"job_id"`
+	Job      *Job   `gorm:"foreignKey:JobID" json:"job,omitempty"`
+	JobType  string `gorm:"index" json:"job_type"`
+	Reason   string `json:"reason"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
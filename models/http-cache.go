@@ -0,0 +1,11 @@
+package models
+
+import "gorm.io/gorm"
+
+// validators of the last successful response for a github url, used for conditional requests
+type HTTPCacheEntry struct {
+	gorm.Model
+	URL          string `gorm:"uniqueIndex" json:"url"`
+	ETag         string `json:"etag"`
+	LastModified string `json:"last_modified"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	JobTypeFetchUserRepos  = "fetch-user-repos"
+	JobTypeFetchRepo       = "fetch-repo"
+	JobTypeRefreshRepo     = "refresh-repo"
+	JobTypeSyncCommits     = "sync-commits"
+	JobTypeBackfillCommits = "backfill-commits"
+	JobTypeDeliverWebhook  = "deliver-webhook"
+)
+
+const (
+	JobStatePending   = "pending"
+	JobStateRunning   = "running"
+	JobStateSucceeded = "succeeded"
+	JobStateFailed    = "failed"
+)
+
+// a unit of background work in the durable queue; workers claim pending jobs by
+// taking a lease, and jobs whose lease runs out are picked up again by another worker.
+// Owner is the username of the github account the job works on, and the progress
+// counters describe the current attempt
+type Job struct {
+	gorm.Model
+	Type           string     `gorm:"index:idx_jobs_claim,priority:2" json:"type"`
+	Owner          string     `gorm:"index" json:"owner"`
+	Payload        string     `json:"payload"`
+	State          string     `gorm:"index:idx_jobs_claim,priority:1" json:"state"`
+	Attempts       int        `json:"attempts"`
+	RunAt          time.Time  `gorm:"index:idx_jobs_claim,priority:3" json:"run_at"`
+	LeaseOwner     string     `json:"-"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at"`
+	LastError      string     `json:"last_error"`
+	// the dead letter reason of a job that failed for good
+	FailureReason string     `json:"failure_reason,omitempty"`
+	FinishedAt    *time.Time `json:"finished_at"`
+	PagesFetched  int        `json:"pages_fetched"`
+	CommitsStored int        `json:"commits_stored"`
+	// jobs with a dedupe key are only queued when no pending or running job has the same key
+	DedupeKey string `json:"dedupe_key,omitempty"`
+}
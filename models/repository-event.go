@@ -0,0 +1,36 @@
+package models
+
+import "gorm.io/gorm"
+
+const (
+	RepositoryEventDiscovered           = "discovered"
+	RepositoryEventRenamed              = "renamed"
+	RepositoryEventTransferred          = "transferred"
+	RepositoryEventDescriptionChanged   = "description_changed"
+	RepositoryEventLanguageChanged      = "language_changed"
+	RepositoryEventDefaultBranchChanged = "default_branch_changed"
+	RepositoryEventStarsChanged         = "stars_changed"
+	RepositoryEventForksChanged         = "forks_changed"
+	RepositoryEventOpenIssuesChanged    = "open_issues_changed"
+	RepositoryEventWatchersChanged      = "watchers_changed"
+)
+
+var RepositoryEventTypes = []string{
+	RepositoryEventDiscovered, RepositoryEventRenamed, RepositoryEventTransferred,
+	RepositoryEventDescriptionChanged, RepositoryEventLanguageChanged, RepositoryEventDefaultBranchChanged,
+	RepositoryEventStarsChanged, RepositoryEventForksChanged, RepositoryEventOpenIssuesChanged, RepositoryEventWatchersChanged,
+}
+
+// a change found in a repository when it was stored, kept as an audit log. counts also record
+// by how much they moved
+type RepositoryEvent struct {
+	gorm.Model
+	RepositoryID uint        `gorm:"index" json:"repository_id"`
+	Repository   *Repository `gorm:"foreignKey:RepositoryID" json:"-"`
+	Type         string      `gorm:"index" json:"type"`
+	Field        string      `json:"field,omitempty"`
+	OldValue     string      `json:"old_value,omitempty"`
+	NewValue     string      `json:"new_value,omitempty"`
+	Delta        int         `json:"delta,omitempty"`
+	Summary      string      `json:"summary"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// the metrics of a repository as a refresh found them; one is recorded when a repository is
+// first stored and again whenever a refresh sees any of them change
+type RepositorySnapshot struct {
+	gorm.Model
+	RepositoryID uint        `gorm:"index:idx_repository_snapshots_repository_taken,priority:1" json:"repository_id"`
+	Repository   *Repository `gorm:"foreignKey:RepositoryID" json:"-"`
+	StarsCount   int         `json:"stars_count"`
+	ForksCount   int         `json:"forks_count"`
+	OpenIssues   int         `json:"open_issues"`
+	Watchers     int         `json:"watchers"`
+	TakenAt      time.Time   `gorm:"index:idx_repository_snapshots_repository_taken,priority:2" json:"taken_at"`
+}
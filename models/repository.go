@@ -1,22 +1,33 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type Repository struct {
 	gorm.Model
 	RemoteID        int    `gorm:"remote_id"`
-	OwnerID         uint   `gorm:"owner_id"`
+	OwnerID         uint   `gorm:"index:idx_repositories_owner_stars,priority:1;index:idx_repositories_owner_forks,priority:1;index:idx_repositories_owner_name,priority:1;index:idx_repositories_owner_updated,priority:1;index:idx_repositories_owner_created,priority:1"`
 	Owner           *User  `gorm:"foreignKey:OwnerID"`
-	Name            string `gorm:"name"`
+	Name            string `gorm:"index:idx_repositories_owner_name,priority:2"`
 	Description     string `gorm:"description"`
 	URL             string `gorm:"html_url"`
 	Language        string `gorm:"language"`
-	ForksCount      int    `gorm:"forks_count"`
-	StarsCount      int    `gorm:"stargazers_count"`
+	ForksCount      int    `gorm:"index:idx_repositories_owner_forks,priority:2"`
+	StarsCount      int    `gorm:"index:idx_repositories_owner_stars,priority:2"`
 	OpenIssues      int    `gorm:"open_issues_count"`
 	Watchers        int    `gorm:"watchers_count"`
-	RemoteCreatedAt string `gorm:"remote_created_at"`
-	RemoteUpdatedAt string `gorm:"remote_updated_at"`
+	RemoteCreatedAt string `gorm:"index:idx_repositories_owner_created,priority:2"`
+	RemoteUpdatedAt string `gorm:"index:idx_repositories_owner_updated,priority:2"`
+	DefaultBranch   string `gorm:"default_branch"`
+	// date of the newest commit stored for this repository, used for incremental syncs
+	LatestCommitAt *time.Time `gorm:"latest_commit_at"`
+	// per repository start date for commit syncs, overriding the global setting
+	SyncCommitsSince *time.Time `gorm:"sync_commits_since"`
+	// when the scheduler next queues an update check; nil until it has been scheduled once
+	NextRefreshAt *time.Time `gorm:"index"`
+	// the events the last store of this repository logged, its discovery or what changed; not persisted
+	Changes []*RepositoryEvent `gorm:"-" json:"-"`
 }
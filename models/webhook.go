@@ -0,0 +1,55 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookEventCommitCreated        = "commit.created"
+	WebhookEventRepositoryDiscovered = "repository.discovered"
+	WebhookEventRepositoryUpdated    = "repository.updated"
+)
+
+var WebhookEvents = []string{WebhookEventCommitCreated, WebhookEventRepositoryDiscovered, WebhookEventRepositoryUpdated}
+
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliveryRetrying  = "retrying"
+	WebhookDeliveryDelivered = "delivered"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// a downstream service that wants to be told about the events it subscribed to; the secret
+// signs every payload sent to it and is never shown again once set
+type WebhookSubscription struct {
+	gorm.Model
+	URL    string   `json:"url"`
+	Secret string   `json:"-"`
+	Events []string `gorm:"serializer:json" json:"events"`
+}
+
+func (s *WebhookSubscription) Subscribes(event string) bool {
+	return slices.Contains(s.Events, event)
+}
+
+// one event sent to one subscription, along with how the last attempt at sending it went.
+// the attempts themselves are made by a deliver-webhook job; a redelivery is a new delivery
+// of the same payload
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint                 `gorm:"index" json:"subscription_id"`
+	Subscription   *WebhookSubscription `gorm:"foreignKey:SubscriptionID" json:"-"`
+	JobID          uint                 `gorm:"index" json:"job_id"`
+	Event          string               `json:"event"`
+	Payload        string               `json:"payload"`
+	State          string               `gorm:"index" json:"state"`
+	Attempts       int                  `json:"attempts"`
+	StatusCode     int                  `json:"status_code,omitempty"`
+	Error          string               `json:"error,omitempty"`
+	LastAttemptAt  *time.Time           `json:"last_attempt_at"`
+	DeliveredAt    *time.Time           `json:"delivered_at"`
+	RedeliveryOf   *uint                `json:"redelivery_of,omitempty"`
+}
@@ -0,0 +1,79 @@
+package requester_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/requester"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type memoryCache struct {
+	entries map[string]*models.HTTPCacheEntry
+}
+
+func (c *memoryCache) GetHTTPCacheEntry(ctx context.Context, url string) (*models.HTTPCacheEntry, error) {
+	return c.entries[url], nil
+}
+
+func (c *memoryCache) StoreHTTPCacheEntry(ctx context.Context, url, etag, lastModified string) error {
+	c.entries[url] = &models.HTTPCacheEntry{URL: url, ETag: etag, LastModified: lastModified}
+	return nil
+}
+
+func TestGetRepositoryInfoIfModified_ReturnsNotModifiedForMatchingETag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Last-Modified", "Mon, 01 Jul 2024 00:00:00 GMT")
+		json.NewEncoder(w).Encode(dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"})
+	}))
+	defer server.Close()
+
+	cache := &memoryCache{entries: map[string]*models.HTTPCacheEntry{}}
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, cache)
+
+	repo, validators, err := repoRequester.GetRepositoryInfoIfModified(context.Background(), "testuser", "testrepo")
+	assert.NoError(t, err)
+	assert.Equal(t, "testrepo", repo.Name)
+	assert.Equal(t, `"v1"`, validators.ETag)
+	// nothing is stored until the caller has stored the repository
+	assert.Empty(t, cache.entries)
+	assert.NoError(t, repoRequester.StoreValidators(context.Background(), validators))
+	assert.Equal(t, `"v1"`, cache.entries[server.URL+"/repos/testuser/testrepo"].ETag)
+
+	repo, validators, err = repoRequester.GetRepositoryInfoIfModified(context.Background(), "testuser", "testrepo")
+	assert.ErrorIs(t, err, utils.ErrNotModified)
+	assert.Nil(t, repo)
+	assert.Nil(t, validators)
+}
+
+func TestGetRepositoryInfo_ReturnsButDoesNotSendValidators(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", `"v2"`)
+		json.NewEncoder(w).Encode(dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"})
+	}))
+	defer server.Close()
+
+	cache := &memoryCache{entries: map[string]*models.HTTPCacheEntry{}}
+	cache.StoreHTTPCacheEntry(context.Background(), server.URL+"/repos/testuser/testrepo", `"v1"`, "")
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, cache)
+
+	_, validators, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+	assert.NoError(t, err)
+	assert.Equal(t, `"v2"`, validators.ETag)
+	assert.Equal(t, `"v1"`, cache.entries[server.URL+"/repos/testuser/testrepo"].ETag)
+	assert.NoError(t, repoRequester.StoreValidators(context.Background(), validators))
+	assert.Equal(t, `"v2"`, cache.entries[server.URL+"/repos/testuser/testrepo"].ETag)
+}
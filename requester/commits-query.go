@@ -0,0 +1,33 @@
+package requester
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// optional filters applied when listing the commits of a repository
+type CommitsQuery struct {
+	// only return commits after this date
+	Since time.Time
+	// branch name or sha to start listing commits from
+	SHA string
+}
+
+func (r *RepositoryRequester) commitsURL(owner, repo string, query *CommitsQuery) string {
+	commitsURL := fmt.Sprintf("%s/repos/%s/%s/commits", r.baseURL, owner, repo)
+	if query == nil {
+		return commitsURL
+	}
+	params := url.Values{}
+	if !query.Since.IsZero() {
+		params.Set("since", query.Since.UTC().Format(time.RFC3339))
+	}
+	if query.SHA != "" {
+		params.Set("sha", query.SHA)
+	}
+	if len(params) == 0 {
+		return commitsURL
+	}
+	return commitsURL + "?" + params.Encode()
+}
@@ -1,11 +1,31 @@
 package requester
 
 import (
+	"context"
+
 	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
 )
 
 type Requester interface {
-	GetRepositoryInfo(owner, repo string) (*dto.RepositoryInfoResponseDTO, error)
-	GetRepositoryCommits(owner, repo string) (*[]dto.CommitResponseDTO, error)
-	GetAllUserRepositories(owner string) (*[]dto.RepositoryInfoResponseDTO, error)
+	// the repository along with the validators github answered with, if any; pass them to
+	// StoreValidators once the repository is stored
+	GetRepositoryInfo(ctx context.Context, owner, repo string) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error)
+	// conditional variant that returns utils.ErrNotModified when github reports no change
+	GetRepositoryInfoIfModified(ctx context.Context, owner, repo string) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error)
+	StoreValidators(ctx context.Context, validators *models.HTTPCacheEntry) error
+	GetRepositoryCommits(ctx context.Context, owner, repo string, query *CommitsQuery) (*[]dto.CommitResponseDTO, error)
+	GetAllUserRepositories(ctx context.Context, owner string) (*[]dto.RepositoryInfoResponseDTO, error)
+	// streaming variants hand over each page as soon as it is fetched
+	StreamRepositoryCommits(ctx context.Context, owner, repo string, query *CommitsQuery, handler func(page *[]dto.CommitResponseDTO) error) error
+	StreamUserRepositories(ctx context.Context, owner string, handler func(page *[]dto.RepositoryInfoResponseDTO) error) error
+	// page by page access for long running walks that checkpoint the url of the next page
+	RepositoryCommitsURL(owner, repo string, query *CommitsQuery) string
+	GetRepositoryCommitsPage(ctx context.Context, pageURL string) (*[]dto.CommitResponseDTO, string, error)
+}
+
+// persists the ETag and Last-Modified validators of responses per url
+type ResponseCache interface {
+	GetHTTPCacheEntry(ctx context.Context, url string) (*models.HTTPCacheEntry, error)
+	StoreHTTPCacheEntry(ctx context.Context, url, etag, lastModified string) error
 }
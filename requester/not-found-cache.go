@@ -0,0 +1,48 @@
+package requester
+
+import (
+	"sync"
+	"time"
+)
+
+// remembers urls github answered with a 404 so they aren't requested again until the ttl runs out;
+// a zero ttl disables it
+type notFoundCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	expires map[string]time.Time
+}
+
+func newNotFoundCache(ttl time.Duration) *notFoundCache {
+	return &notFoundCache{ttl: ttl, expires: map[string]time.Time{}}
+}
+
+func (c *notFoundCache) has(url string) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires, ok := c.expires[url]
+	if ok && time.Now().After(expires) {
+		delete(c.expires, url)
+		return false
+	}
+	return ok
+}
+
+func (c *notFoundCache) add(url string) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	// drop expired entries while we're here so urls that are never asked for again don't pile up
+	for cached, expires := range c.expires {
+		if now.After(expires) {
+			delete(c.expires, cached)
+		}
+	}
+	c.expires[url] = now.Add(c.ttl)
+}
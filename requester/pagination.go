@@ -0,0 +1,73 @@
+package requester
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"regexp"
+	"strconv"
+)
+
+var linkNextPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+// extract the url of the next page from a github Link header
+func parseNextLink(linkHeader string) string {
+	matches := linkNextPattern.FindStringSubmatch(linkHeader)
+	if len(matches) < 2 {
+		return ""
+	}
+	return matches[1]
+}
+
+// add the configured per_page parameter to a list url
+func (r *RepositoryRequester) withPerPage(rawURL string) string {
+	if r.perPage <= 0 {
+		return rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := parsed.Query()
+	query.Set("per_page", strconv.Itoa(r.perPage))
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// walk the Link rel="next" chain starting at pageURL, handing every decoded page to handler
+func fetchPages[T any](ctx context.Context, r *RepositoryRequester, pageURL string, handler func(page *[]T) error) error {
+	pageURL = r.withPerPage(pageURL)
+	pagesFetched := 0
+	for pageURL != "" {
+		var page []T
+		nextURL, err := r.fetchPage(ctx, pageURL, &page)
+		if err != nil {
+			return err
+		}
+		pagesFetched++
+		if err := handler(&page); err != nil {
+			return err
+		}
+		if r.maxPages > 0 && pagesFetched >= r.maxPages {
+			if nextURL != "" {
+				log.Printf("Reached max page cap of %d; not following remaining pages", r.maxPages)
+			}
+			break
+		}
+		pageURL = nextURL
+	}
+	return nil
+}
+
+// walk every page and merge the results into a single slice
+func fetchAllPages[T any](ctx context.Context, r *RepositoryRequester, pageURL string) (*[]T, error) {
+	results := []T{}
+	err := fetchPages(ctx, r, pageURL, func(page *[]T) error {
+		results = append(results, *page...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &results, nil
+}
@@ -1,119 +1,286 @@
 package requester
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
-	"strconv"
+	"strings"
 	"time"
 
+	"github.com/midedickson/github-service/config"
 	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
 	"github.com/midedickson/github-service/utils"
 )
 
 type RepositoryRequester struct {
 	http.Client
-	rateLimit          int
-	rateLimitRemaining int
-	rateLimitReset     time.Time
+	baseURL  string
+	perPage  int
+	maxPages int
+	tokens   *tokenPool
+	cache    ResponseCache
+	// semaphore bounding the requests in flight across every worker; nil means unbounded
+	inFlight chan struct{}
+	requests *requestGroup
+	notFound *notFoundCache
 }
 
-func NewRepositoryRequester() *RepositoryRequester {
-	return &RepositoryRequester{}
+func NewRepositoryRequester(cfg *config.Config, cache ResponseCache) *RepositoryRequester {
+	r := &RepositoryRequester{
+		cache:    cache,
+		baseURL:  strings.TrimRight(cfg.GithubAPIURL, "/"),
+		perPage:  cfg.GithubPerPage,
+		maxPages: cfg.GithubMaxPages,
+		tokens:   newTokenPool(cfg.GithubTokens),
+		requests: newRequestGroup(),
+		notFound: newNotFoundCache(cfg.GithubNotFoundTTL),
+	}
+	if cfg.GithubMaxConcurrentRequests > 0 {
+		r.inFlight = make(chan struct{}, cfg.GithubMaxConcurrentRequests)
+	}
+	return r
 }
 
-// handling rate limit
-func (r *RepositoryRequester) checkRateLimit(resp *http.Response) {
-	if limit := resp.Header.Get("x-ratelimit-limit"); limit != "" {
-		r.rateLimit, _ = strconv.Atoi(limit)
+// wait for a free request slot; the returned function gives it back. gives up when ctx is done
+func (r *RepositoryRequester) acquireSlot(ctx context.Context) (func(), error) {
+	if r.inFlight == nil {
+		return func() {}, nil
 	}
-	if remaining := resp.Header.Get("x-ratelimit-remaining"); remaining != "" {
-		r.rateLimitRemaining, _ = strconv.Atoi(remaining)
+	select {
+	case r.inFlight <- struct{}{}:
+		return func() { <-r.inFlight }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
 	}
-	if reset := resp.Header.Get("x-ratelimit-reset"); reset != "" {
-		resetTime, _ := strconv.ParseInt(reset, 10, 64)
-		r.rateLimitReset = time.Unix(resetTime, 0)
-	}
-	log.Printf("Rate limit: %d, Remaining: %d, Reset: %v", r.rateLimit, r.rateLimitRemaining, r.rateLimitReset)
+}
 
+// handling rate limit
+func (r *RepositoryRequester) checkRateLimit(token *tokenState, resp *http.Response) {
+	limit, remaining, reset := r.tokens.update(token, resp)
+	log.Printf("Rate limit for token #%d: %d, Remaining: %d, Reset: %v", token.index, limit, remaining, reset)
 }
 
-func (r *RepositoryRequester) waitForRateLimitReset() {
-	if r.rateLimitRemaining == 0 && time.Now().Before(r.rateLimitReset) {
-		log.Println("Waiting for rate limit reset")
-		time.Sleep(time.Until(r.rateLimitReset))
+// pick the token with the most remaining quota; when every token is exhausted the request
+// fails with a utils.RateLimitError instead of blocking, so the job queue can retry it after the reset
+func (r *RepositoryRequester) acquireToken() (*tokenState, error) {
+	token, wait := r.tokens.acquire()
+	if wait > 0 {
+		log.Printf("All %d token(s) exhausted; rate limit resets in %v", r.tokens.size(), wait.Round(time.Second))
+		return nil, &utils.RateLimitError{Reset: token.reset}
 	}
+	return token, nil
 }
 
-func (r *RepositoryRequester) doRequest(req *http.Request) (*http.Response, error) {
-	r.waitForRateLimitReset()
-
-	resp, err := r.Do(req)
-	if err != nil {
-		log.Printf("Error whilke making request: %v", err)
-		return nil, err
-	}
+func isRateLimited(resp *http.Response) bool {
+	return (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		resp.Header.Get("x-ratelimit-remaining") == "0"
+}
 
-	r.checkRateLimit(resp)
+func (r *RepositoryRequester) doRequest(req *http.Request) (*http.Response, error) {
+	// try each token at most once before giving up on a rate limited request
+	for attempt := 0; ; attempt++ {
+		token, err := r.acquireToken()
+		if err != nil {
+			return nil, err
+		}
+		if token.token != "" {
+			req.Header.Set("Authorization", "Bearer "+token.token)
+		} else {
+			req.Header.Del("Authorization")
+		}
 
-	if resp.StatusCode == http.StatusForbidden && resp.Header.Get("x-ratelimit-remaining") == "0" {
-		r.waitForRateLimitReset()
-		resp, err = r.Do(req)
+		resp, err := r.Do(req)
 		if err != nil {
+			log.Printf("Error while making request: %v", err)
 			return nil, err
 		}
-		r.checkRateLimit(resp)
+
+		r.checkRateLimit(token, resp)
+
+		if !isRateLimited(resp) || attempt >= r.tokens.size() {
+			return resp, nil
+		}
+		log.Printf("Token #%d hit the rate limit; rotating", token.index)
+		r.tokens.exhaust(token)
+		resp.Body.Close()
 	}
+}
 
-	return resp, nil
+type cacheMode int
+
+const (
+	// list pages; validators are neither sent nor returned
+	noCache cacheMode = iota
+	// return the response's validators for the caller to store (see StoreValidators)
+	recordValidators
+	// send the stored validators and surface a 304 as utils.ErrNotModified
+	conditionalRequest
+)
+
+// fetch a single page, decode it into result and return the url of the next page if any
+func (r *RepositoryRequester) fetchPage(ctx context.Context, url string, result interface{}) (string, error) {
+	page, err := r.fetch(ctx, url, result, noCache)
+	if err != nil {
+		return "", err
+	}
+	return page.next, nil
+}
+
+func (r *RepositoryRequester) fetch(ctx context.Context, url string, result interface{}, mode cacheMode) (*fetchedPage, error) {
+	if r.notFound.has(url) {
+		return nil, utils.ErrRepoNotFound
+	}
+	// concurrent callers asking for the same url share a single request to github
+	key := fmt.Sprintf("%d %s", mode, url)
+	page, err := r.requests.do(ctx, key, func() (*fetchedPage, error) {
+		return r.fetchOnce(ctx, url, mode)
+	})
+	if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		// the shared request was made with the context of a caller that has since given up
+		page, err = r.fetchOnce(ctx, url, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(page.body, result); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
 
-func (r *RepositoryRequester) fetchAndDecode(url string, result interface{}) error {
-	req, err := http.NewRequest(http.MethodGet, url, nil)
+func (r *RepositoryRequester) fetchOnce(ctx context.Context, url string, mode cacheMode) (*fetchedPage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if mode == conditionalRequest {
+		r.addValidators(req)
+	}
+	// hold the slot until the body has been read
+	release, err := r.acquireSlot(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer release()
 	resp, err := r.doRequest(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, utils.ErrNotModified
+	}
 	if resp.StatusCode == http.StatusNotFound {
-		return utils.ErrRepoNotFound
+		r.notFound.add(url)
+		return nil, utils.ErrRepoNotFound
+	}
+	if isRateLimited(resp) {
+		// every token was tried; the pool knows when the first one resets
+		_, wait := r.tokens.acquire()
+		return nil, &utils.RateLimitError{Reset: time.Now().Add(wait)}
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, &utils.HTTPStatusError{StatusCode: resp.StatusCode, URL: url}
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("invalid json in response from %s", url)
 	}
-	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
-		return err
+	page := &fetchedPage{body: body, next: parseNextLink(resp.Header.Get("Link"))}
+	if etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified"); mode != noCache && (etag != "" || lastModified != "") {
+		// not stored here: until the caller has stored the response, a conditional request
+		// sent with them would skip a change that was never saved
+		page.validators = &models.HTTPCacheEntry{URL: url, ETag: etag, LastModified: lastModified}
 	}
-	return nil
+	return page, nil
 }
 
-func (r *RepositoryRequester) GetRepositoryInfo(owner, repo string) (*dto.RepositoryInfoResponseDTO, error) {
-	// fetch repository info for owner
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s", owner, repo)
-	var repository dto.RepositoryInfoResponseDTO
-	if err := r.fetchAndDecode(url, &repository); err != nil {
-		return nil, err
+func (r *RepositoryRequester) addValidators(req *http.Request) {
+	if r.cache == nil {
+		return
+	}
+	entry, err := r.cache.GetHTTPCacheEntry(req.Context(), req.URL.String())
+	if err != nil {
+		log.Printf("Error in reading cache entry: %v", err)
+		return
+	}
+	if entry == nil {
+		return
+	}
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
 	}
-	return &repository, nil
 }
-func (r *RepositoryRequester) GetRepositoryCommits(owner, repo string) (*[]dto.CommitResponseDTO, error) {
-	// logic to fetch repository commits
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits", owner, repo)
-	var commits []dto.CommitResponseDTO
 
-	if err := r.fetchAndDecode(url, &commits); err != nil {
-		return nil, err
+// persist the validators of a response once what it carried has been stored, so the next
+// conditional request for its url can be answered with a 304. nil validators are ignored
+func (r *RepositoryRequester) StoreValidators(ctx context.Context, validators *models.HTTPCacheEntry) error {
+	if r.cache == nil || validators == nil {
+		return nil
 	}
-	return &commits, nil
+	return r.cache.StoreHTTPCacheEntry(ctx, validators.URL, validators.ETag, validators.LastModified)
 }
 
-func (r *RepositoryRequester) GetAllUserRepositories(owner string) (*[]dto.RepositoryInfoResponseDTO, error) {
-	//  logic to fetch all repositories for a user
-	url := fmt.Sprintf("https://api.github.com/users/%s/repos", owner)
-	var repositories []dto.RepositoryInfoResponseDTO
-	if err := r.fetchAndDecode(url, &repositories); err != nil {
-		return nil, err
+func (r *RepositoryRequester) fetchRepositoryInfo(ctx context.Context, owner, repo string, mode cacheMode) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s", r.baseURL, owner, repo)
+	var repository dto.RepositoryInfoResponseDTO
+	page, err := r.fetch(ctx, url, &repository, mode)
+	if err != nil {
+		return nil, nil, err
 	}
-	return &repositories, nil
+	return &repository, page.validators, nil
+}
+
+func (r *RepositoryRequester) GetRepositoryInfo(ctx context.Context, owner, repo string) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error) {
+	// fetch repository info for owner
+	return r.fetchRepositoryInfo(ctx, owner, repo, recordValidators)
+}
+
+func (r *RepositoryRequester) GetRepositoryInfoIfModified(ctx context.Context, owner, repo string) (*dto.RepositoryInfoResponseDTO, *models.HTTPCacheEntry, error) {
+	return r.fetchRepositoryInfo(ctx, owner, repo, conditionalRequest)
+}
+
+func (r *RepositoryRequester) GetRepositoryCommits(ctx context.Context, owner, repo string, query *CommitsQuery) (*[]dto.CommitResponseDTO, error) {
+	// logic to fetch repository commits across all pages
+	return fetchAllPages[dto.CommitResponseDTO](ctx, r, r.commitsURL(owner, repo, query))
+}
+
+func (r *RepositoryRequester) StreamRepositoryCommits(ctx context.Context, owner, repo string, query *CommitsQuery, handler func(page *[]dto.CommitResponseDTO) error) error {
+	return fetchPages(ctx, r, r.commitsURL(owner, repo, query), handler)
+}
+
+func (r *RepositoryRequester) RepositoryCommitsURL(owner, repo string, query *CommitsQuery) string {
+	return r.withPerPage(r.commitsURL(owner, repo, query))
+}
+
+// fetch a single page of commits, returning the url of the next (older) page if there is one
+func (r *RepositoryRequester) GetRepositoryCommitsPage(ctx context.Context, pageURL string) (*[]dto.CommitResponseDTO, string, error) {
+	var commits []dto.CommitResponseDTO
+	nextURL, err := r.fetchPage(ctx, pageURL, &commits)
+	if err != nil {
+		return nil, "", err
+	}
+	return &commits, nextURL, nil
+}
+
+func (r *RepositoryRequester) GetAllUserRepositories(ctx context.Context, owner string) (*[]dto.RepositoryInfoResponseDTO, error) {
+	//  logic to fetch all repositories for a user across all pages
+	url := fmt.Sprintf("%s/users/%s/repos", r.baseURL, owner)
+	return fetchAllPages[dto.RepositoryInfoResponseDTO](ctx, r, url)
+}
+
+func (r *RepositoryRequester) StreamUserRepositories(ctx context.Context, owner string, handler func(page *[]dto.RepositoryInfoResponseDTO) error) error {
+	url := fmt.Sprintf("%s/users/%s/repos", r.baseURL, owner)
+	return fetchPages(ctx, r, url, handler)
 }
@@ -0,0 +1,287 @@
+package requester_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/requester"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// serve three pages of repositories linked together with Link headers
+func newPaginatedServer(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s/users/testuser/repos?per_page=2&page=%d>; rel="next", <%s/users/testuser/repos?per_page=2&page=3>; rel="last"`, server.URL, page+1, server.URL))
+		}
+		repos := []dto.RepositoryInfoResponseDTO{
+			{ID: page*10 + 1, Name: fmt.Sprintf("repo-%d-1", page)},
+			{ID: page*10 + 2, Name: fmt.Sprintf("repo-%d-2", page)},
+		}
+		json.NewEncoder(w).Encode(repos)
+	}))
+	return server
+}
+
+func TestGetAllUserRepositories_FollowsLinkHeader(t *testing.T) {
+	server := newPaginatedServer(t)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubPerPage: 2}, nil)
+	repos, err := repoRequester.GetAllUserRepositories(context.Background(), "testuser")
+
+	assert.NoError(t, err)
+	assert.Len(t, *repos, 6)
+	assert.Equal(t, "repo-3-2", (*repos)[5].Name)
+}
+
+func TestGetAllUserRepositories_RespectsMaxPages(t *testing.T) {
+	server := newPaginatedServer(t)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubPerPage: 2, GithubMaxPages: 2}, nil)
+	repos, err := repoRequester.GetAllUserRepositories(context.Background(), "testuser")
+
+	assert.NoError(t, err)
+	assert.Len(t, *repos, 4)
+}
+
+func TestStreamUserRepositories_HandsOverEachPage(t *testing.T) {
+	server := newPaginatedServer(t)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubPerPage: 2}, nil)
+	pageSizes := []int{}
+	err := repoRequester.StreamUserRepositories(context.Background(), "testuser", func(page *[]dto.RepositoryInfoResponseDTO) error {
+		pageSizes = append(pageSizes, len(*page))
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 2, 2}, pageSizes)
+}
+
+func TestStreamUserRepositories_StopsOnHandlerError(t *testing.T) {
+	server := newPaginatedServer(t)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubPerPage: 2}, nil)
+	pagesSeen := 0
+	err := repoRequester.StreamUserRepositories(context.Background(), "testuser", func(page *[]dto.RepositoryInfoResponseDTO) error {
+		pagesSeen++
+		return assert.AnError
+	})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 1, pagesSeen)
+}
+
+func TestGetRepositoryCommits_SendsSinceAndSHA(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/repos/testuser/testrepo/commits", r.URL.Path)
+		assert.Equal(t, "2024-07-01T12:00:00Z", r.URL.Query().Get("since"))
+		assert.Equal(t, "main", r.URL.Query().Get("sha"))
+		w.Write([]byte(`[{"sha": "abc", "commit": {"message": "msg", "author": {"name": "author", "date": "2024-07-02T00:00:00Z"}}}]`))
+	}))
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, nil)
+	commits, err := repoRequester.GetRepositoryCommits(context.Background(), "testuser", "testrepo", &requester.CommitsQuery{
+		Since: time.Date(2024, 7, 1, 12, 0, 0, 0, time.UTC),
+		SHA:   "main",
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, *commits, 1)
+	assert.Equal(t, "abc", (*commits)[0].SHA)
+}
+
+func TestGetRepositoryInfo_ReportsStatusErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/repos/testuser/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, nil)
+
+	_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "missing")
+	assert.ErrorIs(t, err, utils.ErrRepoNotFound)
+
+	_, _, err = repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+	var statusErr *utils.HTTPStatusError
+	assert.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+}
+
+func TestRequester_BoundsConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		json.NewEncoder(w).Encode(dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"})
+	}))
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubMaxConcurrentRequests: 2}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// distinct repositories, as identical requests would be coalesced
+			_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", fmt.Sprintf("testrepo-%d", i))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 2, maxInFlight)
+}
+
+func TestRequester_CoalescesConcurrentIdenticalRequests(t *testing.T) {
+	var requests atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"})
+	}))
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+			if assert.NoError(t, err) {
+				assert.Equal(t, "testrepo", repo.Name)
+			}
+		}()
+	}
+	// give every caller time to join the request in flight
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestRequester_StopsWaitingOnASharedRequestWhenContextIsDone(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		json.NewEncoder(w).Encode(dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"})
+	}))
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, nil)
+	first := make(chan error, 1)
+	go func() {
+		_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+		first <- err
+	}()
+	// give the first caller time to put its request in flight
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	joined := make(chan error, 1)
+	go func() {
+		_, _, err := repoRequester.GetRepositoryInfo(ctx, "testuser", "testrepo")
+		joined <- err
+	}()
+	select {
+	case err := <-joined:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(time.Second):
+		t.Fatal("caller kept waiting on the shared request after its context expired")
+	}
+
+	// the caller that made the request still gets its answer
+	close(release)
+	assert.NoError(t, <-first)
+}
+
+func TestRequester_CachesNotFound(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubNotFoundTTL: 50 * time.Millisecond}, nil)
+	for i := 0; i < 3; i++ {
+		_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "missing")
+		assert.ErrorIs(t, err, utils.ErrRepoNotFound)
+	}
+	assert.Equal(t, int32(1), requests.Load())
+
+	// github is asked again once the ttl runs out
+	time.Sleep(60 * time.Millisecond)
+	_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "missing")
+	assert.ErrorIs(t, err, utils.ErrRepoNotFound)
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestRequester_GivesUpWhenContextIsDone(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubMaxConcurrentRequests: 1}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// one request hangs on github while holding the only slot, the other waits for the slot
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, _, err := repoRequester.GetRepositoryInfo(ctx, "testuser", "testrepo")
+			errs <- err
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			assert.ErrorIs(t, err, context.DeadlineExceeded)
+		case <-time.After(time.Second):
+			t.Fatal("request did not give up when its context expired")
+		}
+	}
+}
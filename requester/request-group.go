@@ -0,0 +1,60 @@
+package requester
+
+import (
+	"context"
+	"sync"
+
+	"github.com/midedickson/github-service/models"
+)
+
+// a response body read in full so it can be handed to every caller waiting on the same request
+type fetchedPage struct {
+	body []byte
+	next string
+	// the response's validators, when they were asked for and github sent any
+	validators *models.HTTPCacheEntry
+}
+
+// coalesces concurrent identical requests: the first caller makes the request and every caller
+// that asks for the same key while it is in flight gets its result
+type requestGroup struct {
+	mu       sync.Mutex
+	inFlight map[string]*pendingRequest
+}
+
+type pendingRequest struct {
+	done chan struct{}
+	page *fetchedPage
+	err  error
+}
+
+func newRequestGroup() *requestGroup {
+	return &requestGroup{inFlight: map[string]*pendingRequest{}}
+}
+
+// a caller that joins a request in flight stops waiting for it once its own ctx is done; the
+// request carries on for the caller that made it
+func (g *requestGroup) do(ctx context.Context, key string, fetch func() (*fetchedPage, error)) (*fetchedPage, error) {
+	g.mu.Lock()
+	if pending, ok := g.inFlight[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-pending.done:
+			return pending.page, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := &pendingRequest{done: make(chan struct{})}
+	g.inFlight[key] = pending
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.inFlight, key)
+		g.mu.Unlock()
+		close(pending.done)
+	}()
+	pending.page, pending.err = fetch()
+	return pending.page, pending.err
+}
@@ -0,0 +1,106 @@
+package requester
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rate limit state tracked for a single token; an empty token means anonymous access
+type tokenState struct {
+	// position of the token in the pool, used to identify it in logs without leaking it
+	index     int
+	token     string
+	limit     int
+	remaining int
+	reset     time.Time
+}
+
+type tokenPool struct {
+	mu     sync.Mutex
+	tokens []*tokenState
+}
+
+func newTokenPool(tokens []string) *tokenPool {
+	pool := &tokenPool{}
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		// remaining is unknown until the first response, so treat it as unused
+		pool.tokens = append(pool.tokens, &tokenState{index: len(pool.tokens) + 1, token: token, remaining: -1})
+	}
+	if len(pool.tokens) == 0 {
+		pool.tokens = append(pool.tokens, &tokenState{index: 0, remaining: -1})
+	}
+	return pool
+}
+
+func (p *tokenPool) size() int {
+	return len(p.tokens)
+}
+
+// pick the token with the most remaining quota; when every token is exhausted,
+// return the one that resets first along with how long to wait for it
+func (p *tokenPool) acquire() (*tokenState, time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	var best, earliest *tokenState
+	for _, state := range p.tokens {
+		if state.remaining != 0 || !now.Before(state.reset) {
+			if best == nil || state.available(now) > best.available(now) {
+				best = state
+			}
+			continue
+		}
+		if earliest == nil || state.reset.Before(earliest.reset) {
+			earliest = state
+		}
+	}
+	if best != nil {
+		return best, 0
+	}
+	return earliest, time.Until(earliest.reset)
+}
+
+// remaining quota of the token, accounting for windows that have already reset
+func (s *tokenState) available(now time.Time) int {
+	if s.remaining < 0 || (!s.reset.IsZero() && !now.Before(s.reset)) {
+		if s.limit > 0 {
+			return s.limit
+		}
+		// unknown quota; prefer it over tokens we know are running low
+		return int(^uint(0) >> 1)
+	}
+	return s.remaining
+}
+
+// record the x-ratelimit-* headers of a response against the token that made it
+func (p *tokenPool) update(state *tokenState, resp *http.Response) (int, int, time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if limit := resp.Header.Get("x-ratelimit-limit"); limit != "" {
+		state.limit, _ = strconv.Atoi(limit)
+	}
+	if remaining := resp.Header.Get("x-ratelimit-remaining"); remaining != "" {
+		state.remaining, _ = strconv.Atoi(remaining)
+	}
+	if reset := resp.Header.Get("x-ratelimit-reset"); reset != "" {
+		resetTime, _ := strconv.ParseInt(reset, 10, 64)
+		state.reset = time.Unix(resetTime, 0)
+	}
+	return state.limit, state.remaining, state.reset
+}
+
+// mark a token as exhausted after github rejected a request for exceeding its quota
+func (p *tokenPool) exhaust(state *tokenState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	state.remaining = 0
+	if !state.reset.After(time.Now()) {
+		// without a reset header, back off for a minute as github suggests for secondary limits
+		state.reset = time.Now().Add(time.Minute)
+	}
+}
@@ -0,0 +1,97 @@
+package requester_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/requester"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// fake github that gives each token its own quota and reports it in the rate limit headers
+func newRateLimitedServer(quota map[string]int, seen *[]string) *httptest.Server {
+	var mu sync.Mutex
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		auth := r.Header.Get("Authorization")
+		*seen = append(*seen, auth)
+		w.Header().Set("x-ratelimit-limit", "5000")
+		w.Header().Set("x-ratelimit-reset", fmt.Sprint(time.Now().Add(time.Hour).Unix()))
+		if quota[auth] <= 0 {
+			w.Header().Set("x-ratelimit-remaining", "0")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		quota[auth]--
+		w.Header().Set("x-ratelimit-remaining", fmt.Sprint(quota[auth]))
+		json.NewEncoder(w).Encode(dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"})
+	}))
+}
+
+func TestDoRequest_RotatesToTokenWithMostRemainingQuota(t *testing.T) {
+	seen := []string{}
+	server := newRateLimitedServer(map[string]int{"Bearer first": 1, "Bearer second": 10}, &seen)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubTokens: []string{"first", "second"}}, nil)
+	for i := 0; i < 3; i++ {
+		_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+		assert.NoError(t, err)
+	}
+
+	// the first request has no quota information yet; afterwards the fuller token is preferred
+	assert.Equal(t, []string{"Bearer first", "Bearer second", "Bearer second"}, seen)
+}
+
+func TestDoRequest_RotatesWhenTokenIsRateLimited(t *testing.T) {
+	seen := []string{}
+	server := newRateLimitedServer(map[string]int{"Bearer first": 0, "Bearer second": 10}, &seen)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubTokens: []string{"first", "second"}}, nil)
+	repo, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "testrepo", repo.Name)
+	assert.Equal(t, []string{"Bearer first", "Bearer second"}, seen)
+}
+
+func TestDoRequest_ReturnsRateLimitErrorWhenAllTokensAreExhausted(t *testing.T) {
+	seen := []string{}
+	server := newRateLimitedServer(map[string]int{"Bearer first": 0, "Bearer second": 0}, &seen)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL, GithubTokens: []string{"first", "second"}}, nil)
+	_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+
+	var rateLimitErr *utils.RateLimitError
+	assert.ErrorAs(t, err, &rateLimitErr)
+	assert.True(t, rateLimitErr.Reset.After(time.Now().Add(50*time.Minute)))
+
+	// the pool knows both tokens are exhausted, so the next request doesn't reach github at all
+	_, _, err = repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+	assert.ErrorAs(t, err, &rateLimitErr)
+	assert.Len(t, seen, 2)
+}
+
+func TestDoRequest_AnonymousWithoutTokens(t *testing.T) {
+	seen := []string{}
+	server := newRateLimitedServer(map[string]int{"": 10}, &seen)
+	defer server.Close()
+
+	repoRequester := requester.NewRepositoryRequester(&config.Config{GithubAPIURL: server.URL}, nil)
+	_, _, err := repoRequester.GetRepositoryInfo(context.Background(), "testuser", "testrepo")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{""}, seen)
+}
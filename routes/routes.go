@@ -7,7 +7,33 @@ import (
 
 func ConnectRoutes(r *mux.Router, controller *controllers.Controller) {
 	r.HandleFunc("/register", controller.CreateUser).Methods("POST")
+	r.HandleFunc("/jobs/stats", controller.GetQueueStats).Methods("GET")
+	r.HandleFunc("/jobs/{id}", controller.GetJob).Methods("GET")
+	r.HandleFunc("/authors/top", controller.GetTopAuthors).Methods("GET")
+	r.HandleFunc("/search", controller.Search).Methods("GET")
+	r.HandleFunc("/repos/trending", controller.GetTrendingRepositories).Methods("GET")
+	r.HandleFunc("/events/stream", controller.StreamEvents).Methods("GET")
+	r.HandleFunc("/hooks/github", controller.ReceiveGithubHook).Methods("POST")
+	r.HandleFunc("/webhooks", controller.CreateWebhook).Methods("POST")
+	r.HandleFunc("/webhooks", controller.GetWebhooks).Methods("GET")
+	r.HandleFunc("/webhooks/{id}", controller.GetWebhook).Methods("GET")
+	r.HandleFunc("/webhooks/{id}", controller.DeleteWebhook).Methods("DELETE")
+	r.HandleFunc("/webhooks/{id}/deliveries", controller.GetWebhookDeliveries).Methods("GET")
+	r.HandleFunc("/webhooks/{id}/deliveries/{deliveryID}", controller.GetWebhookDelivery).Methods("GET")
+	r.HandleFunc("/webhooks/{id}/deliveries/{deliveryID}/redeliver", controller.RedeliverWebhook).Methods("POST")
+	r.HandleFunc("/dead-letters", controller.GetDeadLetters).Methods("GET")
+	r.HandleFunc("/dead-letters/{id}", controller.GetDeadLetter).Methods("GET")
+	r.HandleFunc("/dead-letters/{id}/requeue", controller.RequeueDeadLetter).Methods("POST")
 	r.HandleFunc("/{owner}/repos", controller.GetRepositories).Methods("GET")
+	r.HandleFunc("/{owner}/sync", controller.SyncUser).Methods("POST")
+	r.HandleFunc("/{owner}/sync-status", controller.GetSyncStatus).Methods("GET")
 	r.HandleFunc("/{owner}/repos/{repo}", controller.GetRepositoryInfo).Methods("GET")
 	r.HandleFunc("/{owner}/repos/{repo}/commits", controller.GetRepositoryCommits).Methods("GET")
+	r.HandleFunc("/{owner}/repos/{repo}/commits/{sha}", controller.GetRepositoryCommit).Methods("GET")
+	r.HandleFunc("/{owner}/repos/{repo}/authors", controller.GetRepositoryTopAuthors).Methods("GET")
+	r.HandleFunc("/{owner}/repos/{repo}/history", controller.GetRepositoryHistory).Methods("GET")
+	r.HandleFunc("/{owner}/repos/{repo}/events", controller.GetRepositoryEvents).Methods("GET")
+	r.HandleFunc("/{owner}/repos/{repo}/sync", controller.SyncRepository).Methods("POST")
+	r.HandleFunc("/{owner}/repos/{repo}/sync-settings", controller.UpdateRepositorySyncSettings).Methods("PUT")
+	r.HandleFunc("/{owner}/repos/{repo}/backfill", controller.BackfillRepositoryCommits).Methods("POST")
 }
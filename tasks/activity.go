@@ -0,0 +1,54 @@
+package tasks
+
+import (
+	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/eventbus"
+	"github.com/midedickson/github-service/models"
+)
+
+// subscribers read events after the worker has moved on, so they get copies of what the worker
+// keeps changing rather than the worker's own structs
+func repositoryCopy(repo *models.Repository) *models.Repository {
+	snapshot := *repo
+	// the owner is named on the event itself
+	snapshot.Owner = nil
+	return &snapshot
+}
+
+// publish the discovery of a repository that was just stored, or what changed about it
+func (t *AsyncTask) publishRepositoryChanges(owner *models.User, repo *models.Repository) {
+	if len(repo.Changes) == 0 {
+		return
+	}
+	data := &dto.RepositoryActivityDTO{RepositoryName: repo.Name, Repository: repositoryCopy(repo)}
+	eventType := eventbus.EventRepositoryUpdated
+	if repo.Changes[0].Type == models.RepositoryEventDiscovered {
+		eventType = eventbus.EventRepositoryDiscovered
+	} else {
+		data.Changes = repo.Changes
+	}
+	t.eventBus.Publish(eventbus.Event{Type: eventType, Owner: owner.Username, Data: data})
+}
+
+// publish the commits of a repository that a sync or backfill stored for the first time
+func (t *AsyncTask) publishNewCommits(owner *models.User, repo *models.Repository, commits []*models.Commit) {
+	if len(commits) == 0 {
+		return
+	}
+	t.eventBus.Publish(eventbus.Event{
+		Type: eventbus.EventCommitCreated, Owner: owner.Username,
+		Data: &dto.RepositoryActivityDTO{RepositoryName: repo.Name, Repository: repositoryCopy(repo), Commits: commits},
+	})
+}
+
+// publish a failed attempt at a job; retryAt is ignored when the job went to the dead letters
+func (t *AsyncTask) publishJobFailure(job *models.Job, jobErr error, retryAt time.Time, deadLetterReason string) {
+	snapshot := *job
+	data := &dto.JobFailureDTO{Job: &snapshot, Error: jobErr.Error(), DeadLetterReason: deadLetterReason}
+	if deadLetterReason == "" {
+		data.RetryAt = &retryAt
+	}
+	t.eventBus.Publish(eventbus.Event{Type: eventbus.EventJobFailed, Owner: job.Owner, Data: data})
+}
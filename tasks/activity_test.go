@@ -0,0 +1,80 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/eventbus"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func receiveEvent(t *testing.T, sub *eventbus.Subscription) eventbus.Event {
+	select {
+	case event := <-sub.Events():
+		return event
+	default:
+		t.Fatal("no event was published")
+		return eventbus.Event{}
+	}
+}
+
+func TestPublishRepositoryChanges(t *testing.T) {
+	bus := eventbus.New(10)
+	sub := bus.Subscribe("alice")
+	task := NewAsyncTask(new(mocks.MockRequester), new(mocks.MockDBRepository), &config.Config{}, bus)
+	alice := &models.User{Username: "alice"}
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, Name: "api", Owner: alice}
+
+	// storing a repository that didn't change publishes nothing
+	task.publishRepositoryChanges(alice, repo)
+	assert.Empty(t, sub.Events())
+
+	repo.Changes = []*models.RepositoryEvent{{Type: models.RepositoryEventDiscovered}}
+	task.publishRepositoryChanges(alice, repo)
+	event := receiveEvent(t, sub)
+	assert.Equal(t, eventbus.EventRepositoryDiscovered, event.Type)
+	assert.Equal(t, "alice", event.Owner)
+
+	repo.Changes = []*models.RepositoryEvent{{Type: models.RepositoryEventRenamed, OldValue: "api", NewValue: "api-v2"}}
+	task.publishRepositoryChanges(alice, repo)
+	event = receiveEvent(t, sub)
+	assert.Equal(t, eventbus.EventRepositoryUpdated, event.Type)
+	data := event.Data.(*dto.RepositoryActivityDTO)
+	assert.Equal(t, repo.Changes, data.Changes)
+	// the event gets its own copy of the repository
+	repo.Name = "api-v2"
+	assert.Equal(t, "api", data.Repository.Name)
+}
+
+func TestFailJob_PublishesTheFailure(t *testing.T) {
+	bus := eventbus.New(10)
+	sub := bus.Subscribe("")
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{}, bus)
+	job := &models.Job{Model: gorm.Model{ID: 1}, Type: models.JobTypeFetchRepo, Owner: "alice", Attempts: 1}
+	mockDBRepository.On("RetryJob", job, "connection reset", mock.Anything).Return(nil).Once()
+	mockDBRepository.On("FailJob", job, models.DeadLetterReasonPermanent, "repository not found").Return(nil).Once()
+
+	task.failJob(context.Background(), job, errors.New("connection reset"))
+	event := receiveEvent(t, sub)
+	assert.Equal(t, eventbus.EventJobFailed, event.Type)
+	assert.Equal(t, "alice", event.Owner)
+	failure := event.Data.(*dto.JobFailureDTO)
+	assert.Equal(t, "connection reset", failure.Error)
+	require.NotNil(t, failure.RetryAt)
+	assert.Empty(t, failure.DeadLetterReason)
+
+	task.failJob(context.Background(), job, permanent(errors.New("repository not found")))
+	failure = receiveEvent(t, sub).Data.(*dto.JobFailureDTO)
+	assert.Nil(t, failure.RetryAt)
+	assert.Equal(t, models.DeadLetterReasonPermanent, failure.DeadLetterReason)
+	mockDBRepository.AssertExpectations(t)
+}
@@ -1,25 +1,108 @@
 package tasks
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/midedickson/github-service/config"
 	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/eventbus"
 	"github.com/midedickson/github-service/models"
 	"github.com/midedickson/github-service/requester"
 )
 
+type jobHandler func(ctx context.Context, job *models.Job) error
+
 type AsyncTask struct {
-	GetAllRepoForUserQueue       chan *models.User
-	FetchNewlyRequestedRepoQueue chan *RepoRequest
-	CheckForUpdateOnAllRepoQueue chan string
-	requester                    requester.Requester
-	dbRepository                 database.DBRepository
+	requester       requester.Requester
+	dbRepository    database.DBRepository
+	commitSyncSince time.Time
+	// identifies this process as the holder of job leases
+	workerID        string
+	pollInterval    time.Duration
+	leaseDuration   time.Duration
+	refreshInterval time.Duration
+	minRefresh      time.Duration
+	maxRefresh      time.Duration
+	schedules       map[string]Schedule
+	poolSize        int
+	poolSizes       map[string]int
+	queueLimit      int64
+	// how long a repository github couldn't find is answered as not found without fetching it again
+	notFoundTTL time.Duration
+	// sends webhook deliveries; github requests go through the requester instead
+	webhookClient *http.Client
+	// where the workers announce what they stored and which jobs failed, for the event stream
+	eventBus  *eventbus.Bus
+	handlers  map[string]jobHandler
+	recurring map[string]recurringJob
+	// context of running jobs; it outlives the one that stops the workers so jobs get to finish
+	// during shutdown, and is only cancelled once the shutdown deadline has passed
+	jobCtx     context.Context
+	cancelJobs context.CancelFunc
+
+	// jobs turned away because their queue was full, by job type, since this process started
+	rejectedMu sync.Mutex
+	rejected   map[string]int64
+}
+
+func NewAsyncTask(requester requester.Requester, dbRepository database.DBRepository, cfg *config.Config, eventBus *eventbus.Bus) *AsyncTask {
+	hostname, _ := os.Hostname()
+	t := &AsyncTask{
+		requester:       requester,
+		dbRepository:    dbRepository,
+		commitSyncSince: cfg.CommitSyncSince,
+		workerID:        fmt.Sprintf("%s-%d-%d", hostname, os.Getpid(), time.Now().UnixNano()),
+		pollInterval:    cfg.JobPollInterval,
+		leaseDuration:   cfg.JobLeaseDuration,
+		refreshInterval: cfg.RepoRefreshInterval,
+		minRefresh:      cfg.RepoRefreshMinInterval,
+		maxRefresh:      cfg.RepoRefreshMaxInterval,
+		schedules:       loadSchedules(cfg.JobSchedules),
+		poolSize:        cfg.WorkerPoolSize,
+		poolSizes:       cfg.WorkerPoolSizes,
+		queueLimit:      int64(cfg.JobQueueLimit),
+		notFoundTTL:     cfg.GithubNotFoundTTL,
+		webhookClient:   &http.Client{Timeout: cfg.WebhookTimeout},
+		eventBus:        eventBus,
+		rejected:        map[string]int64{},
+	}
+	t.jobCtx, t.cancelJobs = context.WithCancel(context.Background())
+	t.handlers = map[string]jobHandler{
+		models.JobTypeFetchUserRepos:  t.GetAllRepoForUser,
+		models.JobTypeFetchRepo:       t.FetchNewlyRequestedRepo,
+		models.JobTypeRefreshRepo:     t.RefreshRepository,
+		models.JobTypeSyncCommits:     t.SyncRepositoryCommits,
+		models.JobTypeBackfillCommits: t.BackfillRepositoryCommits,
+		models.JobTypeDeliverWebhook:  t.DeliverWebhook,
+	}
+	t.recurring = map[string]recurringJob{
+		models.JobTypeRefreshRepo: t.scheduleRepositoryRefreshes,
+	}
+	return t
 }
 
-func NewAsyncTask(requester requester.Requester, dbRepository database.DBRepository) *AsyncTask {
-	return &AsyncTask{
-		GetAllRepoForUserQueue:       make(chan *models.User),
-		FetchNewlyRequestedRepoQueue: make(chan *RepoRequest),
-		CheckForUpdateOnAllRepoQueue: make(chan string),
-		requester:                    requester,
-		dbRepository:                 dbRepository,
+// wait for the workers, which stop claiming jobs once the context they were started with is
+// cancelled, to finish their current jobs. jobs still running when ctx expires are interrupted
+// and handed back to the queue, so the next start picks them up again
+func (t *AsyncTask) Shutdown(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
 	}
+	log.Println("Shutdown deadline reached; interrupting running jobs")
+	t.cancelJobs()
+	<-done
+	return ctx.Err()
 }
@@ -0,0 +1,96 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/requester"
+)
+
+// the date commit syncs start from: the repository's own setting, falling back to the global one
+func (t *AsyncTask) syncStartDate(repo *models.Repository) time.Time {
+	if repo.SyncCommitsSince != nil {
+		return *repo.SyncCommitsSince
+	}
+	return t.commitSyncSince
+}
+
+func (t *AsyncTask) BackfillRepositoryCommits(ctx context.Context, job *models.Job) error {
+	//  logic to walk the full commit history of a repository, newest page first
+	var request BackfillRequest
+	if err := decodePayload(job, &request); err != nil {
+		return err
+	}
+	backfill, err := t.dbRepository.GetCommitBackfill(ctx, request.BackfillID)
+	if err != nil {
+		return err
+	}
+	if backfill == nil || backfill.Repository == nil {
+		return fmt.Errorf("backfill %d not found", request.BackfillID)
+	}
+
+	err = t.backfillRepositoryCommits(ctx, job, backfill)
+	if err != nil && ctx.Err() != nil {
+		// interrupted by shutdown; the checkpoint lets the released job resume where this one stopped
+		backfill.Status = models.BackfillStatusPending
+	} else if err != nil {
+		backfill.Status = models.BackfillStatusFailed
+		backfill.LastError = err.Error()
+	} else {
+		log.Printf("Backfilled %d commits for repo: %s", backfill.CommitsFetched, backfill.Repository.Name)
+		backfill.Status = models.BackfillStatusCompleted
+		backfill.LastError = ""
+	}
+	if updateErr := t.dbRepository.UpdateCommitBackfill(context.WithoutCancel(ctx), backfill); updateErr != nil {
+		log.Printf("Error in saving backfill status: %v", updateErr)
+	}
+	return err
+}
+
+func (t *AsyncTask) backfillRepositoryCommits(ctx context.Context, job *models.Job, backfill *models.CommitBackfill) error {
+	repo := backfill.Repository
+	pageURL := backfill.NextPageURL
+	if pageURL == "" {
+		// fresh backfill; github lists commits newest first, so following the next links walks back in time
+		query := &requester.CommitsQuery{SHA: repo.DefaultBranch}
+		if backfill.Since != nil {
+			query.Since = *backfill.Since
+		}
+		pageURL = t.requester.RepositoryCommitsURL(repo.Owner.Username, repo.Name, query)
+	}
+	backfill.Status = models.BackfillStatusRunning
+	for pageURL != "" {
+		commits, nextURL, err := t.requester.GetRepositoryCommitsPage(ctx, pageURL)
+		if err != nil {
+			return err
+		}
+		newCommits, err := t.dbRepository.StoreRepositoryCommits(ctx, commits, repo)
+		if err != nil {
+			return err
+		}
+		t.publishNewCommits(repo.Owner, repo, newCommits)
+		if backfill.PagesFetched == 0 {
+			// the first page holds the newest commits, which incremental syncs can start after
+			if latestCommitAt := latestCommitDate(commits); !latestCommitAt.IsZero() {
+				if err := t.dbRepository.UpdateRepositoryLatestCommitAt(ctx, repo, latestCommitAt); err != nil {
+					return err
+				}
+			}
+		}
+		// checkpoint after every page so a restart resumes from the next one
+		backfill.PagesFetched++
+		backfill.CommitsFetched += len(*commits)
+		backfill.NextPageURL = nextURL
+		if err := t.dbRepository.UpdateCommitBackfill(ctx, backfill); err != nil {
+			return err
+		}
+		if err := t.recordProgress(ctx, job, len(newCommits)); err != nil {
+			return err
+		}
+		pageURL = nextURL
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/midedickson/github-service/models"
+)
+
+// payload of fetch-user-repos jobs
+type UserRequest struct {
+	Username string `json:"username"`
+	// resync the commits of every repository from the start instead of from the latest stored one
+	Full bool `json:"full,omitempty"`
+}
+
+// payload of fetch-repo jobs
+type RepoRequest struct {
+	Username string `json:"username"`
+	RepoName string `json:"repo_name"`
+}
+
+// payload of refresh-repo and sync-commits jobs
+type RepositoryRequest struct {
+	RepositoryID uint `json:"repository_id"`
+	// fetch the repository even if github reports it unchanged; set by manual syncs
+	Force bool `json:"force,omitempty"`
+	// resync commits from the start instead of from the latest stored one
+	Full bool `json:"full,omitempty"`
+}
+
+// payload of backfill-commits jobs
+type BackfillRequest struct {
+	BackfillID uint `json:"backfill_id"`
+}
+
+// payload of deliver-webhook jobs, which the database queues along with the delivery
+type WebhookDeliveryRequest struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+func decodePayload(job *models.Job, payload interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), payload); err != nil {
+		return permanent(fmt.Errorf("invalid payload for %s job %d: %w", job.Type, job.ID, err))
+	}
+	return nil
+}
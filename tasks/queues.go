@@ -1,26 +1,194 @@
 package tasks
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
 )
 
-func (t *AsyncTask) AddUserToGetAllRepoQueue(user *models.User) {
-	t.GetAllRepoForUserQueue <- user
+// returned when a job type already has as many pending jobs as the queue limit allows
+var ErrQueueFull = errors.New("job queue is full")
+
+// like enqueue, but turns the job away with ErrQueueFull when its type already has as many pending
+// jobs as the queue limit allows. the limit is for work requested through the api; the follow-up
+// jobs the workers queue themselves skip it, as failing those would fail work already half done
+func (t *AsyncTask) enqueueLimited(ctx context.Context, jobType, owner, dedupeKey string, payload interface{}, runAt time.Time) (*models.Job, bool, error) {
+	if err := t.checkQueueLimit(ctx, jobType); err != nil {
+		return nil, false, err
+	}
+	return t.enqueue(ctx, jobType, owner, dedupeKey, payload, runAt)
+}
+
+func (t *AsyncTask) checkQueueLimit(ctx context.Context, jobType string) error {
+	if t.queueLimit > 0 {
+		// the count and the insert aren't atomic, so concurrent enqueues may overshoot the limit slightly
+		pending, err := t.dbRepository.CountPendingJobs(ctx, jobType)
+		if err != nil {
+			return err
+		}
+		if pending >= t.queueLimit {
+			t.countRejected(jobType)
+			return ErrQueueFull
+		}
+	}
+	return nil
+}
+
+// queue a job unless a pending or running job has the same dedupe key, in which case that job is
+// returned instead; the bool reports whether a new job was queued. an empty key always queues
+func (t *AsyncTask) enqueue(ctx context.Context, jobType, owner, dedupeKey string, payload interface{}, runAt time.Time) (*models.Job, bool, error) {
+	job, err := newJob(jobType, owner, dedupeKey, payload, runAt)
+	if err != nil {
+		return nil, false, err
+	}
+	if dedupeKey != "" {
+		return t.dbRepository.EnqueueUniqueJob(ctx, job)
+	}
+	if err := t.dbRepository.EnqueueJob(ctx, job); err != nil {
+		return nil, false, err
+	}
+	return job, true, nil
+}
+
+// like enqueue, but only joins a pending job with the same key. work that reads what github has at
+// the time it starts is queued again behind a running job, which may have read it before the change
+// that brought the caller here
+func (t *AsyncTask) enqueueBehindRunning(ctx context.Context, jobType, owner, dedupeKey string, payload interface{}, runAt time.Time) (*models.Job, bool, error) {
+	job, err := newJob(jobType, owner, dedupeKey, payload, runAt)
+	if err != nil {
+		return nil, false, err
+	}
+	return t.dbRepository.EnqueuePendingUniqueJob(ctx, job)
+}
+
+func newJob(jobType, owner, dedupeKey string, payload interface{}, runAt time.Time) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Job{Type: jobType, Owner: owner, Payload: string(data), RunAt: runAt, DedupeKey: dedupeKey}, nil
+}
+
+// identical work is keyed by job type and subject, plus any flags that make it do more, so a
+// request for work that is already queued or running joins that job instead of repeating it
+func dedupeKey(jobType, subject string, flags ...string) string {
+	return strings.Join(append([]string{jobType, subject}, flags...), ":")
+}
+
+// a full sync covers an incremental one but not the other way round, so they are keyed apart
+func fullFlag(full bool) []string {
+	if full {
+		return []string{"full"}
+	}
+	return nil
+}
+
+func (t *AsyncTask) AddUserToGetAllRepoQueue(ctx context.Context, user *models.User) (*models.Job, error) {
+	job, _, err := t.SyncUser(ctx, user, false)
+	return job, err
+}
+
+func (t *AsyncTask) AddRequestToFetchNewlyRequestedRepoQueue(ctx context.Context, username, repoName string) (*models.Job, error) {
+	key := dedupeKey(models.JobTypeFetchRepo, username+"/"+repoName)
+	notFound, err := t.recentlyNotFound(ctx, username, key)
+	if err != nil {
+		return nil, err
+	}
+	if notFound {
+		// another fetch would only fail the same way, and leave another dead letter behind
+		return nil, utils.ErrRepoNotFound
+	}
+	job, queued, err := t.enqueueLimited(ctx, models.JobTypeFetchRepo, username, key, &RepoRequest{Username: username, RepoName: repoName}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if queued {
+		log.Printf("Queued fetch of newly requested repo %s/%s", username, repoName)
+	}
+	return job, nil
+}
+
+// whether the last fetch of a repository failed because github doesn't have it, recently enough
+// that the answer still stands. this is kept in the jobs rather than the requester's cache of
+// 404s, so it holds whichever instance ran the fetch
+func (t *AsyncTask) recentlyNotFound(ctx context.Context, owner, key string) (bool, error) {
+	if t.notFoundTTL <= 0 {
+		return false, nil
+	}
+	last, err := t.dbRepository.GetLatestJobByDedupeKey(ctx, owner, key)
+	if err != nil || last == nil {
+		return false, err
+	}
+	return last.State == models.JobStateFailed && last.FailureReason == models.DeadLetterReasonNotFound &&
+		last.FinishedAt != nil && time.Since(*last.FinishedAt) < t.notFoundTTL, nil
 }
 
-func (t *AsyncTask) AddRequestToFetchNewlyRequestedRepoQueue(username, repoName string) {
-	log.Println("Adding request to fetch newly requested")
+// refetch all of a user's repositories and sync their commits; a sync of the user that is
+// already queued or running is returned instead of queueing another
+func (t *AsyncTask) SyncUser(ctx context.Context, user *models.User, full bool) (*models.Job, bool, error) {
+	key := dedupeKey(models.JobTypeFetchUserRepos, user.Username, fullFlag(full)...)
+	return t.enqueueLimited(ctx, models.JobTypeFetchUserRepos, user.Username, key, &UserRequest{Username: user.Username, Full: full}, time.Now())
+}
+
+// refetch a repository and sync its commits; a sync of the repository that is already queued is
+// returned instead of queueing another. one that is already running may have fetched the
+// repository before whatever prompted this sync, so another is queued behind it
+func (t *AsyncTask) SyncRepository(ctx context.Context, repo *models.Repository, full bool) (*models.Job, bool, error) {
+	// a scheduled check may skip an unmodified repository, so it doesn't stand in for a forced one
+	key := dedupeKey(models.JobTypeRefreshRepo, fmt.Sprint(repo.ID), append([]string{"force"}, fullFlag(full)...)...)
+	if err := t.checkQueueLimit(ctx, models.JobTypeRefreshRepo); err != nil {
+		return nil, false, err
+	}
+	request := &RepositoryRequest{RepositoryID: repo.ID, Force: true, Full: full}
+	return t.enqueueBehindRunning(ctx, models.JobTypeRefreshRepo, repo.Owner.Username, key, request, time.Now())
+}
 
-	t.FetchNewlyRequestedRepoQueue <- &RepoRequest{
-		Username: username,
-		RepoName: repoName,
+// refresh a repository github told us about through one of its webhooks. as long as the hooks
+// keep arriving they keep the repository current, so its scheduled update check is pushed out
+// to the longest interval and polling only catches what the hooks missed
+func (t *AsyncTask) RefreshRepositoryFromHook(ctx context.Context, repo *models.Repository) (*models.Job, error) {
+	job, _, err := t.SyncRepository(ctx, repo, false)
+	if err != nil {
+		return nil, err
+	}
+	fallback := t.maxRefresh
+	if fallback <= 0 {
+		fallback = t.adaptiveRefreshInterval(repo, time.Now())
+	}
+	if _, err := t.dbRepository.RescheduleRepositoryRefresh(ctx, repo, time.Now().Add(fallback)); err != nil {
+		log.Printf("Error in postponing update check for repo %s: %v", repo.Name, err)
 	}
-	log.Println("Added request to fetch newly requested")
+	return job, nil
+}
+
+func (t *AsyncTask) AddRepositoryToBackfillQueue(ctx context.Context, backfill *models.CommitBackfill) (*models.Job, error) {
+	key := dedupeKey(models.JobTypeBackfillCommits, fmt.Sprint(backfill.ID))
+	job, _, err := t.enqueueLimited(ctx, models.JobTypeBackfillCommits, backfill.Repository.Owner.Username, key, &BackfillRequest{BackfillID: backfill.ID}, time.Now())
+	return job, err
+}
+
+func (t *AsyncTask) addRepositoryToRefreshQueue(ctx context.Context, repo *models.Repository, runAt time.Time) error {
+	key := dedupeKey(models.JobTypeRefreshRepo, fmt.Sprint(repo.ID))
+	_, _, err := t.enqueue(ctx, models.JobTypeRefreshRepo, repo.Owner.Username, key, &RepositoryRequest{RepositoryID: repo.ID}, runAt)
+	return err
+}
 
+func (t *AsyncTask) addRepositoryToSyncCommitsQueue(ctx context.Context, owner *models.User, repo *models.Repository, full bool) error {
+	key := dedupeKey(models.JobTypeSyncCommits, fmt.Sprint(repo.ID), fullFlag(full)...)
+	_, _, err := t.enqueueBehindRunning(ctx, models.JobTypeSyncCommits, owner.Username, key, &RepositoryRequest{RepositoryID: repo.ID, Full: full}, time.Now())
+	return err
 }
 
-func (t *AsyncTask) AddSignalToCheckForUpdateOnAllRepoQueue() {
-	t.CheckForUpdateOnAllRepoQueue <- "signal"
+func (t *AsyncTask) countRejected(jobType string) {
+	t.rejectedMu.Lock()
+	defer t.rejectedMu.Unlock()
+	t.rejected[jobType]++
+	log.Printf("Rejected %s job: queue is full (%d rejected so far)", jobType, t.rejected[jobType])
 }
@@ -0,0 +1,136 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestEnqueueRejectsJobsWhenQueueIsFull(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{JobQueueLimit: 2}, nil)
+
+	mockDBRepository.On("CountPendingJobs", models.JobTypeFetchRepo).Return(int64(1), nil).Once()
+	mockDBRepository.On("EnqueueUniqueJob", mock.AnythingOfType("*models.Job")).Return(&models.Job{Model: gorm.Model{ID: 1}, Owner: "testuser"}, true, nil).Once()
+	job, err := task.AddRequestToFetchNewlyRequestedRepoQueue(context.Background(), "testuser", "testrepo")
+	assert.NoError(t, err)
+	assert.Equal(t, "testuser", job.Owner)
+
+	mockDBRepository.On("CountPendingJobs", models.JobTypeFetchRepo).Return(int64(2), nil).Once()
+	_, err = task.AddRequestToFetchNewlyRequestedRepoQueue(context.Background(), "testuser", "testrepo")
+	assert.ErrorIs(t, err, ErrQueueFull)
+
+	mockDBRepository.On("CountActiveJobsByType").Return(map[string]map[string]int64{
+		models.JobTypeFetchRepo: {models.JobStatePending: 2},
+	}, nil)
+	stats, err := task.GetQueueStats(context.Background())
+	assert.NoError(t, err)
+	for _, queue := range stats {
+		if queue.Type == models.JobTypeFetchRepo {
+			assert.Equal(t, int64(2), queue.Pending)
+			assert.Equal(t, int64(1), queue.Rejected)
+			assert.Equal(t, int64(2), queue.Limit)
+		}
+	}
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestAddRequestToFetchNewlyRequestedRepoQueue_RepositoryRecentlyNotFound(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{GithubNotFoundTTL: 5 * time.Minute}, nil)
+	finishedAt := time.Now().Add(-time.Minute)
+	last := &models.Job{
+		Type: models.JobTypeFetchRepo, State: models.JobStateFailed, FailureReason: models.DeadLetterReasonNotFound,
+		LastError: "fetching repository info: " + utils.ErrRepoNotFound.Error(), FinishedAt: &finishedAt,
+	}
+	mockDBRepository.On("GetLatestJobByDedupeKey", "testuser", "fetch-repo:testuser/missing").Return(last, nil)
+
+	_, err := task.AddRequestToFetchNewlyRequestedRepoQueue(context.Background(), "testuser", "missing")
+	assert.ErrorIs(t, err, utils.ErrRepoNotFound)
+	mockDBRepository.AssertNotCalled(t, "EnqueueUniqueJob", mock.Anything)
+
+	// once the answer is older than the ttl the repository is fetched again
+	finishedAt = time.Now().Add(-10 * time.Minute)
+	mockDBRepository.On("EnqueueUniqueJob", mock.AnythingOfType("*models.Job")).Return(&models.Job{Model: gorm.Model{ID: 2}}, true, nil).Once()
+	job, err := task.AddRequestToFetchNewlyRequestedRepoQueue(context.Background(), "testuser", "missing")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2), job.ID)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestFollowUpJobsSkipTheQueueLimit(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{JobQueueLimit: 1}, nil)
+	owner := &models.User{Username: "testuser"}
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, Owner: owner}
+
+	// a worker halfway through a user's repositories can always queue their commit syncs
+	mockDBRepository.On("EnqueuePendingUniqueJob", mock.MatchedBy(func(job *models.Job) bool {
+		return job.Type == models.JobTypeSyncCommits
+	})).Return(&models.Job{Model: gorm.Model{ID: 1}}, true, nil).Once()
+	assert.NoError(t, task.addRepositoryToSyncCommitsQueue(context.Background(), owner, repo, false))
+	mockDBRepository.AssertNotCalled(t, "CountPendingJobs", mock.Anything)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestAddRequestToFetchNewlyRequestedRepoQueue_JoinsTheJobAlreadyQueued(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{}, nil)
+	existing := &models.Job{Model: gorm.Model{ID: 7}, Type: models.JobTypeFetchRepo, Owner: "testuser", State: models.JobStateRunning}
+
+	mockDBRepository.On("EnqueueUniqueJob", mock.MatchedBy(func(job *models.Job) bool {
+		return job.DedupeKey == "fetch-repo:testuser/testrepo"
+	})).Return(existing, false, nil).Twice()
+	for i := 0; i < 2; i++ {
+		job, err := task.AddRequestToFetchNewlyRequestedRepoQueue(context.Background(), "testuser", "testrepo")
+		assert.NoError(t, err)
+		assert.Equal(t, uint(7), job.ID)
+	}
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestRefreshRepositoryFromHook_PostponesPolling(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{RepoRefreshMaxInterval: 24 * time.Hour}, nil)
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, Name: "testrepo", Owner: &models.User{Username: "testuser"}}
+
+	// a push may land while a sync of the repository is running, so only a queued one is joined
+	mockDBRepository.On("EnqueuePendingUniqueJob", mock.MatchedBy(func(job *models.Job) bool {
+		return job.Type == models.JobTypeRefreshRepo && job.DedupeKey == "refresh-repo:4:force"
+	})).Return(&models.Job{Model: gorm.Model{ID: 9}}, true, nil).Once()
+	mockDBRepository.On("RescheduleRepositoryRefresh", repo, mock.MatchedBy(func(next time.Time) bool {
+		return time.Until(next) > 23*time.Hour
+	})).Return(true, nil).Once()
+
+	job, err := task.RefreshRepositoryFromHook(context.Background(), repo)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(9), job.ID)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestDedupeKey(t *testing.T) {
+	assert.Equal(t, "sync-commits:4", dedupeKey(models.JobTypeSyncCommits, "4", fullFlag(false)...))
+	assert.Equal(t, "sync-commits:4:full", dedupeKey(models.JobTypeSyncCommits, "4", fullFlag(true)...))
+	assert.Equal(t, "refresh-repo:4:force:full", dedupeKey(models.JobTypeRefreshRepo, "4", "force", "full"))
+}
+
+func TestWorkerPoolSize(t *testing.T) {
+	task := NewAsyncTask(new(mocks.MockRequester), new(mocks.MockDBRepository), &config.Config{
+		WorkerPoolSize:  2,
+		WorkerPoolSizes: map[string]int{models.JobTypeSyncCommits: 5},
+	}, nil)
+	assert.Equal(t, 5, task.workerPoolSize(models.JobTypeSyncCommits))
+	assert.Equal(t, 2, task.workerPoolSize(models.JobTypeFetchRepo))
+
+	// an unset pool size still gets every job type a worker
+	task = NewAsyncTask(new(mocks.MockRequester), new(mocks.MockDBRepository), &config.Config{}, nil)
+	assert.Equal(t, 1, task.workerPoolSize(models.JobTypeFetchRepo))
+}
@@ -1,6 +0,0 @@
-package tasks
-
-type RepoRequest struct {
-	Username string
-	RepoName string
-}
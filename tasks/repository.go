@@ -1,124 +1,191 @@
 package tasks
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
-	"sync"
 	"time"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/requester"
+	"github.com/midedickson/github-service/utils"
 )
 
-func (t *AsyncTask) GetAllRepoForUser(wg *sync.WaitGroup) {
+func (t *AsyncTask) GetAllRepoForUser(ctx context.Context, job *models.Job) error {
 	//  logic to fetch all repositories for the given user
-	// Use the GetAllRepoForUserQueue channel to send and recieve the user to and from the worker pool
-	defer wg.Done()
-	for user := range t.GetAllRepoForUserQueue {
+	var request UserRequest
+	if err := decodePayload(job, &request); err != nil {
+		return err
+	}
+	user, err := t.dbRepository.GetUser(ctx, request.Username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return permanent(fmt.Errorf("user %s not found", request.Username))
+	}
 
-		// if !ok {
-		// 	log.Println("GetAllRepoForUserQueue channel closed")
-		// 	return
-		// }
+	// fetch the user's repositories page by page, storing each page as it arrives
+	err = t.requester.StreamUserRepositories(ctx, user.Username, func(page *[]dto.RepositoryInfoResponseDTO) error {
+		for _, newRepoInfo := range *page {
+			// storing is idempotent, so a failure here fails the job and the retry starts over
+			repo, err := t.dbRepository.StoreRepositoryInfo(ctx, &newRepoInfo, user)
+			if err != nil {
+				return fmt.Errorf("storing repository %s: %w", newRepoInfo.Name, err)
+			}
+			t.publishRepositoryChanges(user, repo)
+			// commits are synced by their own jobs so users with many repositories don't hold up this worker
+			if err := t.addRepositoryToSyncCommitsQueue(ctx, user, repo, request.Full); err != nil {
+				return fmt.Errorf("queueing commit sync for repo %s: %w", repo.Name, err)
+			}
+		}
+		return t.recordProgress(ctx, job, 0)
+	})
+	if err != nil {
+		return fmt.Errorf("fetching repositories for user %v: %w", user.Username, err)
+	}
+	log.Printf("Gotten repositories for user %v", user.Username)
+	return nil
+}
 
-		// todo: Fetch all repositories for the user
-		userRepositories, err := t.requester.GetAllUserRepositories(user.Username)
+// fetch the commits of a repository page by page and store each page as it arrives;
+// unless full is set only commits newer than the latest one already stored are requested from github
+func (t *AsyncTask) syncRepositoryCommits(ctx context.Context, job *models.Job, user *models.User, repo *models.Repository, full bool) error {
+	query := &requester.CommitsQuery{SHA: repo.DefaultBranch, Since: t.syncStartDate(repo)}
+	if !full && repo.LatestCommitAt != nil && repo.LatestCommitAt.After(query.Since) {
+		query.Since = *repo.LatestCommitAt
+	}
+	var latestCommitAt time.Time
+	err := t.requester.StreamRepositoryCommits(ctx, user.Username, repo.Name, query, func(page *[]dto.CommitResponseDTO) error {
+		if pageLatest := latestCommitDate(page); pageLatest.After(latestCommitAt) {
+			latestCommitAt = pageLatest
+		}
+		newCommits, err := t.dbRepository.StoreRepositoryCommits(ctx, page, repo)
 		if err != nil {
-			log.Printf("Error in fetching repositories for user %v: %v", user.Username, err)
-			continue
+			return err
 		}
-		go func() {
-			// using a go routine to optimize the saving of repositories and fetching the repo  commits
-			// this will help the worker process tasks from the channel faster for users at scale
-			for _, newRepoInfo := range *userRepositories {
-				_, err := t.dbRepository.StoreRepositoryInfo(&newRepoInfo, user)
-				if err != nil {
-					log.Printf("Error in storing repository: %v", err)
-					continue
-				}
-				log.Printf("fetching repository commits for repo: %s...", newRepoInfo.Name)
-				remoteCommits, err := t.requester.GetRepositoryCommits(user.Username, newRepoInfo.Name)
-				if err != nil {
-					log.Printf("Error in fetching commits: %v", err)
-					continue
-				}
-				err = t.dbRepository.StoreRepositoryCommits(remoteCommits, newRepoInfo.Name, user)
-				if err != nil {
-					log.Printf("Error in saving commits: %v", err)
-					continue
-				}
-			}
-			log.Printf("Gotten repositories for user %v", user)
-		}()
-
+		t.publishNewCommits(user, repo, newCommits)
+		return t.recordProgress(ctx, job, len(newCommits))
+	})
+	if err != nil {
+		// github lists the newest commits first, so moving the checkpoint before every page is
+		// stored would make the retry skip the older ones
+		return err
+	}
+	if latestCommitAt.IsZero() {
+		return nil
 	}
+	return t.dbRepository.UpdateRepositoryLatestCommitAt(ctx, repo, latestCommitAt)
+}
 
+// the date of the newest commit in a page; zero when none of the dates parse
+func latestCommitDate(commits *[]dto.CommitResponseDTO) time.Time {
+	var latest time.Time
+	for _, commit := range *commits {
+		if commitDate, err := time.Parse(time.RFC3339, commit.Date); err == nil && commitDate.After(latest) {
+			latest = commitDate
+		}
+	}
+	return latest
 }
 
-func (t *AsyncTask) FetchNewlyRequestedRepo(wg *sync.WaitGroup) {
+func (t *AsyncTask) FetchNewlyRequestedRepo(ctx context.Context, job *models.Job) error {
 	//  logic to fetch a newly requested repo and commits for the given repository
-	defer wg.Done()
-	log.Println("waiting for newly requested repos...")
-
-	for repoRequest := range t.FetchNewlyRequestedRepoQueue {
-		log.Println("checking for newly requested repos...")
-
-		remoteRepoInfo, err := t.requester.GetRepositoryInfo(repoRequest.Username, repoRequest.RepoName)
-		if err != nil {
-			continue
-		}
-		user, _ := t.dbRepository.GetUser(repoRequest.Username)
-		repo, _ := t.dbRepository.StoreRepositoryInfo(remoteRepoInfo, user)
-		go func() {
-			log.Printf("fetching repository commits for repo: %s...", repoRequest.RepoName)
-			remoteCommits, err := t.requester.GetRepositoryCommits(user.Username, repo.Name)
-			if err != nil {
-				log.Printf("Error in fetching commits: %v", err)
-				return
-			}
-			err = t.dbRepository.StoreRepositoryCommits(remoteCommits, repo.Name, user)
-			if err != nil {
-				log.Printf("Error in saving commits: %v", err)
-				return
-			}
-		}()
+	var repoRequest RepoRequest
+	if err := decodePayload(job, &repoRequest); err != nil {
+		return err
 	}
-	log.Println("exiting checking for newly requested repos...")
+	log.Printf("fetching newly requested repo %s/%s...", repoRequest.Username, repoRequest.RepoName)
 
+	remoteRepoInfo, validators, err := t.requester.GetRepositoryInfo(ctx, repoRequest.Username, repoRequest.RepoName)
+	if err != nil {
+		return err
+	}
+	user, err := t.dbRepository.GetUser(ctx, repoRequest.Username)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return permanent(fmt.Errorf("user %s not found", repoRequest.Username))
+	}
+	repo, err := t.storeRepositoryInfo(ctx, remoteRepoInfo, validators, user)
+	if err != nil {
+		return err
+	}
+	t.publishRepositoryChanges(user, repo)
+	return t.addRepositoryToSyncCommitsQueue(ctx, user, repo, false)
 }
 
-func (t *AsyncTask) CheckForUpdateOnAllRepo(wg *sync.WaitGroup) {
-	//  logic to check for updates on all repositories in the database
-	defer wg.Done()
-	for {
-		_, ok := <-t.CheckForUpdateOnAllRepoQueue
-		if !ok {
-			log.Println("No more signal to check for updates on all repositories")
-			return
-		}
-		allRepos, err := t.dbRepository.GetAllRepositories()
-		if err != nil {
-			log.Printf("Error in fetching all repositories: %v", err)
-			return
-		}
-
-		for _, repo := range allRepos {
+func (t *AsyncTask) RefreshRepository(ctx context.Context, job *models.Job) error {
+	//  logic to check a stored repository for updates
+	var request RepositoryRequest
+	repo, err := t.loadRepository(ctx, job, &request)
+	if err != nil || repo == nil {
+		return err
+	}
+	log.Printf("Checking for updates on repo: %s...", repo.Name)
+	var remoteRepoInfo *dto.RepositoryInfoResponseDTO
+	var validators *models.HTTPCacheEntry
+	if request.Force {
+		remoteRepoInfo, validators, err = t.requester.GetRepositoryInfo(ctx, repo.Owner.Username, repo.Name)
+	} else {
+		remoteRepoInfo, validators, err = t.requester.GetRepositoryInfoIfModified(ctx, repo.Owner.Username, repo.Name)
+	}
+	if errors.Is(err, utils.ErrNotModified) {
+		log.Printf("Repo %s has not been modified; skipping", repo.Name)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("fetching repository info: %w", err)
+	}
+	// storing diffs the repository against what github reports, so it only writes, and logs
+	// events, for what actually changed
+	updated, err := t.storeRepositoryInfo(ctx, remoteRepoInfo, validators, repo.Owner)
+	if err != nil {
+		return fmt.Errorf("updating repository: %w", err)
+	}
+	t.publishRepositoryChanges(repo.Owner, updated)
+	// the repository changed since the last check, so pull in any commits pushed since then
+	return t.addRepositoryToSyncCommitsQueue(ctx, repo.Owner, repo, request.Full)
+}
 
-			log.Printf("Checking for updates on repo: %s...", repo.Name)
-			remoteRepoInfo, err := t.requester.GetRepositoryInfo(repo.Owner.Username, repo.Name)
-			if err != nil {
-				log.Printf("Error in fetching repository info: %v", err)
-				continue
-			}
-			if repo.RemoteUpdatedAt != remoteRepoInfo.UpdatedAt {
-				_, err = t.dbRepository.StoreRepositoryInfo(remoteRepoInfo, repo.Owner)
-				if err != nil {
-					log.Println("Error in updating repository")
-				}
-			}
-			// simulate more processing to reduce wasting ratelimit requests
-			time.Sleep(90 * time.Second)
+// store a repository fetched from github, then the validators github answered with. storing
+// them any earlier would have the retry of a failed store answered with a 304, losing the change
+func (t *AsyncTask) storeRepositoryInfo(ctx context.Context, remoteRepoInfo *dto.RepositoryInfoResponseDTO, validators *models.HTTPCacheEntry, owner *models.User) (*models.Repository, error) {
+	repo, err := t.dbRepository.StoreRepositoryInfo(ctx, remoteRepoInfo, owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.requester.StoreValidators(ctx, validators); err != nil {
+		log.Printf("Error in storing cache validators of repo %s: %v", remoteRepoInfo.Name, err)
+	}
+	return repo, nil
+}
 
-		}
-		// trigger the update again after 3days (currently passed as seconds)
-		time.Sleep(3 * time.Second)
-		go t.AddSignalToCheckForUpdateOnAllRepoQueue()
+func (t *AsyncTask) SyncRepositoryCommits(ctx context.Context, job *models.Job) error {
+	var request RepositoryRequest
+	repo, err := t.loadRepository(ctx, job, &request)
+	if err != nil || repo == nil {
+		return err
 	}
+	log.Printf("Syncing new commits for repo: %s...", repo.Name)
+	return t.syncRepositoryCommits(ctx, job, repo.Owner, repo, request.Full)
+}
 
+// decode the job's payload into request and load the repository it refers to; a repository
+// deleted since the job was queued yields nil
+func (t *AsyncTask) loadRepository(ctx context.Context, job *models.Job, request *RepositoryRequest) (*models.Repository, error) {
+	if err := decodePayload(job, request); err != nil {
+		return nil, err
+	}
+	repo, err := t.dbRepository.GetRepositoryByID(ctx, request.RepositoryID)
+	if err != nil {
+		return nil, err
+	}
+	if repo == nil {
+		log.Printf("Repository %d no longer exists; skipping %s job", request.RepositoryID, job.Type)
+	}
+	return repo, nil
 }
@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestRefreshRepository_StoresValidatorsOnlyOnceTheRepositoryIsStored(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	mockRequester := new(mocks.MockRequester)
+	task := NewAsyncTask(mockRequester, mockDBRepository, &config.Config{}, nil)
+	owner := &models.User{Username: "testuser"}
+	repo := &models.Repository{Model: gorm.Model{ID: 4}, Name: "testrepo", Owner: owner}
+	validators := &models.HTTPCacheEntry{URL: "https://api.github.com/repos/testuser/testrepo", ETag: `"v2"`}
+	remoteRepoInfo := &dto.RepositoryInfoResponseDTO{ID: 1, Name: "testrepo"}
+	job := &models.Job{Type: models.JobTypeRefreshRepo, Payload: `{"repository_id": 4}`}
+	mockDBRepository.On("GetRepositoryByID", uint(4)).Return(repo, nil)
+	mockRequester.On("GetRepositoryInfoIfModified", "testuser", "testrepo").Return(remoteRepoInfo, validators, nil)
+
+	// a failed store leaves the old validators in place, so the retry gets the full response again
+	mockDBRepository.On("StoreRepositoryInfo", remoteRepoInfo, owner).Return((*models.Repository)(nil), errors.New("database is locked")).Once()
+	assert.Error(t, task.RefreshRepository(context.Background(), job))
+	mockRequester.AssertNotCalled(t, "StoreValidators", mock.Anything)
+
+	mockDBRepository.On("StoreRepositoryInfo", remoteRepoInfo, owner).Return(repo, nil).Once()
+	mockRequester.On("StoreValidators", validators).Return(nil).Once()
+	mockDBRepository.On("EnqueuePendingUniqueJob", mock.AnythingOfType("*models.Job")).Return(&models.Job{}, true, nil).Once()
+	assert.NoError(t, task.RefreshRepository(context.Background(), job))
+	mockRequester.AssertExpectations(t)
+	mockDBRepository.AssertExpectations(t)
+}
@@ -0,0 +1,125 @@
+package tasks
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// how often and how patiently a job type is retried before it is dead-lettered
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+var defaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute}
+
+var retryPolicies = map[string]RetryPolicy{
+	models.JobTypeFetchUserRepos: defaultRetryPolicy,
+	// someone is polling for the repository, so retry sooner
+	models.JobTypeFetchRepo: {MaxAttempts: 5, BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Minute},
+	// the next refresh cycle checks the repository again anyway
+	models.JobTypeRefreshRepo:     {MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: 15 * time.Minute},
+	models.JobTypeSyncCommits:     defaultRetryPolicy,
+	models.JobTypeBackfillCommits: {MaxAttempts: 8, BaseDelay: time.Minute, MaxDelay: time.Hour},
+	// receivers that are down for a deploy or an outage get about an hour to come back
+	models.JobTypeDeliverWebhook: {MaxAttempts: 8, BaseDelay: 30 * time.Second, MaxDelay: 30 * time.Minute},
+}
+
+func retryPolicyFor(jobType string) RetryPolicy {
+	if policy, ok := retryPolicies[jobType]; ok {
+		return policy
+	}
+	return defaultRetryPolicy
+}
+
+// delay before the next attempt: the base delay doubled for every attempt so far, capped at
+// the max delay, with the upper half randomised so jobs that failed together don't retry together
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// an error that retrying can't fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type errorClass int
+
+const (
+	errorRetryable errorClass = iota
+	errorPermanent
+	errorRateLimited
+)
+
+// decide how a failed job should be handled based on what went wrong
+func classifyError(err error) errorClass {
+	var permanentErr *permanentError
+	var rateLimitErr *utils.RateLimitError
+	var statusErr *utils.HTTPStatusError
+	switch {
+	case errors.As(err, &permanentErr), errors.Is(err, utils.ErrRepoNotFound):
+		return errorPermanent
+	case errors.As(err, &rateLimitErr):
+		return errorRateLimited
+	case errors.As(err, &statusErr):
+		// github's own failures and throttling pass; anything else means the request itself is wrong
+		if statusErr.StatusCode >= 500 || statusErr.StatusCode == 429 {
+			return errorRetryable
+		}
+		return errorPermanent
+	default:
+		// network failures, timeouts and database errors are usually transient
+		return errorRetryable
+	}
+}
+
+// spread the jobs that were waiting on the same rate limit reset over this window
+const rateLimitJitter = 30 * time.Second
+
+// when a failed job should run again; a non-empty dead letter reason means it shouldn't.
+// waiting out a rate limit isn't the job's fault, so those retries aren't bounded by the policy
+func nextAttempt(job *models.Job, err error, now time.Time) (time.Time, string) {
+	policy := retryPolicyFor(job.Type)
+	switch classifyError(err) {
+	case errorPermanent:
+		if errors.Is(err, utils.ErrRepoNotFound) {
+			return time.Time{}, models.DeadLetterReasonNotFound
+		}
+		return time.Time{}, models.DeadLetterReasonPermanent
+	case errorRateLimited:
+		var rateLimitErr *utils.RateLimitError
+		errors.As(err, &rateLimitErr)
+		runAt := rateLimitErr.Reset
+		if runAt.Before(now) {
+			runAt = now
+		}
+		return runAt.Add(time.Duration(rand.Int63n(int64(rateLimitJitter)))), ""
+	}
+	if job.Attempts >= policy.MaxAttempts {
+		return time.Time{}, models.DeadLetterReasonRetriesExhausted
+	}
+	return now.Add(policy.Backoff(job.Attempts)), ""
+}
@@ -0,0 +1,53 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyBackoffGrowsWithJitterUpToMaxDelay(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	for attempts, ceiling := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 8: 10 * time.Second} {
+		for i := 0; i < 20; i++ {
+			delay := policy.Backoff(attempts)
+			assert.GreaterOrEqual(t, delay, ceiling/2)
+			assert.LessOrEqual(t, delay, ceiling)
+		}
+	}
+}
+
+func TestNextAttempt(t *testing.T) {
+	now := time.Now()
+	policy := retryPolicyFor(models.JobTypeSyncCommits)
+	job := &models.Job{Type: models.JobTypeSyncCommits, Attempts: 1}
+
+	runAt, reason := nextAttempt(job, &utils.HTTPStatusError{StatusCode: 502}, now)
+	assert.Empty(t, reason)
+	assert.True(t, runAt.After(now))
+	assert.False(t, runAt.After(now.Add(policy.BaseDelay)))
+
+	// wrapped errors are classified by what they wrap
+	_, reason = nextAttempt(job, fmt.Errorf("fetching repository info: %w", utils.ErrRepoNotFound), now)
+	assert.Equal(t, models.DeadLetterReasonNotFound, reason)
+	_, reason = nextAttempt(job, &utils.HTTPStatusError{StatusCode: 401}, now)
+	assert.Equal(t, models.DeadLetterReasonPermanent, reason)
+	_, reason = nextAttempt(job, permanent(errors.New("bad payload")), now)
+	assert.Equal(t, models.DeadLetterReasonPermanent, reason)
+
+	exhausted := &models.Job{Type: models.JobTypeSyncCommits, Attempts: policy.MaxAttempts}
+	_, reason = nextAttempt(exhausted, errors.New("database is locked"), now)
+	assert.Equal(t, models.DeadLetterReasonRetriesExhausted, reason)
+
+	// rate limits wait for the reset however many attempts have been made
+	reset := now.Add(20 * time.Minute)
+	runAt, reason = nextAttempt(exhausted, &utils.RateLimitError{Reset: reset}, now)
+	assert.Empty(t, reason)
+	assert.False(t, runAt.Before(reset))
+	assert.True(t, runAt.Before(reset.Add(rateLimitJitter)))
+}
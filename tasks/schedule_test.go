@@ -0,0 +1,43 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule_Next(t *testing.T) {
+	// a wednesday
+	after := time.Date(2024, 7, 3, 10, 7, 30, 0, time.UTC)
+	cases := []struct {
+		spec string
+		next time.Time
+	}{
+		{"@every 90s", after.Add(90 * time.Second)},
+		{"*/5 * * * *", time.Date(2024, 7, 3, 10, 10, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 7, 3, 11, 0, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 7, 4, 2, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * *", time.Date(2024, 7, 3, 13, 0, 0, 0, time.UTC)},
+		{"15,45 * * * *", time.Date(2024, 7, 3, 10, 15, 0, 0, time.UTC)},
+		// sunday, written as 7
+		{"0 0 * * 7", time.Date(2024, 7, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// with both day fields restricted either one matching is enough: friday the 5th comes first
+		{"0 0 13 * 5", time.Date(2024, 7, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		schedule, err := ParseSchedule(tc.spec)
+		require.NoError(t, err, tc.spec)
+		assert.Equal(t, tc.next, schedule.Next(after), tc.spec)
+	}
+}
+
+func TestParseSchedule_RejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []string{"", "@every", "@every -1m", "* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "0 0 30 2 *"} {
+		_, err := ParseSchedule(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,159 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/midedickson/github-service/models"
+)
+
+// recurring work run by the scheduler; next is when it runs again, which it may spread its work up to
+type recurringJob func(ctx context.Context, now, next time.Time)
+
+var defaultSchedules = map[string]string{
+	models.JobTypeRefreshRepo: "*/5 * * * *",
+}
+
+// repositories queued for an update check per scheduler run at most; the rest wait for the next run
+const refreshBatchSize = 500
+
+// parse the configured schedules over the defaults; an invalid one keeps the default
+func loadSchedules(specs map[string]string) map[string]Schedule {
+	schedules := map[string]Schedule{}
+	for jobType, spec := range defaultSchedules {
+		schedules[jobType], _ = ParseSchedule(spec)
+	}
+	for jobType, spec := range specs {
+		schedule, err := ParseSchedule(spec)
+		if err != nil {
+			log.Printf("Ignoring schedule for %s jobs: %v", jobType, err)
+			continue
+		}
+		schedules[jobType] = schedule
+	}
+	return schedules
+}
+
+// run every recurring job on its schedule until ctx is cancelled
+func (t *AsyncTask) RunScheduler(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	nextRuns := map[string]time.Time{}
+	for jobType := range t.recurring {
+		schedule, ok := t.schedules[jobType]
+		if !ok {
+			log.Printf("No schedule for recurring %s jobs; they won't run", jobType)
+			continue
+		}
+		nextRuns[jobType] = schedule.Next(time.Now())
+	}
+	for len(nextRuns) > 0 {
+		jobType := earliestRun(nextRuns)
+		if !sleep(ctx, time.Until(nextRuns[jobType])) {
+			break
+		}
+		now := time.Now()
+		following := t.schedules[jobType].Next(now)
+		t.recurring[jobType](ctx, now, following)
+		if following.IsZero() {
+			delete(nextRuns, jobType)
+			continue
+		}
+		nextRuns[jobType] = following
+	}
+	log.Println("exiting scheduler...")
+}
+
+func earliestRun(nextRuns map[string]time.Time) string {
+	var earliest string
+	for jobType, runAt := range nextRuns {
+		if earliest == "" || runAt.Before(nextRuns[earliest]) {
+			earliest = jobType
+		}
+	}
+	return earliest
+}
+
+// queue an update check for every repository that is due, spread evenly until the next run
+func (t *AsyncTask) scheduleRepositoryRefreshes(ctx context.Context, now, next time.Time) {
+	due, err := t.dbRepository.GetRepositoriesDueForRefresh(ctx, now, refreshBatchSize)
+	if err != nil {
+		log.Printf("Error in fetching repositories due for an update check: %v", err)
+		return
+	}
+	if len(due) == 0 {
+		return
+	}
+	slot := next.Sub(now) / time.Duration(len(due))
+	queued := 0
+	for i, repo := range due {
+		scheduled, err := t.dbRepository.RescheduleRepositoryRefresh(ctx, repo, now.Add(t.refreshIntervalFor(repo, now)))
+		if err != nil {
+			log.Printf("Error in scheduling next update check for repo %s: %v", repo.Name, err)
+			continue
+		}
+		if !scheduled {
+			// the scheduler of another instance got to it first
+			continue
+		}
+		runAt := now.Add(time.Duration(i)*slot + jitter(slot))
+		if err := t.addRepositoryToRefreshQueue(ctx, repo, runAt); err != nil {
+			log.Printf("Error in queueing update check for repo %s: %v", repo.Name, err)
+			// leave it due so the next run tries again
+			if _, rescheduleErr := t.dbRepository.RescheduleRepositoryRefresh(ctx, repo, now); rescheduleErr != nil {
+				log.Printf("Error in rescheduling update check for repo %s: %v", repo.Name, rescheduleErr)
+			}
+			if errors.Is(err, ErrQueueFull) {
+				break
+			}
+			continue
+		}
+		queued++
+	}
+	log.Printf("Queued update checks for %d of %d due repositories", queued, len(due))
+}
+
+// how long until a repository is checked again, with up to 10% jitter either way so
+// repositories checked together drift apart and spread out over the rate limit window
+func (t *AsyncTask) refreshIntervalFor(repo *models.Repository, now time.Time) time.Duration {
+	interval := t.adaptiveRefreshInterval(repo, now)
+	spread := interval / 10
+	return interval - spread + jitter(2*spread)
+}
+
+// the base refresh interval scaled by how recently the repository saw a commit, so active
+// repositories are checked more often and dormant ones less, within the min and max intervals
+func (t *AsyncTask) adaptiveRefreshInterval(repo *models.Repository, now time.Time) time.Duration {
+	interval := t.refreshInterval
+	if repo.LatestCommitAt != nil {
+		switch idle := now.Sub(*repo.LatestCommitAt); {
+		case idle < 24*time.Hour:
+			interval /= 4
+		case idle < 7*24*time.Hour:
+			interval /= 2
+		case idle < 30*24*time.Hour:
+		case idle < 180*24*time.Hour:
+			interval *= 4
+		default:
+			interval *= 24
+		}
+	}
+	if t.minRefresh > 0 && interval < t.minRefresh {
+		interval = t.minRefresh
+	}
+	if t.maxRefresh > 0 && interval > t.maxRefresh {
+		interval = t.maxRefresh
+	}
+	return interval
+}
+
+// a random duration in [0, d)
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d)))
+}
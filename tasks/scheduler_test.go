@@ -0,0 +1,72 @@
+package tasks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+func TestAdaptiveRefreshInterval(t *testing.T) {
+	task := NewAsyncTask(new(mocks.MockRequester), new(mocks.MockDBRepository), &config.Config{
+		RepoRefreshInterval:    time.Hour,
+		RepoRefreshMinInterval: 20 * time.Minute,
+		RepoRefreshMaxInterval: 12 * time.Hour,
+	}, nil)
+	now := time.Now()
+	lastCommit := func(ago time.Duration) *models.Repository {
+		latestCommitAt := now.Add(-ago)
+		return &models.Repository{LatestCommitAt: &latestCommitAt}
+	}
+
+	assert.Equal(t, time.Hour, task.adaptiveRefreshInterval(&models.Repository{}, now))
+	// a quarter of the base interval, raised to the minimum
+	assert.Equal(t, 20*time.Minute, task.adaptiveRefreshInterval(lastCommit(time.Hour), now))
+	assert.Equal(t, 30*time.Minute, task.adaptiveRefreshInterval(lastCommit(3*24*time.Hour), now))
+	assert.Equal(t, time.Hour, task.adaptiveRefreshInterval(lastCommit(10*24*time.Hour), now))
+	assert.Equal(t, 4*time.Hour, task.adaptiveRefreshInterval(lastCommit(60*24*time.Hour), now))
+	// a day, capped at the maximum
+	assert.Equal(t, 12*time.Hour, task.adaptiveRefreshInterval(lastCommit(365*24*time.Hour), now))
+
+	interval := task.refreshIntervalFor(&models.Repository{}, now)
+	assert.GreaterOrEqual(t, interval, 54*time.Minute)
+	assert.Less(t, interval, 66*time.Minute)
+}
+
+func TestScheduleRepositoryRefreshes_SpreadsDueRepositoriesUntilTheNextRun(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{RepoRefreshInterval: time.Hour}, nil)
+	owner := &models.User{Username: "testuser"}
+	first := &models.Repository{Model: gorm.Model{ID: 1}, Name: "first", Owner: owner}
+	taken := &models.Repository{Model: gorm.Model{ID: 2}, Name: "taken", Owner: owner}
+	last := &models.Repository{Model: gorm.Model{ID: 3}, Name: "last", Owner: owner}
+	now := time.Now()
+	next := now.Add(10 * time.Minute)
+
+	mockDBRepository.On("GetRepositoriesDueForRefresh", now, refreshBatchSize).Return([]*models.Repository{first, taken, last}, nil)
+	mockDBRepository.On("RescheduleRepositoryRefresh", first, mock.AnythingOfType("time.Time")).Return(true, nil)
+	// another instance already scheduled this one
+	mockDBRepository.On("RescheduleRepositoryRefresh", taken, mock.AnythingOfType("time.Time")).Return(false, nil)
+	mockDBRepository.On("RescheduleRepositoryRefresh", last, mock.AnythingOfType("time.Time")).Return(true, nil)
+	var queued []*models.Job
+	mockDBRepository.On("EnqueueUniqueJob", mock.AnythingOfType("*models.Job")).Run(func(args mock.Arguments) {
+		queued = append(queued, args.Get(0).(*models.Job))
+	}).Return(nil, true, nil)
+
+	task.scheduleRepositoryRefreshes(context.Background(), now, next)
+
+	if assert.Len(t, queued, 2) {
+		assert.Equal(t, "refresh-repo:1", queued[0].DedupeKey)
+		assert.Equal(t, "refresh-repo:3", queued[1].DedupeKey)
+		// each repository runs somewhere in its own third of the window
+		assert.True(t, !queued[0].RunAt.Before(now) && queued[0].RunAt.Before(now.Add(200*time.Second)))
+		assert.True(t, !queued[1].RunAt.Before(now.Add(400*time.Second)) && queued[1].RunAt.Before(next))
+	}
+	mockDBRepository.AssertExpectations(t)
+}
@@ -0,0 +1,56 @@
+package tasks
+
+import (
+	"context"
+	"sort"
+
+	"github.com/midedickson/github-service/dto"
+	"github.com/midedickson/github-service/models"
+)
+
+// how many of an owner's jobs the sync status lists
+const recentJobsLimit = 20
+
+func (t *AsyncTask) GetJob(ctx context.Context, id uint) (*models.Job, error) {
+	return t.dbRepository.GetJob(ctx, id)
+}
+
+func (t *AsyncTask) GetSyncStatus(ctx context.Context, owner string) (*dto.SyncStatusResponseDTO, error) {
+	counts, err := t.dbRepository.CountJobsByState(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	recentJobs, err := t.dbRepository.GetJobsByOwner(ctx, owner, recentJobsLimit)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.SyncStatusResponseDTO{
+		Owner:      owner,
+		Syncing:    counts[models.JobStatePending]+counts[models.JobStateRunning] > 0,
+		JobCounts:  counts,
+		RecentJobs: recentJobs,
+	}, nil
+}
+
+// the size, load and limit of every job queue
+func (t *AsyncTask) GetQueueStats(ctx context.Context) ([]*dto.QueueStatsResponseDTO, error) {
+	counts, err := t.dbRepository.CountActiveJobsByType(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.rejectedMu.Lock()
+	defer t.rejectedMu.Unlock()
+	stats := make([]*dto.QueueStatsResponseDTO, 0, len(t.handlers))
+	for jobType := range t.handlers {
+		stats = append(stats, &dto.QueueStatsResponseDTO{
+			Type:     jobType,
+			Workers:  t.workerPoolSize(jobType),
+			Pending:  counts[jobType][models.JobStatePending],
+			Running:  counts[jobType][models.JobStateRunning],
+			Limit:    t.queueLimit,
+			Rejected: t.rejected[jobType],
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Type < stats[j].Type })
+	return stats, nil
+}
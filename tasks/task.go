@@ -1,10 +1,20 @@
 package tasks
 
 import (
+	"context"
+
+	"github.com/midedickson/github-service/dto"
 	"github.com/midedickson/github-service/models"
 )
 
 type Task interface {
-	AddUserToGetAllRepoQueue(user *models.User)
-	AddRequestToFetchNewlyRequestedRepoQueue(username, repoName string)
+	AddUserToGetAllRepoQueue(ctx context.Context, user *models.User) (*models.Job, error)
+	AddRequestToFetchNewlyRequestedRepoQueue(ctx context.Context, username, repoName string) (*models.Job, error)
+	SyncUser(ctx context.Context, user *models.User, full bool) (*models.Job, bool, error)
+	SyncRepository(ctx context.Context, repo *models.Repository, full bool) (*models.Job, bool, error)
+	RefreshRepositoryFromHook(ctx context.Context, repo *models.Repository) (*models.Job, error)
+	AddRepositoryToBackfillQueue(ctx context.Context, backfill *models.CommitBackfill) (*models.Job, error)
+	GetJob(ctx context.Context, id uint) (*models.Job, error)
+	GetSyncStatus(ctx context.Context, owner string) (*dto.SyncStatusResponseDTO, error)
+	GetQueueStats(ctx context.Context) ([]*dto.QueueStatsResponseDTO, error)
 }
@@ -0,0 +1,87 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+)
+
+// send a webhook delivery to its subscription, recording how the attempt went on the delivery.
+// failed attempts fail the job, so the delivery is retried on the job's retry policy
+func (t *AsyncTask) DeliverWebhook(ctx context.Context, job *models.Job) error {
+	var request WebhookDeliveryRequest
+	if err := decodePayload(job, &request); err != nil {
+		return err
+	}
+	delivery, err := t.dbRepository.GetWebhookDelivery(ctx, request.DeliveryID)
+	if err != nil {
+		return err
+	}
+	if delivery == nil {
+		return permanent(fmt.Errorf("webhook delivery %d not found", request.DeliveryID))
+	}
+	if delivery.State == models.WebhookDeliveryDelivered {
+		// the job was requeued after the delivery went through
+		return nil
+	}
+
+	var statusCode int
+	if delivery.Subscription == nil {
+		err = permanent(fmt.Errorf("webhook subscription %d has been deleted", delivery.SubscriptionID))
+	} else {
+		statusCode, err = t.sendWebhook(ctx, delivery)
+	}
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.StatusCode = statusCode
+	if err == nil {
+		delivery.State = models.WebhookDeliveryDelivered
+		delivery.DeliveredAt = &now
+		delivery.Error = ""
+	} else {
+		delivery.Error = err.Error()
+		delivery.State = models.WebhookDeliveryRetrying
+		if _, deadLetterReason := nextAttempt(job, err, now); deadLetterReason != "" {
+			delivery.State = models.WebhookDeliveryFailed
+		}
+	}
+	if updateErr := t.dbRepository.UpdateWebhookDelivery(context.WithoutCancel(ctx), delivery); updateErr != nil {
+		log.Printf("Error in recording attempt of webhook delivery %d: %v", delivery.ID, updateErr)
+	}
+	return err
+}
+
+// post the delivery's payload, signed with the subscription's secret; any 2xx answer delivers it.
+// like github's, a 4xx other than 429 isn't retried, as the receiver turned the payload down
+func (t *AsyncTask) sendWebhook(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "github-service-webhooks")
+	req.Header.Set("X-Webhook-Event", delivery.Event)
+	req.Header.Set("X-Webhook-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(utils.SignatureHeader, utils.SignPayload(delivery.Subscription.Secret, body))
+	resp, err := t.webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain the body so the connection is reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, &utils.HTTPStatusError{StatusCode: resp.StatusCode, URL: delivery.Subscription.URL}
+	}
+	return resp.StatusCode, nil
+}
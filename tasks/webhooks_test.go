@@ -0,0 +1,101 @@
+package tasks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/midedickson/github-service/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestWebhookDelivery(url string) *models.WebhookDelivery {
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: 3,
+		Subscription:   &models.WebhookSubscription{URL: url, Secret: "s3cret", Events: []string{models.WebhookEventCommitCreated}},
+		Event:          models.WebhookEventCommitCreated,
+		Payload:        `{"event":"commit.created"}`,
+		State:          models.WebhookDeliveryPending,
+	}
+	delivery.ID = 7
+	return delivery
+}
+
+func TestDeliverWebhook_SignsThePayload(t *testing.T) {
+	var received *http.Request
+	var body []byte
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{}, nil)
+	delivery := newTestWebhookDelivery(receiver.URL)
+	mockDBRepository.On("GetWebhookDelivery", uint(7)).Return(delivery, nil)
+	mockDBRepository.On("UpdateWebhookDelivery", delivery).Return(nil)
+
+	job := &models.Job{Type: models.JobTypeDeliverWebhook, Payload: `{"delivery_id":7}`, Attempts: 1}
+	assert.NoError(t, task.DeliverWebhook(context.Background(), job))
+
+	assert.Equal(t, delivery.Payload, string(body))
+	assert.Equal(t, utils.SignPayload("s3cret", body), received.Header.Get("X-Hub-Signature-256"))
+	assert.Equal(t, models.WebhookEventCommitCreated, received.Header.Get("X-Webhook-Event"))
+	assert.Equal(t, "7", received.Header.Get("X-Webhook-Delivery"))
+	assert.Equal(t, models.WebhookDeliveryDelivered, delivery.State)
+	assert.Equal(t, http.StatusNoContent, delivery.StatusCode)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.NotNil(t, delivery.DeliveredAt)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestDeliverWebhook_RecordsFailedAttempts(t *testing.T) {
+	status := http.StatusBadGateway
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer receiver.Close()
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{}, nil)
+	delivery := newTestWebhookDelivery(receiver.URL)
+	mockDBRepository.On("GetWebhookDelivery", uint(7)).Return(delivery, nil)
+	mockDBRepository.On("UpdateWebhookDelivery", delivery).Return(nil)
+	job := &models.Job{Type: models.JobTypeDeliverWebhook, Payload: `{"delivery_id":7}`, Attempts: 1}
+
+	// a receiver that is down is tried again
+	err := task.DeliverWebhook(context.Background(), job)
+	assert.Error(t, err)
+	assert.Equal(t, errorRetryable, classifyError(err))
+	assert.Equal(t, models.WebhookDeliveryRetrying, delivery.State)
+	assert.Equal(t, http.StatusBadGateway, delivery.StatusCode)
+	assert.Contains(t, delivery.Error, "unexpected status 502")
+
+	// one that turns the payload down isn't
+	status = http.StatusGone
+	job.Attempts++
+	err = task.DeliverWebhook(context.Background(), job)
+	assert.Equal(t, errorPermanent, classifyError(err))
+	assert.Equal(t, models.WebhookDeliveryFailed, delivery.State)
+	assert.Equal(t, 2, delivery.Attempts)
+	assert.Nil(t, delivery.DeliveredAt)
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestDeliverWebhook_SkipsDeliveredDeliveries(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{}, nil)
+	delivery := newTestWebhookDelivery("http://127.0.0.1:1")
+	delivery.State = models.WebhookDeliveryDelivered
+	mockDBRepository.On("GetWebhookDelivery", uint(7)).Return(delivery, nil)
+
+	job := &models.Job{Type: models.JobTypeDeliverWebhook, Payload: `{"delivery_id":7}`, Attempts: 1}
+	assert.NoError(t, task.DeliverWebhook(context.Background(), job))
+	mockDBRepository.AssertNotCalled(t, "UpdateWebhookDelivery", mock.Anything)
+}
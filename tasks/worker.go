@@ -0,0 +1,155 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/midedickson/github-service/database"
+	"github.com/midedickson/github-service/models"
+)
+
+// start the worker pool of every job type; the workers stop claiming jobs once ctx is cancelled
+func (t *AsyncTask) StartWorkers(ctx context.Context, wg *sync.WaitGroup) {
+	for jobType := range t.handlers {
+		for i := 0; i < t.workerPoolSize(jobType); i++ {
+			wg.Add(1)
+			go t.RunWorker(ctx, wg, jobType)
+		}
+	}
+}
+
+func (t *AsyncTask) workerPoolSize(jobType string) int {
+	if size, ok := t.poolSizes[jobType]; ok {
+		return size
+	}
+	if t.poolSize < 1 {
+		return 1
+	}
+	return t.poolSize
+}
+
+// claim and run jobs of the given type until ctx is cancelled; the job in hand is
+// finished first, under the task's job context (see Shutdown)
+func (t *AsyncTask) RunWorker(ctx context.Context, wg *sync.WaitGroup, jobType string) {
+	defer wg.Done()
+	log.Printf("waiting for %s jobs...", jobType)
+	// a job whose lease ran out on its last attempt is dead-lettered rather than claimed again
+	maxAttempts := retryPolicyFor(jobType).MaxAttempts
+	for ctx.Err() == nil {
+		job, err := t.dbRepository.ClaimJob(ctx, []string{jobType}, t.workerID, t.leaseDuration, maxAttempts)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Error in claiming %s jobs: %v", jobType, err)
+		}
+		if job == nil {
+			sleep(ctx, t.pollInterval)
+			continue
+		}
+		t.runJob(job)
+	}
+	log.Printf("exiting worker for %s jobs...", jobType)
+}
+
+func (t *AsyncTask) runJob(job *models.Job) {
+	// the outcome is recorded even when the job was interrupted
+	ctx := context.WithoutCancel(t.jobCtx)
+	handler, ok := t.handlers[job.Type]
+	if !ok {
+		t.failJob(ctx, job, permanent(fmt.Errorf("no handler for job type %s", job.Type)))
+		return
+	}
+	stopHeartbeat := t.keepLeaseAlive(t.jobCtx, job)
+	err := handler(t.jobCtx, job)
+	stopHeartbeat()
+	if err != nil && t.jobCtx.Err() != nil {
+		// the job didn't fail, it was cut off by shutdown; put it back for the next start
+		log.Printf("%s job %d interrupted by shutdown; releasing it", job.Type, job.ID)
+		if err := t.dbRepository.ReleaseJob(ctx, job); err != nil {
+			log.Printf("Error in releasing %s job %d: %v", job.Type, job.ID, err)
+		}
+		return
+	}
+	if err != nil {
+		t.failJob(ctx, job, err)
+		return
+	}
+	if err := t.dbRepository.CompleteJob(ctx, job); err != nil {
+		log.Printf("Error in completing %s job %d: %v", job.Type, job.ID, err)
+	}
+}
+
+// schedule a failed job to be retried, or move it to the dead letters when retrying won't help
+func (t *AsyncTask) failJob(ctx context.Context, job *models.Job, jobErr error) {
+	runAt, deadLetterReason := nextAttempt(job, jobErr, time.Now())
+	if deadLetterReason != "" {
+		log.Printf("%s job %d failed after %d attempt(s) (%s): %v", job.Type, job.ID, job.Attempts, deadLetterReason, jobErr)
+		if err := t.dbRepository.FailJob(ctx, job, deadLetterReason, jobErr.Error()); err != nil {
+			log.Printf("Error in failing %s job %d: %v", job.Type, job.ID, err)
+		}
+		t.publishJobFailure(job, jobErr, runAt, deadLetterReason)
+		return
+	}
+	log.Printf("%s job %d failed on attempt %d, retrying in %v: %v", job.Type, job.ID, job.Attempts, time.Until(runAt).Round(time.Second), jobErr)
+	if err := t.dbRepository.RetryJob(ctx, job, jobErr.Error(), runAt); err != nil {
+		log.Printf("Error in scheduling retry of %s job %d: %v", job.Type, job.ID, err)
+	}
+	t.publishJobFailure(job, jobErr, runAt, "")
+}
+
+// count a fetched page and the commits stored from it; a job whose lease was lost stops
+// here, since another worker is already running it, as does a job being interrupted
+func (t *AsyncTask) recordProgress(ctx context.Context, job *models.Job, commitsStored int) error {
+	job.PagesFetched++
+	job.CommitsStored += commitsStored
+	err := t.dbRepository.UpdateJobProgress(ctx, job)
+	if errors.Is(err, database.ErrJobLeaseLost) || ctx.Err() != nil {
+		return err
+	}
+	if err != nil {
+		log.Printf("Error in recording progress of %s job %d: %v", job.Type, job.ID, err)
+	}
+	return nil
+}
+
+// extend the job's lease in the background while its handler runs; the returned
+// function stops the heartbeat and waits for it to exit
+func (t *AsyncTask) keepLeaseAlive(ctx context.Context, job *models.Job) func() {
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(t.leaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.dbRepository.ExtendJobLease(ctx, job, t.leaseDuration); err != nil {
+					log.Printf("Error in extending lease of %s job %d: %v", job.Type, job.ID, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-exited
+	}
+}
+
+// wait for d, returning early when ctx is done; reports whether the full wait elapsed
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package tasks
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/midedickson/github-service/config"
+	"github.com/midedickson/github-service/mocks"
+	"github.com/midedickson/github-service/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// start a single fetch-repo worker whose handler is replaced by handler, and wait until it claimed its job
+func startTestWorker(t *testing.T, mockDBRepository *mocks.MockDBRepository, handler jobHandler) (*AsyncTask, *models.Job, context.CancelFunc, *sync.WaitGroup) {
+	task := NewAsyncTask(new(mocks.MockRequester), mockDBRepository, &config.Config{
+		JobPollInterval:  10 * time.Millisecond,
+		JobLeaseDuration: time.Minute,
+	}, nil)
+	job := &models.Job{Type: models.JobTypeFetchRepo, State: models.JobStateRunning, Attempts: 1}
+	job.ID = 1
+	claimed := make(chan struct{})
+	task.handlers[models.JobTypeFetchRepo] = func(ctx context.Context, job *models.Job) error {
+		close(claimed)
+		return handler(ctx, job)
+	}
+	mockDBRepository.On("ClaimJob", []string{models.JobTypeFetchRepo}, task.workerID, time.Minute, 5).Return(job, nil).Once()
+	mockDBRepository.On("ClaimJob", []string{models.JobTypeFetchRepo}, task.workerID, time.Minute, 5).Return(nil, nil).Maybe()
+
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go task.RunWorker(workerCtx, &wg, models.JobTypeFetchRepo)
+	select {
+	case <-claimed:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not pick up the job")
+	}
+	return task, job, stopWorkers, &wg
+}
+
+func TestShutdown_LetsRunningJobsFinishBeforeTheDeadline(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	finish := make(chan struct{})
+	task, job, stopWorkers, wg := startTestWorker(t, mockDBRepository, func(ctx context.Context, job *models.Job) error {
+		<-finish
+		return nil
+	})
+	mockDBRepository.On("CompleteJob", job).Return(nil).Once()
+
+	stopWorkers()
+	close(finish)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, task.Shutdown(ctx, wg))
+	mockDBRepository.AssertExpectations(t)
+}
+
+func TestShutdown_ReleasesJobsInterruptedByTheDeadline(t *testing.T) {
+	mockDBRepository := new(mocks.MockDBRepository)
+	task, job, stopWorkers, wg := startTestWorker(t, mockDBRepository, func(ctx context.Context, job *models.Job) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	mockDBRepository.On("ReleaseJob", job).Return(nil).Once()
+
+	stopWorkers()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, task.Shutdown(ctx, wg), context.DeadlineExceeded)
+	// an interrupted job is neither failed nor retried, it goes straight back to the queue
+	mockDBRepository.AssertNotCalled(t, "RetryJob", mock.Anything, mock.Anything, mock.Anything)
+	mockDBRepository.AssertNotCalled(t, "FailJob", mock.Anything, mock.Anything, mock.Anything)
+	mockDBRepository.AssertExpectations(t)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parse a date given either as a plain day (2006-01-02) or a full RFC3339 timestamp
+func ParseDate(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC3339", value)
+	}
+	return parsed, nil
+}
+
+const (
+	IntervalHour  = "hour"
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// the start of the interval t falls in, in UTC; weeks start on monday
+func IntervalStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case IntervalHour:
+		return t.Truncate(time.Hour)
+	case IntervalWeek:
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case IntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// the start of the interval after the one starting at start
+func NextInterval(start time.Time, interval string) time.Time {
+	return AddIntervals(start, interval, 1)
+}
+
+// move t by n intervals, backwards for a negative n
+func AddIntervals(t time.Time, interval string, n int) time.Time {
+	switch interval {
+	case IntervalHour:
+		return t.Add(time.Duration(n) * time.Hour)
+	case IntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	case IntervalMonth:
+		return t.AddDate(0, n, 0)
+	default:
+		return t.AddDate(0, 0, n)
+	}
+}
+
+// parse a window of time given in days (7d) or as a duration (12h)
+func ParseWindow(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if window, err := time.ParseDuration(value); err == nil && window > 0 {
+		return window, nil
+	}
+	return 0, fmt.Errorf("invalid window %q, expected days (7d) or a duration (12h)", value)
+}
@@ -1,5 +1,33 @@
 package utils
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var ErrRepoNotFound = errors.New("repo not found on github")
+var ErrNotModified = errors.New("resource not modified on github")
+
+// github answered with a status the requester doesn't handle itself
+type HTTPStatusError struct {
+	StatusCode int
+	URL        string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d from %s", e.StatusCode, e.URL)
+}
+
+// every token is out of quota until Reset
+type RateLimitError struct {
+	Reset time.Time
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("github rate limit exceeded until %s", e.Reset.Format(time.RFC3339))
+}
+
+// the list parameters of a request don't fit the list being paged through
+var ErrInvalidSort = errors.New("invalid sort field")
+var ErrInvalidCursor = errors.New("invalid or expired cursor")
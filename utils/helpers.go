@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -18,6 +20,19 @@ func GetPathParam(r *http.Request, name string) (string, error) {
 	return value, nil
 }
 
+// get a numeric id path param from request
+func GetIDPathParam(r *http.Request, name string) (uint, error) {
+	value, err := GetPathParam(r, name)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid %s in request param", name)
+	}
+	return uint(id), nil
+}
+
 func ParseQueryParams(r *http.Request, repoSearchParams *RepositorySearchParams) {
 	query := r.URL.Query()
 	if query.Get("name") != "" {
@@ -30,3 +45,63 @@ func ParseQueryParams(r *http.Request, repoSearchParams *RepositorySearchParams)
 		repoSearchParams.TopStarsCount, _ = strconv.Atoi(query.Get("top_stars"))
 	}
 }
+
+// read the author, message, sha, since and until filters of the commits list
+func ParseCommitFilters(r *http.Request) (*CommitFilters, error) {
+	query := r.URL.Query()
+	filters := &CommitFilters{
+		Author:    query.Get("author"),
+		Message:   query.Get("message"),
+		SHAPrefix: strings.ToLower(query.Get("sha")),
+	}
+	if !isHex(filters.SHAPrefix) {
+		return nil, fmt.Errorf("sha must be a hexadecimal commit sha or prefix")
+	}
+	since, until, err := ParseDateRange(r)
+	if err != nil {
+		return nil, err
+	}
+	filters.Since, filters.Until = since, until
+	return filters, nil
+}
+
+// read the since and until query parameters; either may be missing. a plain day as until covers the whole day
+func ParseDateRange(r *http.Request) (*time.Time, *time.Time, error) {
+	return ParseDateRangeParams(r, "since", "until")
+}
+
+// read a date range from the start and end query parameters, the way ParseDateRange reads since and until
+func ParseDateRangeParams(r *http.Request, start, end string) (*time.Time, *time.Time, error) {
+	query := r.URL.Query()
+	var since, until *time.Time
+	if value := query.Get(start); value != "" {
+		parsed, err := ParseDate(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", start, err)
+		}
+		since = &parsed
+	}
+	if value := query.Get(end); value != "" {
+		parsed, err := ParseDate(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", end, err)
+		}
+		if _, err := time.Parse(time.DateOnly, value); err == nil {
+			parsed = parsed.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		until = &parsed
+	}
+	if since != nil && until != nil && until.Before(*since) {
+		return nil, nil, fmt.Errorf("%s must not be before %s", end, start)
+	}
+	return since, until, nil
+}
+
+func isHex(value string) bool {
+	for _, c := range value {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const (
+	DefaultPerPage = 30
+	MaxPerPage     = 100
+)
+
+// how a list is paged through: by page number when Page is set, otherwise by the opaque
+// cursor handed out with the previous page (none for the first page). empty Sort and Order
+// fall back to the list's defaults
+type ListParams struct {
+	Page    int
+	PerPage int
+	Cursor  string
+	Sort    string
+	Order   string
+}
+
+// where a page sits in the full list; the cursors are only set when paging by cursor and
+// there is a page in that direction
+type PageInfo struct {
+	Total      int64
+	Page       int
+	PerPage    int
+	HasNext    bool
+	HasPrev    bool
+	NextCursor string
+	PrevCursor string
+}
+
+// pagination details of a list response; next and prev link to the neighbouring pages
+type PageMeta struct {
+	Total   int64  `json:"total"`
+	Page    int    `json:"page,omitempty"`
+	PerPage int    `json:"per_page"`
+	Next    string `json:"next,omitempty"`
+	Prev    string `json:"prev,omitempty"`
+}
+
+// read page, per_page, cursor, sort and order from the query string
+func ParseListParams(r *http.Request) (*ListParams, error) {
+	query := r.URL.Query()
+	params := &ListParams{
+		PerPage: DefaultPerPage,
+		Cursor:  query.Get("cursor"),
+		Sort:    query.Get("sort"),
+		Order:   query.Get("order"),
+	}
+	if value := query.Get("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			return nil, fmt.Errorf("page must be a positive number")
+		}
+		params.Page = page
+	}
+	if value := query.Get("per_page"); value != "" {
+		perPage, err := strconv.Atoi(value)
+		if err != nil || perPage < 1 || perPage > MaxPerPage {
+			return nil, fmt.Errorf("per_page must be between 1 and %d", MaxPerPage)
+		}
+		params.PerPage = perPage
+	}
+	if params.Page > 0 && params.Cursor != "" {
+		return nil, fmt.Errorf("use either page or cursor, not both")
+	}
+	if params.Order != "" && params.Order != "asc" && params.Order != "desc" {
+		return nil, fmt.Errorf("order must be asc or desc")
+	}
+	return params, nil
+}
+
+// the meta of a list response, linking to the neighbouring pages with the request's other
+// query parameters kept as they are
+func (p *PageInfo) Meta(r *http.Request) *PageMeta {
+	meta := &PageMeta{Total: p.Total, Page: p.Page, PerPage: p.PerPage}
+	link := func(param, value string) string {
+		query := r.URL.Query()
+		query.Del("page")
+		query.Del("cursor")
+		query.Set(param, value)
+		return r.URL.Path + "?" + query.Encode()
+	}
+	if p.Page > 0 {
+		if p.HasNext {
+			meta.Next = link("page", strconv.Itoa(p.Page+1))
+		}
+		if p.HasPrev {
+			meta.Prev = link("page", strconv.Itoa(p.Page-1))
+		}
+		return meta
+	}
+	if p.NextCursor != "" {
+		meta.Next = link("cursor", p.NextCursor)
+	}
+	if p.PrevCursor != "" {
+		meta.Prev = link("cursor", p.PrevCursor)
+	}
+	return meta
+}
@@ -35,6 +35,28 @@ func Dispatch404Error(w http.ResponseWriter, msg string, err any) {
 	w.Write(WriteError(msg, err))
 }
 
+// 409 - conflict, when the resource is not in a state that allows the request
+func Dispatch409Error(w http.ResponseWriter, msg string, err any) {
+	AddDefaultHeaders(w)
+	w.WriteHeader(http.StatusConflict)
+	w.Write(WriteError(msg, err))
+}
+
+// 202 - accepted, for work handed to the background workers; location points at where to follow it
+func Dispatch202(w http.ResponseWriter, msg string, location string, data any) {
+	AddDefaultHeaders(w)
+	w.Header().Set("Location", location)
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(WriteInfo(msg, data))
+}
+
+// 503 - service unavailable, when the service is too busy to take the request right now
+func Dispatch503Error(w http.ResponseWriter, msg string, err any) {
+	AddDefaultHeaders(w)
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.Write(WriteError(msg, err))
+}
+
 // 200 - OK
 func Dispatch200(w http.ResponseWriter, msg string, data any) {
 	AddDefaultHeaders(w)
@@ -42,12 +64,18 @@ func Dispatch200(w http.ResponseWriter, msg string, data any) {
 	w.Write(WriteInfo(msg, data))
 }
 
+// 200 - OK, for one page of a list along with where it sits in the full list
+func Dispatch200Page(w http.ResponseWriter, msg string, data any, meta *PageMeta) {
+	AddDefaultHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	w.Write(writeResponse(APIResponse{Success: true, Message: msg, Data: data, Meta: meta}))
+}
+
 func WriteInfo(message string, data any) []byte {
-	response := APIResponse{
-		Success: true,
-		Message: message,
-		Data:    data,
-	}
+	return writeResponse(APIResponse{Success: true, Message: message, Data: data})
+}
+
+func writeResponse(response APIResponse) []byte {
 	r, err := json.Marshal(response)
 	if err == nil {
 		return r
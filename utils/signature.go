@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// the header github signs its webhook payloads in, which ours use too so receivers can verify
+// both the same way
+const SignatureHeader = "X-Hub-Signature-256"
+
+// sign a payload the way github does: sha256= followed by the hex hmac-sha256 of the body, keyed by the secret
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// whether signature is the one SignPayload gives for the body, compared in constant time
+func VerifySignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(SignPayload(secret, body)))
+}
@@ -1,9 +1,73 @@
 package utils
 
+import "time"
+
 type APIResponse struct {
 	Success bool        `json:"success"`
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
+	// set on list responses
+	Meta *PageMeta `json:"meta,omitempty"`
+}
+
+// narrows a repository's commits; empty fields don't filter
+type CommitFilters struct {
+	Author    string
+	Message   string
+	SHAPrefix string
+	Since     *time.Time
+	Until     *time.Time
+}
+
+// which commits the author statistics are computed over; empty Owner and RepoName cover every
+// stored repository
+type AuthorStatsQuery struct {
+	Owner    string
+	RepoName string
+	Since    *time.Time
+	Until    *time.Time
+	Top      int
+}
+
+const (
+	SearchTypeCommits      = "commits"
+	SearchTypeRepositories = "repos"
+)
+
+// a full text search, optionally scoped to an owner's repositories or a single repository
+type SearchQuery struct {
+	Text     string
+	Type     string
+	Owner    string
+	RepoName string
+	Page     int
+	PerPage  int
+}
+
+// the repository metrics recorded in snapshots
+const (
+	MetricStars      = "stars"
+	MetricForks      = "forks"
+	MetricOpenIssues = "open_issues"
+	MetricWatchers   = "watchers"
+)
+
+var RepositoryMetrics = []string{MetricStars, MetricForks, MetricOpenIssues, MetricWatchers}
+
+// a repository metric as it stood at the end of every interval between From and To
+type MetricHistoryQuery struct {
+	Metric   string
+	Interval string
+	From     time.Time
+	To       time.Time
+}
+
+// the repositories whose metric grew most since Since; empty Owner covers every stored repository
+type TrendingQuery struct {
+	Metric string
+	Owner  string
+	Since  time.Time
+	Top    int
 }
 
 type RepositorySearchParams struct {